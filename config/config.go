@@ -3,19 +3,86 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	Port       string
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
-	JWTSecret  string
+
+	// Token signing settings
+	JWTIssuer      string
+	JWTKeysDir     string // Directory of <kid>.pem keys; public-only keys are kept for verification
+	JWTActiveKeyID string // Key used to sign new tokens
+
+	// Token lifetimes
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	InvitationTTL    time.Duration
+	PasswordResetTTL time.Duration
+	ImpersonationTTL time.Duration
+
+	// Login protection settings
+	MaxLoginAttempts    int
+	LockoutBaseDuration time.Duration
+	LockoutMaxDuration  time.Duration
+	LoginRateLimit      int
+	LoginRateWindow     time.Duration
+	TrustedProxies      []string // Proxies whose X-Forwarded-For is believed for the client IP; none by default
+
+	// Password policy settings
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordHistorySize   int    // Number of previous passwords that cannot be reused
+	PasswordBlocklistFile string // Optional extra list of common or breached passwords, one per line
+
+	// Two-factor authentication settings
+	RequireTwoFactorForPrivileged bool // Admins and managers must enroll in 2FA
+	TwoFactorChallengeTTL         time.Duration
+	TOTPIssuer                    string
+
+	// OpenID Connect single sign-on settings (disabled when OIDCIssuerURL is empty)
+	OIDCIssuerURL       string
+	OIDCClientID        string
+	OIDCClientSecret    string
+	OIDCRedirectURL     string
+	OIDCScopes          string
+	OIDCRoleClaim       string            // Claim holding the user's groups or roles
+	OIDCRoleMapping     map[string]string // Claim value -> role, e.g. "it-admins=admin"
+	OIDCDefaultRole     string
+	OIDCDepartmentClaim string
+	OIDCDefaultDept     string
+	OIDCLinkByEmail     bool // Link an unlinked local account whose email matches a verified email claim
+
+	// Notification settings
+	Notifier         string // "log" or "file"
+	NotifierFilePath string
+	PasswordResetURL string // Link sent to users, the token is appended
+	InvitationURL    string // Link sent to invitees, the token is appended
+
+	// Avatar upload settings
+	AvatarDir      string // Local avatar storage, served at AvatarBaseURL
+	AvatarBaseURL  string
+	AvatarMaxBytes int64
 }
 
+var (
+	loadedConfig *Config
+	loadOnce     sync.Once
+)
+
 func LoadConfig() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -23,11 +90,146 @@ func LoadConfig() (*Config, error) {
 	}
 
 	return &Config{
+		Port:       getStringEnv("PORT", "8080"),
 		DBHost:     os.Getenv("DB_HOST"),
 		DBPort:     os.Getenv("DB_PORT"),
 		DBUser:     os.Getenv("DB_USER"),
 		DBPassword: os.Getenv("DB_PASSWORD"),
 		DBName:     os.Getenv("DB_NAME"),
-		JWTSecret:  os.Getenv("JWT_SECRET"),
+
+		JWTIssuer:      getStringEnv("JWT_ISSUER", "project-x"),
+		JWTKeysDir:     os.Getenv("JWT_KEYS_DIR"),
+		JWTActiveKeyID: os.Getenv("JWT_ACTIVE_KEY_ID"),
+
+		AccessTokenTTL:   getDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:  getDurationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		InvitationTTL:    getDurationEnv("INVITATION_TTL", 72*time.Hour),
+		PasswordResetTTL: getDurationEnv("PASSWORD_RESET_TTL", time.Hour),
+		ImpersonationTTL: getDurationEnv("IMPERSONATION_TTL", 30*time.Minute),
+
+		MaxLoginAttempts:    getIntEnv("MAX_LOGIN_ATTEMPTS", 5),
+		LockoutBaseDuration: getDurationEnv("LOCKOUT_BASE_DURATION", 5*time.Minute),
+		LockoutMaxDuration:  getDurationEnv("LOCKOUT_MAX_DURATION", 24*time.Hour),
+		LoginRateLimit:      getIntEnv("LOGIN_RATE_LIMIT", 10),
+		LoginRateWindow:     getDurationEnv("LOGIN_RATE_WINDOW", time.Minute),
+		TrustedProxies:      getListEnv("TRUSTED_PROXIES"),
+
+		PasswordMinLength:     getIntEnv("PASSWORD_MIN_LENGTH", 10),
+		PasswordRequireUpper:  getBoolEnv("PASSWORD_REQUIRE_UPPER", true),
+		PasswordRequireLower:  getBoolEnv("PASSWORD_REQUIRE_LOWER", true),
+		PasswordRequireDigit:  getBoolEnv("PASSWORD_REQUIRE_DIGIT", true),
+		PasswordRequireSymbol: getBoolEnv("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordHistorySize:   getIntEnv("PASSWORD_HISTORY_SIZE", 5),
+		PasswordBlocklistFile: os.Getenv("PASSWORD_BLOCKLIST_FILE"),
+
+		RequireTwoFactorForPrivileged: getBoolEnv("REQUIRE_2FA_FOR_PRIVILEGED", false),
+		TwoFactorChallengeTTL:         getDurationEnv("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		TOTPIssuer:                    getStringEnv("TOTP_ISSUER", "Project X"),
+
+		OIDCIssuerURL:       os.Getenv("OIDC_ISSUER_URL"),
+		OIDCClientID:        os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:    os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:     os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:          getStringEnv("OIDC_SCOPES", "openid profile email"),
+		OIDCRoleClaim:       getStringEnv("OIDC_ROLE_CLAIM", "groups"),
+		OIDCRoleMapping:     getMapEnv("OIDC_ROLE_MAPPING"),
+		OIDCDefaultRole:     getStringEnv("OIDC_DEFAULT_ROLE", "employee"),
+		OIDCDepartmentClaim: getStringEnv("OIDC_DEPARTMENT_CLAIM", "department"),
+		OIDCDefaultDept:     getStringEnv("OIDC_DEFAULT_DEPARTMENT", "unassigned"),
+		OIDCLinkByEmail:     getBoolEnv("OIDC_LINK_BY_EMAIL", false),
+
+		Notifier:         getStringEnv("NOTIFIER", "log"),
+		NotifierFilePath: getStringEnv("NOTIFIER_FILE_PATH", "notifications.log"),
+		PasswordResetURL: os.Getenv("PASSWORD_RESET_URL"),
+		InvitationURL:    os.Getenv("INVITATION_URL"),
+
+		AvatarDir:      getStringEnv("AVATAR_DIR", "uploads/avatars"),
+		AvatarBaseURL:  getStringEnv("AVATAR_BASE_URL", "/avatars"),
+		AvatarMaxBytes: int64(getIntEnv("AVATAR_MAX_BYTES", 2<<20)),
 	}, nil
 }
+
+// Get returns the application config, loading it on first use
+func Get() *Config {
+	loadOnce.Do(func() {
+		loadedConfig, _ = LoadConfig()
+	})
+	return loadedConfig
+}
+
+// getDurationEnv reads a duration (e.g. "15m", "168h") from the environment with a fallback
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s, using default %s", key, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// getIntEnv reads an integer from the environment with a fallback
+func getIntEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s, using default %d", key, fallback)
+		return fallback
+	}
+	return number
+}
+
+// getBoolEnv reads a boolean (true/false, 1/0) from the environment with a fallback
+func getBoolEnv(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s, using default %t", key, fallback)
+		return fallback
+	}
+	return enabled
+}
+
+// getStringEnv reads a string from the environment with a fallback
+func getStringEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getListEnv reads a comma-separated list (e.g. "10.0.0.1,10.1.0.0/16") from the environment
+func getListEnv(key string) []string {
+	var result []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// getMapEnv reads comma-separated key=value pairs (e.g. "a=admin,b=manager") from the environment
+func getMapEnv(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" {
+			continue
+		}
+		result[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// currentActor loads what the access policy needs to know about the current user.
+// It writes the error response itself and returns false on failure.
+func currentActor(c *gin.Context, db *gorm.DB) (*services.Actor, bool) {
+	user, _ := c.Get("user")
+	actor, err := services.NewActor(db, user.(*models.User))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve access"})
+		return nil, false
+	}
+	return actor, true
+}
+
+// writeAccessError writes the response for a failed write: 403 when the access policy or a
+// department check refused it, 404 when the task, checklist item or dependency does not exist, 409
+// when the task workflow does not allow a status change, open blockers hold the task, a dependency
+// exists or would close a cycle, or the progress is rolled up, and 400 otherwise. Rejected task
+// edits list the problem with each field.
+func writeAccessError(c *gin.Context, err error) {
+	var fieldErrors services.TaskFieldErrors
+	var transitionErr *services.TransitionError
+	var blockedErr *services.BlockedTaskError
+	var cycleErr *services.DependencyCycleError
+	switch {
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            err.Error(),
+			"task_id":          transitionErr.TaskID,
+			"current_status":   transitionErr.From,
+			"allowed_statuses": transitionErr.Allowed,
+		})
+	case errors.As(err, &blockedErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "task": blockedErr.Task, "blocked_by": blockedErr.Blockers})
+	case errors.As(err, &cycleErr):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "cycle": cycleErr.Cycle})
+	case errors.As(err, &fieldErrors):
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidTaskPatch.Error(), "fields": fieldErrors})
+	case errors.Is(err, services.ErrAccessDenied), errors.Is(err, services.ErrOutsideDepartment):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTaskNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	case errors.Is(err, services.ErrChecklistItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+	case errors.Is(err, services.ErrDependencyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dependency not found"})
+	case errors.Is(err, services.ErrProgressRolledUp), errors.Is(err, services.ErrDependencyExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// workspaceDB binds the handler's database to the request, so queries on workspace-owned
+// models only see the workspace picked by AuthMiddleware
+func workspaceDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c.Request.Context())
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"project-x/services"
 
@@ -27,11 +28,100 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := services.NewAuthService(h.DB).Login(loginRequest.Username, loginRequest.Password)
+	result, err := services.NewAuthService(h.DB).Login(loginRequest.Username, loginRequest.Password, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
+		if errors.Is(err, services.ErrAccountDeactivated) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyTwoFactor completes a two-step login with a TOTP or recovery code
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var verifyRequest struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"` // TOTP code or recovery code
+	}
+
+	if err := c.ShouldBindJSON(&verifyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := services.NewAuthService(h.DB).VerifyTwoFactor(verifyRequest.ChallengeToken, verifyRequest.Code, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrAccountLocked) {
+			c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrAccountDeactivated) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh exchanges a refresh token for a new token pair
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var refreshRequest struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&refreshRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := services.NewAuthService(h.DB).Refresh(refreshRequest.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout revokes the current session
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, _ := c.Get("sessionID")
+
+	if err := services.NewAuthService(h.DB).Logout(sessionID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the current user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	if err := services.NewAuthService(h.DB).LogoutAll(userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all sessions successfully"})
+}
+
+// JWKS publishes the public keys used to verify access tokens
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	keys, err := services.GetKeyRing()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load signing keys"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keys.JWKS())
 }
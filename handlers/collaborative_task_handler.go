@@ -35,7 +35,7 @@ func (h *CollaborativeTaskHandler) CreateCollaborativeTask(c *gin.Context) {
 		return
 	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
 	task, err := collaborativeTaskService.CreateCollaborativeTask(
 		createRequest.Title,
 		createRequest.Description,
@@ -88,10 +88,15 @@ func (h *CollaborativeTaskHandler) AddParticipant(c *gin.Context) {
 		return
 	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
-	err = collaborativeTaskService.AddParticipant(uint(taskID), addRequest.UserID, addRequest.Role, addRequest.Contribution)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	err = collaborativeTaskService.AddParticipant(actor, uint(taskID), addRequest.UserID, addRequest.Role, addRequest.Contribution)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -112,10 +117,15 @@ func (h *CollaborativeTaskHandler) RemoveParticipant(c *gin.Context) {
 		return
 	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
-	err = collaborativeTaskService.RemoveParticipant(uint(taskID), uint(userID))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	err = collaborativeTaskService.RemoveParticipant(actor, uint(taskID), uint(userID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -139,17 +149,93 @@ func (h *CollaborativeTaskHandler) UpdateTaskProgress(c *gin.Context) {
 		return
 	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
-	err = collaborativeTaskService.UpdateTaskProgress(uint(taskID), updateRequest.Progress)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	err = collaborativeTaskService.UpdateTaskProgress(actor, uint(taskID), updateRequest.Progress)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task progress updated successfully"})
 }
 
-// GetCollaborativeTaskDetails returns detailed information about a collaborative task
+// EditCollaborativeTask changes a collaborative task with a JSON merge patch. Contributors may
+// patch the progress alone; other fields need the same access as changing the status.
+func (h *CollaborativeTaskHandler) EditCollaborativeTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	task, err := collaborativeTaskService.EditCollaborativeTask(actor, uint(taskID), patch)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Collaborative task updated successfully",
+		"task": gin.H{
+			"id":           task.ID,
+			"title":        task.Title,
+			"description":  task.Description,
+			"status":       task.Status,
+			"lead_user":    userProfile(&task.LeadUser),
+			"project_id":   task.ProjectID,
+			"priority":     task.Priority,
+			"complexity":   task.Complexity,
+			"progress":     task.Progress,
+			"assigned_at":  task.AssignedAt,
+			"due_date":     task.DueDate,
+			"created_at":   task.CreatedAt,
+			"started_at":   task.StartedAt,
+			"completed_at": task.CompletedAt,
+			"updated_at":   task.UpdatedAt,
+		},
+	})
+}
+
+// GetCollaborativeTaskHistory lists the recorded changes of a collaborative task (participants, managers, project members, or department viewers)
+func (h *CollaborativeTaskHandler) GetCollaborativeTaskHistory(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	history, err := collaborativeTaskService.GetCollaborativeTaskHistory(actor, uint(taskID))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": taskHistoryResponse(history)})
+}
+
+// GetCollaborativeTaskDetails returns detailed information about a collaborative task (anyone who may view it)
 func (h *CollaborativeTaskHandler) GetCollaborativeTaskDetails(c *gin.Context) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -157,10 +243,15 @@ func (h *CollaborativeTaskHandler) GetCollaborativeTaskDetails(c *gin.Context) {
 		return
 	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
-	task, err := collaborativeTaskService.GetCollaborativeTaskWithDetails(uint(taskID))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	task, err := collaborativeTaskService.GetCollaborativeTaskWithDetails(actor, uint(taskID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Collaborative task not found"})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -171,6 +262,8 @@ func (h *CollaborativeTaskHandler) GetCollaborativeTaskDetails(c *gin.Context) {
 			"id":           participant.ID,
 			"user_id":      participant.UserID,
 			"username":     participant.User.Username,
+			"display_name": participant.User.Name(),
+			"avatar_url":   services.AvatarURL(&participant.User),
 			"role":         participant.Role,
 			"status":       participant.Status,
 			"contribution": participant.Contribution,
@@ -180,27 +273,26 @@ func (h *CollaborativeTaskHandler) GetCollaborativeTaskDetails(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"task": gin.H{
-			"id":          task.ID,
-			"title":       task.Title,
-			"description": task.Description,
-			"status":      task.Status,
-			"lead_user": gin.H{
-				"id":       task.LeadUser.ID,
-				"username": task.LeadUser.Username,
-			},
+			"id":           task.ID,
+			"title":        task.Title,
+			"description":  task.Description,
+			"status":       task.Status,
+			"lead_user":    userProfile(&task.LeadUser),
 			"project_id":   task.ProjectID,
 			"priority":     task.Priority,
 			"complexity":   task.Complexity,
 			"progress":     task.Progress,
 			"assigned_at":  task.AssignedAt,
 			"due_date":     task.DueDate,
+			"started_at":   task.StartedAt,
+			"completed_at": task.CompletedAt,
 			"created_at":   task.CreatedAt,
 			"participants": participants,
 		},
 	})
 }
 
-// GetCollaborativeTaskStatistics returns statistics for a collaborative task
+// GetCollaborativeTaskStatistics returns statistics for a collaborative task (anyone who may view it)
 func (h *CollaborativeTaskHandler) GetCollaborativeTaskStatistics(c *gin.Context) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -208,22 +300,31 @@ func (h *CollaborativeTaskHandler) GetCollaborativeTaskStatistics(c *gin.Context
 		return
 	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
-	stats, err := collaborativeTaskService.GetCollaborativeTaskStatistics(uint(taskID))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	stats, err := collaborativeTaskService.GetCollaborativeTaskStatistics(actor, uint(taskID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"statistics": stats})
 }
 
-// GetUserCollaborativeTasks returns all collaborative tasks where user is a participant
+// GetUserCollaborativeTasks returns all collaborative tasks where user is a participant, optionally filtered by ?priority= and ordered by ?sort=
 func (h *CollaborativeTaskHandler) GetUserCollaborativeTasks(c *gin.Context) {
 	userID, _ := c.Get("userID")
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
 
-	collaborativeTaskService := services.NewCollaborativeTaskService(h.DB)
-	tasks, err := collaborativeTaskService.GetUserCollaborativeTasks(userID.(uint))
+	collaborativeTaskService := services.NewCollaborativeTaskService(workspaceDB(c, h.DB))
+	tasks, err := collaborativeTaskService.GetUserCollaborativeTasks(userID.(uint), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborative tasks"})
 		return
@@ -236,10 +337,7 @@ func (h *CollaborativeTaskHandler) GetUserCollaborativeTasks(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
-			"lead_user": gin.H{
-				"id":       task.LeadUser.ID,
-				"username": task.LeadUser.Username,
-			},
+			"lead_user":   userProfile(&task.LeadUser),
 			"project_id":  task.ProjectID,
 			"priority":    task.Priority,
 			"complexity":  task.Complexity,
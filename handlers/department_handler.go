@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DepartmentHandler struct {
+	DB *gorm.DB
+}
+
+func NewDepartmentHandler(db *gorm.DB) *DepartmentHandler {
+	return &DepartmentHandler{DB: db}
+}
+
+// departmentRequest is the body for creating and updating a department
+type departmentRequest struct {
+	Name       string `json:"name" binding:"required"`
+	ParentID   *uint  `json:"parent_id"`    // Omit for a top-level department
+	HeadUserID *uint  `json:"head_user_id"` // Omit for no head
+}
+
+// ListDepartments returns every department (all authenticated users)
+func (h *DepartmentHandler) ListDepartments(c *gin.Context) {
+	departmentService := services.NewDepartmentService(workspaceDB(c, h.DB))
+	departments, err := departmentService.ListDepartments()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch departments"})
+		return
+	}
+
+	var departmentList []gin.H
+	for _, department := range departments {
+		departmentList = append(departmentList, h.departmentResponse(&department))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"departments": departmentList})
+}
+
+// GetDepartment returns a department with its parent and sub-departments (all authenticated users)
+func (h *DepartmentHandler) GetDepartment(c *gin.Context) {
+	departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
+		return
+	}
+
+	departmentService := services.NewDepartmentService(workspaceDB(c, h.DB))
+	department, err := departmentService.GetDepartment(uint(departmentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+		return
+	}
+
+	response := h.departmentResponse(department)
+	if department.Parent != nil {
+		response["parent"] = gin.H{"id": department.Parent.ID, "name": department.Parent.Name}
+	}
+	subDepartments := []gin.H{}
+	for _, child := range department.Children {
+		subDepartments = append(subDepartments, gin.H{"id": child.ID, "name": child.Name})
+	}
+	response["sub_departments"] = subDepartments
+
+	c.JSON(http.StatusOK, gin.H{"department": response})
+}
+
+// CreateDepartment creates a department (department.manage)
+func (h *DepartmentHandler) CreateDepartment(c *gin.Context) {
+	var createRequest departmentRequest
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	departmentService := services.NewDepartmentService(workspaceDB(c, h.DB))
+	department, err := departmentService.CreateDepartment(createRequest.Name, createRequest.ParentID, createRequest.HeadUserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Department created successfully",
+		"department": h.departmentResponse(department),
+	})
+}
+
+// UpdateDepartment renames, moves or changes the head of a department (department.manage)
+func (h *DepartmentHandler) UpdateDepartment(c *gin.Context) {
+	departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
+		return
+	}
+
+	var updateRequest departmentRequest
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	departmentService := services.NewDepartmentService(workspaceDB(c, h.DB))
+	department, err := departmentService.UpdateDepartment(uint(departmentID), updateRequest.Name, updateRequest.ParentID, updateRequest.HeadUserID)
+	if errors.Is(err, services.ErrDepartmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Department updated successfully",
+		"department": h.departmentResponse(department),
+	})
+}
+
+// DeleteDepartment deletes an empty department (department.manage)
+func (h *DepartmentHandler) DeleteDepartment(c *gin.Context) {
+	departmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
+		return
+	}
+
+	// Departments are shared by all workspaces, so the user check must see every workspace
+	departmentService := services.NewDepartmentService(h.DB)
+	err = departmentService.DeleteDepartment(uint(departmentID))
+	if errors.Is(err, services.ErrDepartmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Department deleted successfully"})
+}
+
+// GetDepartmentReport returns task statistics for a department rolled up through its
+// sub-departments (report.view, for departments the caller has access to)
+func (h *DepartmentHandler) GetDepartmentReport(c *gin.Context) {
+	parsedDepartmentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
+		return
+	}
+	departmentID := uint(parsedDepartmentID)
+
+	user, _ := c.Get("user")
+	scope, err := services.NewDepartmentAccessService(workspaceDB(c, h.DB)).ScopeFor(user.(*models.User))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve department access"})
+		return
+	}
+	if !scope.Allows(&departmentID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view reports for your own departments"})
+		return
+	}
+
+	departmentService := services.NewDepartmentService(workspaceDB(c, h.DB))
+	report, err := departmentService.GetDepartmentReport(departmentID)
+	if errors.Is(err, services.ErrDepartmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Department not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate department report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// departmentResponse formats a department and its head
+func (h *DepartmentHandler) departmentResponse(department *models.Department) gin.H {
+	response := gin.H{
+		"id":           department.ID,
+		"name":         department.Name,
+		"parent_id":    department.ParentID,
+		"head_user_id": department.HeadUserID,
+		"created_at":   department.CreatedAt,
+	}
+	if department.Head != nil {
+		response["head"] = gin.H{"id": department.Head.ID, "username": department.Head.Username}
+	}
+	return response
+}
+
+// departmentName returns a loaded department's name, or "" when there is none
+func departmentName(department *models.Department) string {
+	if department == nil {
+		return ""
+	}
+	return department.Name
+}
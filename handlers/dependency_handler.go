@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DependencyHandler serves the blocks/blocked-by links between tasks and collaborative tasks.
+// Each endpoint exists under /api/tasks and /api/collaborative-tasks.
+type DependencyHandler struct {
+	DB *gorm.DB
+}
+
+func NewDependencyHandler(db *gorm.DB) *DependencyHandler {
+	return &DependencyHandler{DB: db}
+}
+
+// GetTaskDependencies lists the tasks blocking a task and the tasks it blocks (anyone who may view it)
+func (h *DependencyHandler) GetTaskDependencies(c *gin.Context) {
+	h.getDependencies(c, models.TaskKindTask)
+}
+
+// GetCollaborativeTaskDependencies lists the tasks blocking a collaborative task and the tasks it
+// blocks (anyone who may view it)
+func (h *DependencyHandler) GetCollaborativeTaskDependencies(c *gin.Context) {
+	h.getDependencies(c, models.TaskKindCollaborative)
+}
+
+// AddTaskDependency links a task to a task it waits for or holds up
+func (h *DependencyHandler) AddTaskDependency(c *gin.Context) {
+	h.addDependency(c, models.TaskKindTask)
+}
+
+// AddCollaborativeTaskDependency links a collaborative task to a task it waits for or holds up
+func (h *DependencyHandler) AddCollaborativeTaskDependency(c *gin.Context) {
+	h.addDependency(c, models.TaskKindCollaborative)
+}
+
+// RemoveTaskDependency removes one of a task's dependencies
+func (h *DependencyHandler) RemoveTaskDependency(c *gin.Context) {
+	h.removeDependency(c, models.TaskKindTask)
+}
+
+// RemoveCollaborativeTaskDependency removes one of a collaborative task's dependencies
+func (h *DependencyHandler) RemoveCollaborativeTaskDependency(c *gin.Context) {
+	h.removeDependency(c, models.TaskKindCollaborative)
+}
+
+func (h *DependencyHandler) getDependencies(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	dependencyService := services.NewDependencyService(workspaceDB(c, h.DB))
+	dependencies, err := dependencyService.GetDependencies(actor, kind, taskID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	blockedBy := make([]gin.H, 0, len(dependencies.BlockedBy))
+	for _, link := range dependencies.BlockedBy {
+		blockedBy = append(blockedBy, dependencyLinkResponse(link))
+	}
+	blocks := make([]gin.H, 0, len(dependencies.Blocks))
+	for _, link := range dependencies.Blocks {
+		blocks = append(blocks, dependencyLinkResponse(link))
+	}
+	c.JSON(http.StatusOK, gin.H{"blocked": dependencies.Blocked, "blocked_by": blockedBy, "blocks": blocks})
+}
+
+// addDependency reads {"relation": "blocked_by" or "blocks", "task_kind": ..., "task_id": ...}
+// naming the other task and how the task in the path relates to it
+func (h *DependencyHandler) addDependency(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	var addRequest struct {
+		Relation string `json:"relation" binding:"required"`
+		TaskKind string `json:"task_kind"` // Optional: defaults to "task"
+		TaskID   uint   `json:"task_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&addRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if addRequest.TaskKind == "" {
+		addRequest.TaskKind = models.TaskKindTask
+	}
+	if addRequest.TaskKind != models.TaskKindTask && addRequest.TaskKind != models.TaskKindCollaborative {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task kind. Must be 'task' or 'collaborative_task'"})
+		return
+	}
+
+	task := services.TaskRef{Kind: kind, ID: taskID}
+	other := services.TaskRef{Kind: addRequest.TaskKind, ID: addRequest.TaskID}
+	blocked, blocker := task, other
+	switch addRequest.Relation {
+	case "blocked_by":
+	case "blocks":
+		blocked, blocker = other, task
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relation. Must be 'blocked_by' or 'blocks'"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	dependencyService := services.NewDependencyService(workspaceDB(c, h.DB))
+	dependency, err := dependencyService.AddDependency(actor, blocked, blocker)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Dependency added successfully",
+		"dependency": gin.H{
+			"id":         dependency.ID,
+			"blocker":    blocker,
+			"blocked":    blocked,
+			"created_by": dependency.CreatedBy,
+			"created_at": dependency.CreatedAt,
+		},
+	})
+}
+
+func (h *DependencyHandler) removeDependency(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+	dependencyID, ok := idParam(c, "dependencyId", "Invalid dependency ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	dependencyService := services.NewDependencyService(workspaceDB(c, h.DB))
+	if err := dependencyService.RemoveDependency(actor, kind, taskID, dependencyID); err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency removed successfully"})
+}
+
+// dependencyLinkResponse describes the task at the other end of a dependency
+func dependencyLinkResponse(link services.DependencyLink) gin.H {
+	return gin.H{
+		"id":         link.DependencyID,
+		"task_kind":  link.Task.Kind,
+		"task_id":    link.Task.ID,
+		"title":      link.Title,
+		"status":     link.Status,
+		"project_id": link.ProjectID,
+		"created_by": link.CreatedBy,
+		"created_at": link.CreatedAt,
+	}
+}
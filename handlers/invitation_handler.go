@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type InvitationHandler struct {
+	DB *gorm.DB
+}
+
+func NewInvitationHandler(db *gorm.DB) *InvitationHandler {
+	return &InvitationHandler{DB: db}
+}
+
+// CreateInvitation creates a single-use invite with a preset role and department and sends its
+// token to the invitee's email address (Admin only)
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var createInvitationRequest struct {
+		Email        string `json:"email" binding:"required"`
+		Role         string `json:"role" binding:"required"`
+		DepartmentID uint   `json:"department_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&createInvitationRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	invitationService := services.NewInvitationService(workspaceDB(c, h.DB))
+	invitation, err := invitationService.CreateInvitation(
+		actor,
+		createInvitationRequest.Email,
+		createInvitationRequest.Role,
+		createInvitationRequest.DepartmentID,
+	)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation created and sent successfully",
+		"invitation": gin.H{
+			"id":            invitation.ID,
+			"email":         invitation.Email,
+			"role":          invitation.Role,
+			"department_id": invitation.DepartmentID,
+			"expires_at":    invitation.ExpiresAt,
+			"created_at":    invitation.CreatedAt,
+		},
+	})
+}
+
+// ListInvitations returns pending invitations (Admin only)
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	invitationService := services.NewInvitationService(workspaceDB(c, h.DB))
+	invitations, err := invitationService.GetPendingInvitations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	var invitationList []gin.H
+	for _, invitation := range invitations {
+		invitationList = append(invitationList, gin.H{
+			"id":            invitation.ID,
+			"email":         invitation.Email,
+			"role":          invitation.Role,
+			"department_id": invitation.DepartmentID,
+			"department":    departmentName(invitation.Department),
+			"invited_by": gin.H{
+				"id":       invitation.Inviter.ID,
+				"username": invitation.Inviter.Username,
+			},
+			"expires_at": invitation.ExpiresAt,
+			"created_at": invitation.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitationList})
+}
+
+// ResendInvitation sends a fresh token for an open invitation to the invitee (Admin only)
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	invitationID, err := strconv.ParseUint(c.Param("inviteId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	invitationService := services.NewInvitationService(workspaceDB(c, h.DB))
+	invitation, err := invitationService.ResendInvitation(actor, uint(invitationID))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation resent successfully",
+		"invitation": gin.H{
+			"id":         invitation.ID,
+			"email":      invitation.Email,
+			"expires_at": invitation.ExpiresAt,
+		},
+	})
+}
+
+// RevokeInvitation revokes an open invitation (Admin only)
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	invitationID, err := strconv.ParseUint(c.Param("inviteId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	invitationService := services.NewInvitationService(workspaceDB(c, h.DB))
+	if err := invitationService.RevokeInvitation(uint(invitationID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// AcceptInvitation lets an invitee choose their username and password (public)
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var acceptRequest struct {
+		Token    string `json:"token" binding:"required"`
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&acceptRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitationService := services.NewInvitationService(workspaceDB(c, h.DB))
+	user, tokens, err := invitationService.AcceptInvitation(
+		acceptRequest.Token,
+		acceptRequest.Username,
+		acceptRequest.Password,
+		c.Request.UserAgent(),
+		c.ClientIP(),
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Account created successfully",
+		"user": gin.H{
+			"id":            user.ID,
+			"username":      user.Username,
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"created_at":    user.CreatedAt,
+		},
+		"tokens": tokens,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OIDCHandler struct {
+	DB *gorm.DB
+}
+
+func NewOIDCHandler(db *gorm.DB) *OIDCHandler {
+	return &OIDCHandler{DB: db}
+}
+
+// Login redirects the user to the identity provider
+func (h *OIDCHandler) Login(c *gin.Context) {
+	oidcService := services.NewOIDCService(h.DB)
+	if !oidcService.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	authURL, err := oidcService.BeginLogin()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Desktop clients can ask for the URL instead of following a redirect
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles the identity provider redirect and issues our own tokens
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if errorCode := c.Query("error"); errorCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":             "Single sign-on failed",
+			"provider_error":    errorCode,
+			"error_description": c.Query("error_description"),
+		})
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state and code are required"})
+		return
+	}
+
+	oidcService := services.NewOIDCService(h.DB)
+	result, err := oidcService.CompleteLogin(state, code, c.Request.UserAgent(), c.ClientIP())
+	switch {
+	case errors.Is(err, services.ErrOIDCAccountExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrAccountLocked):
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrAccountDeactivated):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Users with 2FA finish through POST /auth/2fa/verify, as after a password login
+	c.JSON(http.StatusOK, result)
+}
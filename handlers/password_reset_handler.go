@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PasswordResetHandler struct {
+	DB *gorm.DB
+}
+
+func NewPasswordResetHandler(db *gorm.DB) *PasswordResetHandler {
+	return &PasswordResetHandler{DB: db}
+}
+
+// RequestReset sends a password reset token to the user (public)
+func (h *PasswordResetHandler) RequestReset(c *gin.Context) {
+	var resetRequest struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&resetRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services.NewPasswordResetService(h.DB).RequestReset(resetRequest.Username)
+
+	// Same response whether or not the account exists or the token could be sent
+	c.JSON(http.StatusOK, gin.H{"message": "If the account exists, a password reset token has been sent"})
+}
+
+// ConfirmReset sets a new password with a reset token (public)
+func (h *PasswordResetHandler) ConfirmReset(c *gin.Context) {
+	var confirmRequest struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&confirmRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordResetService := services.NewPasswordResetService(h.DB)
+	if err := passwordResetService.ConfirmReset(confirmRequest.Token, confirmRequest.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
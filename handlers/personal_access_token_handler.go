@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PersonalAccessTokenHandler struct {
+	DB *gorm.DB
+}
+
+func NewPersonalAccessTokenHandler(db *gorm.DB) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{DB: db}
+}
+
+// CreateToken creates a personal access token for the current user (self only)
+func (h *PersonalAccessTokenHandler) CreateToken(c *gin.Context) {
+	var createTokenRequest struct {
+		Name      string     `json:"name" binding:"required"`
+		Scopes    []string   `json:"scopes" binding:"required"` // e.g. ["tasks:write", "*:read"]
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := c.ShouldBindJSON(&createTokenRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	tokenService := services.NewPersonalAccessTokenService(workspaceDB(c, h.DB))
+	token, plaintext, err := tokenService.CreateToken(actor, createTokenRequest.Name, createTokenRequest.Scopes, createTokenRequest.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Token created successfully. Copy it now, it will not be shown again",
+		"token":   plaintext,
+		"token_info": gin.H{
+			"id":         token.ID,
+			"name":       token.Name,
+			"prefix":     token.TokenPrefix,
+			"scopes":     token.ScopeList(),
+			"expires_at": token.ExpiresAt,
+			"created_at": token.CreatedAt,
+		},
+	})
+}
+
+// ListTokens returns a user's personal access tokens (Admin or self)
+func (h *PersonalAccessTokenHandler) ListTokens(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tokenService := services.NewPersonalAccessTokenService(workspaceDB(c, h.DB))
+	tokens, err := tokenService.GetUserTokens(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	var tokenList []gin.H
+	for _, token := range tokens {
+		tokenList = append(tokenList, gin.H{
+			"id":           token.ID,
+			"name":         token.Name,
+			"prefix":       token.TokenPrefix,
+			"scopes":       token.ScopeList(),
+			"expires_at":   token.ExpiresAt,
+			"last_used_at": token.LastUsedAt,
+			"revoked_at":   token.RevokedAt,
+			"active":       token.IsActive(),
+			"created_at":   token.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokenList})
+}
+
+// RevokeToken revokes a personal access token (Admin or self)
+func (h *PersonalAccessTokenHandler) RevokeToken(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("tokenId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	tokenService := services.NewPersonalAccessTokenService(workspaceDB(c, h.DB))
+	if err := tokenService.RevokeToken(uint(userID), uint(tokenID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"project-x/config"
+	"project-x/models"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ProfileHandler struct {
+	DB *gorm.DB
+}
+
+func NewProfileHandler(db *gorm.DB) *ProfileHandler {
+	return &ProfileHandler{DB: db}
+}
+
+// GetProfile returns the current user's profile
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	userService := services.NewUserService(workspaceDB(c, h.DB))
+	user, err := userService.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": h.profileResponse(user)})
+}
+
+// UpdateProfile changes the current user's email, display name, timezone or locale.
+// Fields left out of the body are not changed.
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	var updateRequest services.ProfileUpdate
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	profileService := services.NewProfileService(workspaceDB(c, h.DB))
+	user, err := profileService.UpdateProfile(userID.(uint), updateRequest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"user":    h.profileResponse(user),
+	})
+}
+
+// UploadAvatar replaces the current user's avatar with the "avatar" file of a multipart form
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	// Stop reading oversized uploads early; the limit leaves room for the form encoding
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.Get().AvatarMaxBytes+64<<10)
+
+	fileHeader, err := c.FormFile("avatar")
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "avatar file is required"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read avatar file"})
+		return
+	}
+	defer file.Close()
+
+	userID, _ := c.Get("userID")
+	profileService := services.NewProfileService(workspaceDB(c, h.DB))
+	user, err := profileService.SetAvatar(userID.(uint), file)
+	switch {
+	case errors.Is(err, services.ErrAvatarTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrAvatarUnsupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save avatar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar updated successfully",
+		"user":    h.profileResponse(user),
+	})
+}
+
+// DeleteAvatar removes the current user's avatar
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	profileService := services.NewProfileService(workspaceDB(c, h.DB))
+	user, err := profileService.RemoveAvatar(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove avatar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar removed successfully",
+		"user":    h.profileResponse(user),
+	})
+}
+
+// profileResponse formats the full profile, only shown to the user themselves
+func (h *ProfileHandler) profileResponse(user *models.User) gin.H {
+	return gin.H{
+		"id":             user.ID,
+		"username":       user.Username,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"display_name":   user.Name(),
+		"avatar_url":     services.AvatarURL(user),
+		"timezone":       user.Timezone,
+		"locale":         user.Locale,
+		"role":           user.Role,
+		"department_id":  user.DepartmentID,
+		"department":     user.DepartmentName(),
+		"manager_id":     user.ManagerID,
+		"created_at":     user.CreatedAt,
+	}
+}
+
+// userProfile formats the public profile shown wherever a user appears inside another
+// resource, such as a task's owner or a project member
+func userProfile(user *models.User) gin.H {
+	return gin.H{
+		"id":           user.ID,
+		"username":     user.Username,
+		"display_name": user.Name(),
+		"avatar_url":   services.AvatarURL(user),
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"project-x/models"
 	"project-x/services"
@@ -36,7 +37,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 	// Get current user ID from context
 	userID, _ := c.Get("userID")
 
-	projectService := services.NewProjectService(h.DB)
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
 	project, err := projectService.CreateProject(
 		createProjectRequest.Title,
 		createProjectRequest.Description,
@@ -68,7 +69,7 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 func (h *ProjectHandler) GetUserProjects(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
-	projectService := services.NewProjectService(h.DB)
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
 	projects, err := projectService.GetUserProjects(userID.(uint))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
@@ -100,7 +101,7 @@ func (h *ProjectHandler) GetProjectDetails(c *gin.Context) {
 		return
 	}
 
-	projectService := services.NewProjectService(h.DB)
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
 	project, err := projectService.GetProjectWithDetails(uint(projectID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
@@ -118,9 +119,11 @@ func (h *ProjectHandler) GetProjectDetails(c *gin.Context) {
 			"end_date":    project.EndDate,
 			"created_at":  project.CreatedAt,
 			"creator": gin.H{
-				"id":       project.Creator.ID,
-				"username": project.Creator.Username,
-				"role":     project.Creator.Role,
+				"id":           project.Creator.ID,
+				"username":     project.Creator.Username,
+				"display_name": project.Creator.Name(),
+				"avatar_url":   services.AvatarURL(&project.Creator),
+				"role":         project.Creator.Role,
 			},
 			"member_count": len(project.Users),
 			"task_count":   len(project.Tasks) + len(project.CollaborativeTasks),
@@ -136,27 +139,32 @@ func (h *ProjectHandler) GetProjectMembers(c *gin.Context) {
 		return
 	}
 
-	projectService := services.NewProjectService(h.DB)
-	members, err := projectService.GetProjectMembers(uint(projectID))
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
+	memberships, err := projectService.GetProjectMemberships(uint(projectID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project members"})
 		return
 	}
 
 	var memberList []gin.H
-	for _, member := range members {
+	for _, membership := range memberships {
 		memberList = append(memberList, gin.H{
-			"id":         member.ID,
-			"username":   member.Username,
-			"role":       member.Role,
-			"department": member.Department,
+			"id":            membership.User.ID,
+			"username":      membership.User.Username,
+			"display_name":  membership.User.Name(),
+			"avatar_url":    services.AvatarURL(&membership.User),
+			"role":          membership.User.Role,
+			"department_id": membership.User.DepartmentID,
+			"department":    membership.User.DepartmentName(),
+			"project_role":  membership.Role,
+			"joined_at":     membership.JoinedAt,
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{"members": memberList})
 }
 
-// AddUserToProject adds a user to a project (Manager/Admin only)
+// AddUserToProject adds a user to a project (project.manage_members, globally or as a project lead)
 func (h *ProjectHandler) AddUserToProject(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -174,17 +182,22 @@ func (h *ProjectHandler) AddUserToProject(c *gin.Context) {
 		return
 	}
 
-	projectService := services.NewProjectService(h.DB)
-	err = projectService.AddUserToProject(addUserRequest.UserID, uint(projectID), addUserRequest.Role)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
+	err = projectService.AddUserToProject(actor, addUserRequest.UserID, uint(projectID), addUserRequest.Role)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User added to project successfully"})
 }
 
-// RemoveUserFromProject removes a user from a project (Manager/Admin only)
+// RemoveUserFromProject removes a user from a project (project.manage_members, globally or as a project lead)
 func (h *ProjectHandler) RemoveUserFromProject(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -198,17 +211,22 @@ func (h *ProjectHandler) RemoveUserFromProject(c *gin.Context) {
 		return
 	}
 
-	projectService := services.NewProjectService(h.DB)
-	err = projectService.RemoveUserFromProject(uint(userID), uint(projectID))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
+	err = projectService.RemoveUserFromProject(actor, uint(userID), uint(projectID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User removed from project successfully"})
 }
 
-// UpdateProjectStatus updates project status (Manager/Admin only)
+// UpdateProjectStatus updates project status (project.update_status, globally or as the project manager)
 func (h *ProjectHandler) UpdateProjectStatus(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -239,17 +257,22 @@ func (h *ProjectHandler) UpdateProjectStatus(c *gin.Context) {
 		return
 	}
 
-	projectService := services.NewProjectService(h.DB)
-	err = projectService.UpdateProjectStatus(uint(projectID), models.ProjectStatus(updateRequest.Status))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
+	err = projectService.UpdateProjectStatus(actor, uint(projectID), models.ProjectStatus(updateRequest.Status))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project status"})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Project status updated successfully"})
 }
 
-// DeleteProject deletes a project (Admin only)
+// DeleteProject deletes a project (project.delete)
 func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -257,10 +280,15 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 		return
 	}
 
-	projectService := services.NewProjectService(h.DB)
-	err = projectService.DeleteProject(uint(projectID))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
+	err = projectService.DeleteProject(actor, uint(projectID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -276,7 +304,7 @@ func (h *ProjectHandler) GetProjectStatistics(c *gin.Context) {
 	}
 
 	// Get project details
-	projectService := services.NewProjectService(h.DB)
+	projectService := services.NewProjectService(workspaceDB(c, h.DB))
 	project, err := projectService.GetProjectWithDetails(uint(projectID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
@@ -334,3 +362,49 @@ func (h *ProjectHandler) GetProjectStatistics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"statistics": stats})
 }
+
+// GetDependencyGraph returns the tasks of a project as nodes and their blocks/blocked-by links as
+// edges. Tasks of other projects linked to the project appear as external nodes without a title.
+func (h *ProjectHandler) GetDependencyGraph(c *gin.Context) {
+	projectID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	dependencyService := services.NewDependencyService(workspaceDB(c, h.DB))
+	graph, err := dependencyService.GetDependencyGraph(uint(projectID))
+	if errors.Is(err, services.ErrProjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the dependency graph"})
+		return
+	}
+
+	nodes := make([]gin.H, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes = append(nodes, gin.H{
+			"id":         node.Task.String(),
+			"kind":       node.Task.Kind,
+			"task_id":    node.Task.ID,
+			"title":      node.Title,
+			"status":     node.Status,
+			"priority":   node.Priority,
+			"project_id": node.ProjectID,
+			"blocked":    node.Blocked,
+			"external":   node.External,
+		})
+	}
+	edges := make([]gin.H, 0, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		edges = append(edges, gin.H{
+			"dependency_id": edge.DependencyID,
+			"from":          edge.From.String(), // The blocker
+			"to":            edge.To.String(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"project_id": projectID, "nodes": nodes, "edges": edges})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RoleHandler struct {
+	DB *gorm.DB
+}
+
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{DB: db}
+}
+
+// ListPermissions returns the permission catalog
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	permissionService := services.NewPermissionService(h.DB)
+	permissions, err := permissionService.ListPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch permissions"})
+		return
+	}
+
+	var permissionList []gin.H
+	for _, permission := range permissions {
+		permissionList = append(permissionList, gin.H{
+			"name":        permission.Name,
+			"description": permission.Description,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"permissions": permissionList})
+}
+
+// ListRoles returns every role with its permissions
+func (h *RoleHandler) ListRoles(c *gin.Context) {
+	permissionService := services.NewPermissionService(h.DB)
+	roles, err := permissionService.ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	var roleList []gin.H
+	for _, role := range roles {
+		roleList = append(roleList, h.roleResponse(&role))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roleList})
+}
+
+// GetRole returns a single role with its permissions
+func (h *RoleHandler) GetRole(c *gin.Context) {
+	permissionService := services.NewPermissionService(h.DB)
+	role, err := permissionService.GetRole(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"role": h.roleResponse(role)})
+}
+
+// CreateRole creates a custom role
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var createRoleRequest struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"` // e.g. ["report.view", "task.view_all"]
+	}
+
+	if err := c.ShouldBindJSON(&createRoleRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	permissionService := services.NewPermissionService(h.DB)
+	role, err := permissionService.CreateRole(actor, createRoleRequest.Name, createRoleRequest.Description, createRoleRequest.Permissions)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Role created successfully",
+		"role":    h.roleResponse(role),
+	})
+}
+
+// UpdateRolePermissions replaces the permissions of a role
+func (h *RoleHandler) UpdateRolePermissions(c *gin.Context) {
+	var updateRequest struct {
+		Permissions []string `json:"permissions" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	permissionService := services.NewPermissionService(h.DB)
+	role, err := permissionService.SetRolePermissions(actor, c.Param("name"), updateRequest.Permissions)
+	if errors.Is(err, services.ErrRoleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role permissions updated successfully",
+		"role":    h.roleResponse(role),
+	})
+}
+
+// DeleteRole deletes a custom role that is not assigned to any user
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	permissionService := services.NewPermissionService(h.DB)
+	err := permissionService.DeleteRole(c.Param("name"))
+	if errors.Is(err, services.ErrRoleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// roleResponse formats a role and its permission names
+func (h *RoleHandler) roleResponse(role *models.RoleDefinition) gin.H {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, grant := range role.Permissions {
+		permissions = append(permissions, grant.Permission)
+	}
+
+	return gin.H{
+		"name":        role.Name,
+		"description": role.Description,
+		"built_in":    role.BuiltIn,
+		"permissions": permissions,
+		"created_at":  role.CreatedAt,
+	}
+}
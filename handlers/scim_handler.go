@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"project-x/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SCIMHandler serves the SCIM 2.0 protocol (RFC 7644) to identity providers
+type SCIMHandler struct {
+	DB *gorm.DB
+}
+
+func NewSCIMHandler(db *gorm.DB) *SCIMHandler {
+	return &SCIMHandler{DB: db}
+}
+
+// scimPatchRequest is the body of a PATCH request
+type scimPatchRequest struct {
+	Schemas    []string                      `json:"schemas"`
+	Operations []services.SCIMPatchOperation `json:"Operations" binding:"required,dive"`
+}
+
+// GetServiceProviderConfig describes the supported SCIM features
+func (h *SCIMHandler) GetServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": services.SCIMMaxResults},
+		"changePassword": gin.H{"supported": true},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "A provisioning token created with POST /workspaces/current/scim-tokens",
+			"primary":     true,
+		}},
+		"meta": gin.H{"resourceType": "ServiceProviderConfig", "location": services.SCIMBasePath + "/ServiceProviderConfig"},
+	})
+}
+
+// GetResourceTypes lists the resource types served
+func (h *SCIMHandler) GetResourceTypes(c *gin.Context) {
+	resourceTypes := []gin.H{
+		{
+			"schemas":          []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+			"id":               "User",
+			"name":             "User",
+			"endpoint":         "/Users",
+			"schema":           services.SCIMUserSchema,
+			"schemaExtensions": []gin.H{{"schema": services.SCIMEnterpriseUserSchema, "required": false}},
+			"meta":             gin.H{"resourceType": "ResourceType", "location": services.SCIMBasePath + "/ResourceTypes/User"},
+		},
+		{
+			"schemas":     []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"},
+			"id":          "Group",
+			"name":        "Group",
+			"endpoint":    "/Groups",
+			"description": "Roles (role-<name>) and departments (department-<id>)",
+			"schema":      services.SCIMGroupSchema,
+			"meta":        gin.H{"resourceType": "ResourceType", "location": services.SCIMBasePath + "/ResourceTypes/Group"},
+		},
+	}
+	scimJSON(c, http.StatusOK, scimListResponse(resourceTypes, len(resourceTypes), 1))
+}
+
+// ListUsers returns the workspace's users, optionally filtered, e.g. ?filter=userName eq "alice"
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	startIndex, count := scimPage(c)
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	users, total, err := scimService.ListUsers(c.Query("filter"), startIndex, count)
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	resources := make([]services.SCIMUserResource, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, services.NewSCIMUserResource(&user))
+	}
+	scimJSON(c, http.StatusOK, scimListResponse(resources, int(total), startIndex))
+}
+
+// GetUser returns a user
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	user, err := scimService.GetUser(c.Param("id"))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, services.NewSCIMUserResource(user))
+}
+
+// CreateUser provisions a user
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var resource services.SCIMUserResource
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", services.ErrSCIMInvalidSyntax, err))
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	user, err := scimService.CreateUser(&resource, scimActorID(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	created := services.NewSCIMUserResource(user)
+	c.Header("Location", created.Meta.Location)
+	scimJSON(c, http.StatusCreated, created)
+}
+
+// ReplaceUser replaces a user's attributes
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var resource services.SCIMUserResource
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", services.ErrSCIMInvalidSyntax, err))
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	user, err := scimService.ReplaceUser(c.Param("id"), &resource, scimActorID(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, services.NewSCIMUserResource(user))
+}
+
+// PatchUser changes single attributes of a user, e.g. {"op": "replace", "path": "active", "value": false}
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var patchRequest scimPatchRequest
+	if err := c.ShouldBindJSON(&patchRequest); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", services.ErrSCIMInvalidSyntax, err))
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	user, err := scimService.PatchUser(c.Param("id"), patchRequest.Operations, scimActorID(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, services.NewSCIMUserResource(user))
+}
+
+// DeleteUser deactivates a user, handing their open work to their manager
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	if err := scimService.DeleteUser(c.Param("id"), scimActorID(c)); err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups returns the workspace's roles and departments as groups. Send
+// ?excludedAttributes=members to leave out the member lists.
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	startIndex, count := scimPage(c)
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	groups, total, err := scimService.ListGroups(c.Query("filter"), startIndex, count, scimWantsMembers(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, scimListResponse(groups, total, startIndex))
+}
+
+// GetGroup returns a group with its members
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	group, err := scimService.GetGroup(c.Param("id"), scimWantsMembers(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+// CreateGroup is answered with 409 for groups that match a role or department, which lets
+// identity providers link them, and refused otherwise
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var resource services.SCIMGroupResource
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", services.ErrSCIMInvalidSyntax, err))
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	writeSCIMError(c, scimService.CreateGroup(&resource))
+}
+
+// ReplaceGroup sets a group's members
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var resource services.SCIMGroupResource
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", services.ErrSCIMInvalidSyntax, err))
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	group, err := scimService.ReplaceGroup(c.Param("id"), &resource, scimActorID(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+// PatchGroup adds and removes group members
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var patchRequest scimPatchRequest
+	if err := c.ShouldBindJSON(&patchRequest); err != nil {
+		writeSCIMError(c, fmt.Errorf("%w: %v", services.ErrSCIMInvalidSyntax, err))
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	group, err := scimService.PatchGroup(c.Param("id"), patchRequest.Operations, scimActorID(c))
+	if err != nil {
+		writeSCIMError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+// DeleteGroup is refused; roles and departments are deleted through their own endpoints
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	writeSCIMError(c, scimService.DeleteGroup(c.Param("id")))
+}
+
+// scimActorID is the admin who created the request's token
+func scimActorID(c *gin.Context) uint {
+	createdBy, _ := c.Get("scimTokenCreatedBy")
+	return createdBy.(uint)
+}
+
+// scimPage reads the 1-based startIndex and the page size, capped at SCIMMaxResults
+func scimPage(c *gin.Context) (int, int) {
+	startIndex, err := strconv.Atoi(c.Query("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(services.SCIMMaxResults)))
+	if err != nil || count > services.SCIMMaxResults {
+		count = services.SCIMMaxResults
+	}
+	if count < 0 {
+		count = 0
+	}
+	return startIndex, count
+}
+
+// scimWantsMembers reports whether group members should be returned
+func scimWantsMembers(c *gin.Context) bool {
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return false
+		}
+	}
+	if attributes := c.Query("attributes"); attributes != "" {
+		return strings.Contains(strings.ToLower(attributes), "members")
+	}
+	return true
+}
+
+func scimListResponse(resources interface{}, total, startIndex int) gin.H {
+	itemsPerPage := 0
+	switch list := resources.(type) {
+	case []services.SCIMUserResource:
+		itemsPerPage = len(list)
+	case []services.SCIMGroupResource:
+		itemsPerPage = len(list)
+	case []gin.H:
+		itemsPerPage = len(list)
+	}
+
+	return gin.H{
+		"schemas":      []string{services.SCIMListResponseSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": itemsPerPage,
+		"Resources":    resources,
+	}
+}
+
+// scimJSON writes a response with the SCIM media type
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(status, body)
+}
+
+// writeSCIMError writes a SCIM error response; scimType tells clients what went wrong
+func writeSCIMError(c *gin.Context, err error) {
+	status, scimType := http.StatusBadRequest, "invalidValue"
+	switch {
+	case errors.Is(err, services.ErrSCIMNotFound):
+		status, scimType = http.StatusNotFound, ""
+	case errors.Is(err, services.ErrUsernameTaken), errors.Is(err, services.ErrEmailTaken), errors.Is(err, services.ErrSCIMGroupExists):
+		status, scimType = http.StatusConflict, "uniqueness"
+	case errors.Is(err, services.ErrSCIMDeactivationBlocked):
+		status, scimType = http.StatusConflict, ""
+	case errors.Is(err, services.ErrSCIMGroupsFixed), errors.Is(err, services.ErrAccessDenied):
+		status, scimType = http.StatusForbidden, ""
+	case errors.Is(err, services.ErrSCIMInvalidFilter):
+		scimType = "invalidFilter"
+	case errors.Is(err, services.ErrSCIMInvalidSyntax):
+		scimType = "invalidSyntax"
+	case errors.Is(err, services.ErrSCIMInvalidPath):
+		scimType = "invalidPath"
+	case errors.Is(err, services.ErrSCIMNoTarget):
+		scimType = "noTarget"
+	case errors.Is(err, services.ErrSCIMMutability):
+		scimType = "mutability"
+	}
+
+	body := gin.H{
+		"schemas": []string{services.SCIMErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  err.Error(),
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	scimJSON(c, status, body)
+}
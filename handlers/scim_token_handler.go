@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SCIMTokenHandler struct {
+	DB *gorm.DB
+}
+
+func NewSCIMTokenHandler(db *gorm.DB) *SCIMTokenHandler {
+	return &SCIMTokenHandler{DB: db}
+}
+
+// CreateToken creates a token an identity provider uses to provision users into the current workspace
+func (h *SCIMTokenHandler) CreateToken(c *gin.Context) {
+	var createTokenRequest struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&createTokenRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	token, plaintext, err := scimService.CreateToken(createTokenRequest.Name, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Token created successfully. Copy it now, it will not be shown again",
+		"token":    plaintext,
+		"scim_url": services.SCIMBasePath,
+		"token_info": gin.H{
+			"id":           token.ID,
+			"name":         token.Name,
+			"prefix":       token.TokenPrefix,
+			"workspace_id": token.WorkspaceID,
+			"created_by":   token.CreatedBy,
+			"created_at":   token.CreatedAt,
+		},
+	})
+}
+
+// ListTokens returns the current workspace's provisioning tokens
+func (h *SCIMTokenHandler) ListTokens(c *gin.Context) {
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	tokens, err := scimService.ListTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+		return
+	}
+
+	var tokenList []gin.H
+	for _, token := range tokens {
+		tokenList = append(tokenList, gin.H{
+			"id":           token.ID,
+			"name":         token.Name,
+			"prefix":       token.TokenPrefix,
+			"created_by":   userProfile(&token.Creator),
+			"last_used_at": token.LastUsedAt,
+			"revoked_at":   token.RevokedAt,
+			"active":       token.IsActive(),
+			"created_at":   token.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokenList})
+}
+
+// RevokeToken revokes a provisioning token
+func (h *SCIMTokenHandler) RevokeToken(c *gin.Context) {
+	tokenID, err := strconv.ParseUint(c.Param("tokenId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	scimService := services.NewSCIMService(workspaceDB(c, h.DB))
+	if err := scimService.RevokeToken(uint(tokenID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SubtaskHandler serves the subtasks and checklists of tasks and collaborative tasks. Each
+// endpoint exists under /api/tasks and /api/collaborative-tasks.
+type SubtaskHandler struct {
+	DB *gorm.DB
+}
+
+func NewSubtaskHandler(db *gorm.DB) *SubtaskHandler {
+	return &SubtaskHandler{DB: db}
+}
+
+// GetTaskSubtasks lists the subtasks of a task (anyone who may view it)
+func (h *SubtaskHandler) GetTaskSubtasks(c *gin.Context) {
+	h.getSubtasks(c, models.TaskKindTask)
+}
+
+// GetCollaborativeTaskSubtasks lists the subtasks of a collaborative task (anyone who may view it)
+func (h *SubtaskHandler) GetCollaborativeTaskSubtasks(c *gin.Context) {
+	h.getSubtasks(c, models.TaskKindCollaborative)
+}
+
+// CreateTaskSubtask adds a subtask under a task (same access as editing it)
+func (h *SubtaskHandler) CreateTaskSubtask(c *gin.Context) {
+	h.createSubtask(c, models.TaskKindTask)
+}
+
+// CreateCollaborativeTaskSubtask adds a subtask under a collaborative task (same access as editing it)
+func (h *SubtaskHandler) CreateCollaborativeTaskSubtask(c *gin.Context) {
+	h.createSubtask(c, models.TaskKindCollaborative)
+}
+
+// ReorderTaskSubtasks sets the order of a task's subtasks
+func (h *SubtaskHandler) ReorderTaskSubtasks(c *gin.Context) {
+	h.reorder(c, models.TaskKindTask, services.NewSubtaskService(workspaceDB(c, h.DB)).ReorderSubtasks)
+}
+
+// ReorderCollaborativeTaskSubtasks sets the order of a collaborative task's subtasks
+func (h *SubtaskHandler) ReorderCollaborativeTaskSubtasks(c *gin.Context) {
+	h.reorder(c, models.TaskKindCollaborative, services.NewSubtaskService(workspaceDB(c, h.DB)).ReorderSubtasks)
+}
+
+// PromoteSubtask turns a subtask into a top-level task (same access as editing its parent)
+func (h *SubtaskHandler) PromoteSubtask(c *gin.Context) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	task, err := subtaskService.PromoteSubtask(actor, taskID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subtask promoted to a task", "task": subtaskResponse(task)})
+}
+
+// GetTaskChecklist lists the checklist of a task (anyone who may view it)
+func (h *SubtaskHandler) GetTaskChecklist(c *gin.Context) {
+	h.getChecklist(c, models.TaskKindTask)
+}
+
+// GetCollaborativeTaskChecklist lists the checklist of a collaborative task (anyone who may view it)
+func (h *SubtaskHandler) GetCollaborativeTaskChecklist(c *gin.Context) {
+	h.getChecklist(c, models.TaskKindCollaborative)
+}
+
+// AddTaskChecklistItem adds an item to a task's checklist (same access as editing it)
+func (h *SubtaskHandler) AddTaskChecklistItem(c *gin.Context) {
+	h.addChecklistItem(c, models.TaskKindTask)
+}
+
+// AddCollaborativeTaskChecklistItem adds an item to a collaborative task's checklist (same access as editing it)
+func (h *SubtaskHandler) AddCollaborativeTaskChecklistItem(c *gin.Context) {
+	h.addChecklistItem(c, models.TaskKindCollaborative)
+}
+
+// UpdateTaskChecklistItem renames or checks off an item of a task's checklist
+func (h *SubtaskHandler) UpdateTaskChecklistItem(c *gin.Context) {
+	h.updateChecklistItem(c, models.TaskKindTask)
+}
+
+// UpdateCollaborativeTaskChecklistItem renames or checks off an item of a collaborative task's
+// checklist; contributors may check items off
+func (h *SubtaskHandler) UpdateCollaborativeTaskChecklistItem(c *gin.Context) {
+	h.updateChecklistItem(c, models.TaskKindCollaborative)
+}
+
+// DeleteTaskChecklistItem removes an item from a task's checklist
+func (h *SubtaskHandler) DeleteTaskChecklistItem(c *gin.Context) {
+	h.deleteChecklistItem(c, models.TaskKindTask)
+}
+
+// DeleteCollaborativeTaskChecklistItem removes an item from a collaborative task's checklist
+func (h *SubtaskHandler) DeleteCollaborativeTaskChecklistItem(c *gin.Context) {
+	h.deleteChecklistItem(c, models.TaskKindCollaborative)
+}
+
+// ReorderTaskChecklist sets the order of a task's checklist
+func (h *SubtaskHandler) ReorderTaskChecklist(c *gin.Context) {
+	h.reorder(c, models.TaskKindTask, services.NewSubtaskService(workspaceDB(c, h.DB)).ReorderChecklist)
+}
+
+// ReorderCollaborativeTaskChecklist sets the order of a collaborative task's checklist
+func (h *SubtaskHandler) ReorderCollaborativeTaskChecklist(c *gin.Context) {
+	h.reorder(c, models.TaskKindCollaborative, services.NewSubtaskService(workspaceDB(c, h.DB)).ReorderChecklist)
+}
+
+// ConvertTaskChecklistItem turns an item of a task's checklist into a subtask
+func (h *SubtaskHandler) ConvertTaskChecklistItem(c *gin.Context) {
+	h.convertChecklistItem(c, models.TaskKindTask)
+}
+
+// ConvertCollaborativeTaskChecklistItem turns an item of a collaborative task's checklist into a subtask
+func (h *SubtaskHandler) ConvertCollaborativeTaskChecklistItem(c *gin.Context) {
+	h.convertChecklistItem(c, models.TaskKindCollaborative)
+}
+
+func (h *SubtaskHandler) getSubtasks(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	subtasks, err := subtaskService.GetSubtasks(actor, kind, taskID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	subtaskList := make([]gin.H, 0, len(subtasks))
+	for i := range subtasks {
+		subtaskList = append(subtaskList, subtaskResponse(&subtasks[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"subtasks": subtaskList})
+}
+
+func (h *SubtaskHandler) createSubtask(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	var createRequest struct {
+		Title       string     `json:"title" binding:"required"`
+		Description string     `json:"description"`
+		UserID      *uint      `json:"user_id"`  // Optional: defaults to the parent's owner or lead
+		DueDate     *time.Time `json:"due_date"` // Optional due date
+		Priority    string     `json:"priority"` // Optional: defaults to the parent's priority
+	}
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if createRequest.Priority != "" && !models.Priority(createRequest.Priority).Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidPriority.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	subtask, err := subtaskService.CreateSubtask(actor, kind, taskID, services.SubtaskInput{
+		Title:       createRequest.Title,
+		Description: createRequest.Description,
+		UserID:      createRequest.UserID,
+		DueDate:     createRequest.DueDate,
+		Priority:    models.Priority(createRequest.Priority),
+	})
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Subtask created successfully", "subtask": subtaskResponse(subtask)})
+}
+
+func (h *SubtaskHandler) getChecklist(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	items, err := subtaskService.GetChecklist(actor, kind, taskID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	itemList := make([]gin.H, 0, len(items))
+	for i := range items {
+		itemList = append(itemList, checklistItemResponse(&items[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"checklist": itemList})
+}
+
+func (h *SubtaskHandler) addChecklistItem(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	var addRequest struct {
+		Title string `json:"title" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&addRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	item, err := subtaskService.AddChecklistItem(actor, kind, taskID, addRequest.Title)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Checklist item added successfully", "item": checklistItemResponse(item)})
+}
+
+func (h *SubtaskHandler) updateChecklistItem(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+	itemID, ok := idParam(c, "itemId", "Invalid checklist item ID")
+	if !ok {
+		return
+	}
+
+	var updateRequest struct {
+		Title *string `json:"title"`
+		Done  *bool   `json:"done"`
+	}
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	item, err := subtaskService.UpdateChecklistItem(actor, kind, taskID, itemID, updateRequest.Title, updateRequest.Done)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist item updated successfully", "item": checklistItemResponse(item)})
+}
+
+func (h *SubtaskHandler) deleteChecklistItem(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+	itemID, ok := idParam(c, "itemId", "Invalid checklist item ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	if err := subtaskService.DeleteChecklistItem(actor, kind, taskID, itemID); err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist item deleted successfully"})
+}
+
+func (h *SubtaskHandler) convertChecklistItem(c *gin.Context, kind string) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+	itemID, ok := idParam(c, "itemId", "Invalid checklist item ID")
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	subtaskService := services.NewSubtaskService(workspaceDB(c, h.DB))
+	subtask, err := subtaskService.ConvertChecklistItem(actor, kind, taskID, itemID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Checklist item converted to a subtask", "subtask": subtaskResponse(subtask)})
+}
+
+// reorder reads {"ids": [...]} listing every subtask or checklist item in the new order
+func (h *SubtaskHandler) reorder(c *gin.Context, kind string, apply func(actor *services.Actor, kind string, taskID uint, ids []uint) error) {
+	taskID, ok := idParam(c, "id", "Invalid task ID")
+	if !ok {
+		return
+	}
+
+	var orderRequest struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&orderRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	if err := apply(actor, kind, taskID, orderRequest.IDs); err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated successfully"})
+}
+
+// idParam parses an id path parameter. It writes the error response itself and returns false when the id is invalid.
+func idParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// subtaskResponse describes a subtask, or a task just promoted from one
+func subtaskResponse(task *models.Task) gin.H {
+	return gin.H{
+		"id":           task.ID,
+		"title":        task.Title,
+		"description":  task.Description,
+		"status":       task.Status,
+		"priority":     task.Priority,
+		"progress":     task.Progress,
+		"user_id":      task.UserID,
+		"user":         userProfile(&task.User),
+		"project_id":   task.ProjectID,
+		"parent_kind":  task.ParentKind,
+		"parent_id":    task.ParentID,
+		"position":     task.Position,
+		"due_date":     task.DueDate,
+		"started_at":   task.StartedAt,
+		"completed_at": task.CompletedAt,
+		"created_at":   task.CreatedAt,
+	}
+}
+
+// checklistItemResponse describes a checklist item
+func checklistItemResponse(item *models.ChecklistItem) gin.H {
+	return gin.H{
+		"id":         item.ID,
+		"title":      item.Title,
+		"done":       item.Done,
+		"done_by":    item.DoneBy,
+		"done_at":    item.DoneAt,
+		"position":   item.Position,
+		"created_by": item.CreatedBy,
+		"created_at": item.CreatedAt,
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"project-x/models"
 	"project-x/services"
@@ -26,7 +27,8 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		Description string     `json:"description" binding:"required"`
 		ProjectID   *uint      `json:"project_id"`
 		DueDate     *time.Time `json:"due_date"`
-		AssignedTo  *uint      `json:"assigned_to"` // Optional: assign to specific user (Admin/Manager only)
+		Priority    string     `json:"priority"`    // high, medium (default), low
+		AssignedTo  *uint      `json:"assigned_to"` // Optional: assign to a report, or anyone in your departments with task.assign
 	}
 
 	if err := c.ShouldBindJSON(&createTaskRequest); err != nil {
@@ -36,14 +38,19 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 
 	// Get current user info from context
 	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("userRole")
+
+	priority, err := services.ParsePriority(createTaskRequest.Priority)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Determine who the task should be assigned to
 	var assignedUserID uint
 	if createTaskRequest.AssignedTo != nil {
-		// Check if current user has permission to assign tasks to others
-		if userRole == models.RoleEmployee || userRole == models.RoleHead {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Employees and Heads cannot assign tasks to other users"})
+		// Managers can assign to their reports, task.assign holders within their departments
+		if err := h.checkAssignment(c, *createTaskRequest.AssignedTo); err != nil {
+			writeAccessError(c, err)
 			return
 		}
 		assignedUserID = *createTaskRequest.AssignedTo
@@ -52,13 +59,14 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		assignedUserID = userID.(uint)
 	}
 
-	taskService := services.NewTaskService(h.DB)
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
 	task, err := taskService.CreateTask(
 		createTaskRequest.Title,
 		createTaskRequest.Description,
 		assignedUserID,
 		createTaskRequest.ProjectID,
 		createTaskRequest.DueDate,
+		priority,
 	)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -72,6 +80,7 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"user_id":     task.UserID,
 			"project_id":  task.ProjectID,
 			"assigned_at": task.AssignedAt,
@@ -81,7 +90,7 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 	})
 }
 
-// CreateTaskForUser allows Admin/Manager to create tasks for specific users
+// CreateTaskForUser creates a task for a report, or for anyone in the caller's departments with task.assign
 func (h *TaskHandler) CreateTaskForUser(c *gin.Context) {
 	var createTaskRequest struct {
 		Title       string     `json:"title" binding:"required"`
@@ -89,7 +98,7 @@ func (h *TaskHandler) CreateTaskForUser(c *gin.Context) {
 		UserID      uint       `json:"user_id" binding:"required"`
 		ProjectID   *uint      `json:"project_id"`
 		DueDate     *time.Time `json:"due_date"`
-		Priority    string     `json:"priority"` // high, medium, low
+		Priority    string     `json:"priority"` // high, medium (default), low
 	}
 
 	if err := c.ShouldBindJSON(&createTaskRequest); err != nil {
@@ -100,23 +109,13 @@ func (h *TaskHandler) CreateTaskForUser(c *gin.Context) {
 	// Get current user info from context
 	currentUserID, _ := c.Get("userID")
 
-	// Validate priority
-	if createTaskRequest.Priority != "" {
-		validPriorities := []string{"high", "medium", "low"}
-		validPriority := false
-		for _, p := range validPriorities {
-			if p == createTaskRequest.Priority {
-				validPriority = true
-				break
-			}
-		}
-		if !validPriority {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority. Must be 'high', 'medium', or 'low'"})
-			return
-		}
+	priority, err := services.ParsePriority(createTaskRequest.Priority)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
 	task, err := taskService.CreateTaskForUser(
 		createTaskRequest.Title,
 		createTaskRequest.Description,
@@ -124,10 +123,10 @@ func (h *TaskHandler) CreateTaskForUser(c *gin.Context) {
 		currentUserID.(uint),
 		createTaskRequest.ProjectID,
 		createTaskRequest.DueDate,
-		createTaskRequest.Priority,
+		priority,
 	)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -138,6 +137,7 @@ func (h *TaskHandler) CreateTaskForUser(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"user_id":     task.UserID,
 			"assigned_by": currentUserID,
 			"project_id":  task.ProjectID,
@@ -155,7 +155,8 @@ func (h *TaskHandler) CreateCollaborativeTask(c *gin.Context) {
 		Description string     `json:"description" binding:"required"`
 		ProjectID   *uint      `json:"project_id"`
 		DueDate     *time.Time `json:"due_date"`
-		AssignedTo  *uint      `json:"assigned_to"` // Optional: assign to specific user (Admin/Manager only)
+		Priority    string     `json:"priority"`    // high, medium (default), low
+		AssignedTo  *uint      `json:"assigned_to"` // Optional: assign to a report, or anyone in your departments with task.assign
 	}
 
 	if err := c.ShouldBindJSON(&createTaskRequest); err != nil {
@@ -165,14 +166,19 @@ func (h *TaskHandler) CreateCollaborativeTask(c *gin.Context) {
 
 	// Get current user info from context
 	userID, _ := c.Get("userID")
-	userRole, _ := c.Get("userRole")
+
+	priority, err := services.ParsePriority(createTaskRequest.Priority)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Determine who the task should be assigned to
 	var assignedUserID uint
 	if createTaskRequest.AssignedTo != nil {
-		// Check if current user has permission to assign tasks to others
-		if userRole == models.RoleEmployee || userRole == models.RoleHead {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Employees and Heads cannot assign collaborative tasks to other users"})
+		// Managers can assign to their reports, task.assign holders within their departments
+		if err := h.checkAssignment(c, *createTaskRequest.AssignedTo); err != nil {
+			writeAccessError(c, err)
 			return
 		}
 		assignedUserID = *createTaskRequest.AssignedTo
@@ -181,13 +187,14 @@ func (h *TaskHandler) CreateCollaborativeTask(c *gin.Context) {
 		assignedUserID = userID.(uint)
 	}
 
-	taskService := services.NewTaskService(h.DB)
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
 	task, err := taskService.CreateCollaborativeTask(
 		createTaskRequest.Title,
 		createTaskRequest.Description,
 		assignedUserID,
 		createTaskRequest.ProjectID,
 		createTaskRequest.DueDate,
+		priority,
 	)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -201,6 +208,7 @@ func (h *TaskHandler) CreateCollaborativeTask(c *gin.Context) {
 			"title":        task.Title,
 			"description":  task.Description,
 			"status":       task.Status,
+			"priority":     task.Priority,
 			"lead_user_id": task.LeadUserID,
 			"project_id":   task.ProjectID,
 			"assigned_at":  task.AssignedAt,
@@ -210,7 +218,7 @@ func (h *TaskHandler) CreateCollaborativeTask(c *gin.Context) {
 	})
 }
 
-// CreateCollaborativeTaskForUser allows Admin/Manager to create collaborative tasks for specific users
+// CreateCollaborativeTaskForUser creates a collaborative task for a report, or for anyone in the caller's departments with task.assign
 func (h *TaskHandler) CreateCollaborativeTaskForUser(c *gin.Context) {
 	var createTaskRequest struct {
 		Title       string     `json:"title" binding:"required"`
@@ -218,7 +226,7 @@ func (h *TaskHandler) CreateCollaborativeTaskForUser(c *gin.Context) {
 		UserID      uint       `json:"user_id" binding:"required"`
 		ProjectID   *uint      `json:"project_id"`
 		DueDate     *time.Time `json:"due_date"`
-		Priority    string     `json:"priority"` // high, medium, low
+		Priority    string     `json:"priority"` // high, medium (default), low
 	}
 
 	if err := c.ShouldBindJSON(&createTaskRequest); err != nil {
@@ -229,23 +237,13 @@ func (h *TaskHandler) CreateCollaborativeTaskForUser(c *gin.Context) {
 	// Get current user info from context
 	currentUserID, _ := c.Get("userID")
 
-	// Validate priority
-	if createTaskRequest.Priority != "" {
-		validPriorities := []string{"high", "medium", "low"}
-		validPriority := false
-		for _, p := range validPriorities {
-			if p == createTaskRequest.Priority {
-				validPriority = true
-				break
-			}
-		}
-		if !validPriority {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid priority. Must be 'high', 'medium', or 'low'"})
-			return
-		}
+	priority, err := services.ParsePriority(createTaskRequest.Priority)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
 	task, err := taskService.CreateCollaborativeTaskForUser(
 		createTaskRequest.Title,
 		createTaskRequest.Description,
@@ -253,10 +251,10 @@ func (h *TaskHandler) CreateCollaborativeTaskForUser(c *gin.Context) {
 		currentUserID.(uint),
 		createTaskRequest.ProjectID,
 		createTaskRequest.DueDate,
-		createTaskRequest.Priority,
+		priority,
 	)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -267,6 +265,7 @@ func (h *TaskHandler) CreateCollaborativeTaskForUser(c *gin.Context) {
 			"title":        task.Title,
 			"description":  task.Description,
 			"status":       task.Status,
+			"priority":     task.Priority,
 			"lead_user_id": task.LeadUserID,
 			"assigned_by":  currentUserID,
 			"project_id":   task.ProjectID,
@@ -277,12 +276,17 @@ func (h *TaskHandler) CreateCollaborativeTaskForUser(c *gin.Context) {
 	})
 }
 
-// GetUserTasks returns all tasks for the current user
+// GetUserTasks returns all tasks for the current user, optionally filtered by ?priority= and ordered by ?sort=
 func (h *TaskHandler) GetUserTasks(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetUserTasks(userID.(uint))
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetUserTasks(userID.(uint), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 		return
@@ -295,6 +299,7 @@ func (h *TaskHandler) GetUserTasks(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"project_id":  task.ProjectID,
 			"assigned_at": task.AssignedAt,
 			"due_date":    task.DueDate,
@@ -309,8 +314,13 @@ func (h *TaskHandler) GetUserTasks(c *gin.Context) {
 func (h *TaskHandler) GetUserCollaborativeTasks(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetUserCollaborativeTasks(userID.(uint))
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetUserCollaborativeTasks(userID.(uint), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborative tasks"})
 		return
@@ -323,6 +333,7 @@ func (h *TaskHandler) GetUserCollaborativeTasks(c *gin.Context) {
 			"title":        task.Title,
 			"description":  task.Description,
 			"status":       task.Status,
+			"priority":     task.Priority,
 			"lead_user_id": task.LeadUserID,
 			"project_id":   task.ProjectID,
 			"assigned_at":  task.AssignedAt,
@@ -342,8 +353,13 @@ func (h *TaskHandler) GetProjectTasks(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetProjectTasks(uint(projectID))
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetProjectTasks(uint(projectID), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project tasks"})
 		return
@@ -356,7 +372,9 @@ func (h *TaskHandler) GetProjectTasks(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"user_id":     task.UserID,
+			"user":        userProfile(&task.User),
 			"assigned_at": task.AssignedAt,
 			"due_date":    task.DueDate,
 			"created_at":  task.CreatedAt,
@@ -374,8 +392,13 @@ func (h *TaskHandler) GetProjectCollaborativeTasks(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetProjectCollaborativeTasks(uint(projectID))
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetProjectCollaborativeTasks(uint(projectID), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project collaborative tasks"})
 		return
@@ -388,8 +411,11 @@ func (h *TaskHandler) GetProjectCollaborativeTasks(c *gin.Context) {
 			"title":          task.Title,
 			"description":    task.Description,
 			"status":         task.Status,
+			"priority":       task.Priority,
 			"lead_user_id":   task.LeadUserID,
 			"lead_user_name": task.LeadUser.Username,
+			"lead_user":      userProfile(&task.LeadUser),
+			"department":     task.LeadUser.DepartmentName(),
 			"project_id":     task.ProjectID,
 			"assigned_at":    task.AssignedAt,
 			"due_date":       task.DueDate,
@@ -400,7 +426,7 @@ func (h *TaskHandler) GetProjectCollaborativeTasks(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"collaborative_tasks": taskList})
 }
 
-// UpdateTaskStatus updates task status
+// UpdateTaskStatus updates task status (owner, project lead/manager, or task.update_any in the owner's department)
 func (h *TaskHandler) UpdateTaskStatus(c *gin.Context) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -431,17 +457,147 @@ func (h *TaskHandler) UpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
-	err = taskService.UpdateTaskStatus(uint(taskID), models.TaskStatus(updateRequest.Status))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	err = taskService.UpdateTaskStatus(actor, uint(taskID), models.TaskStatus(updateRequest.Status))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task status"})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task status updated successfully"})
 }
 
-// UpdateCollaborativeTaskStatus updates collaborative task status
+// EditTask changes a task with a JSON merge patch (same access as changing the status).
+// Fields left out keep their value; null clears the due date or project.
+func (h *TaskHandler) EditTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	patch, ok := readMergePatch(c)
+	if !ok {
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	task, err := taskService.EditTask(actor, uint(taskID), patch)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Task updated successfully",
+		"task": gin.H{
+			"id":           task.ID,
+			"title":        task.Title,
+			"description":  task.Description,
+			"status":       task.Status,
+			"priority":     task.Priority,
+			"progress":     task.Progress,
+			"user":         userProfile(&task.User),
+			"project_id":   task.ProjectID,
+			"parent_kind":  task.ParentKind,
+			"parent_id":    task.ParentID,
+			"assigned_at":  task.AssignedAt,
+			"due_date":     task.DueDate,
+			"created_at":   task.CreatedAt,
+			"started_at":   task.StartedAt,
+			"completed_at": task.CompletedAt,
+			"updated_at":   task.UpdatedAt,
+		},
+	})
+}
+
+// GetTaskHistory lists the recorded changes of a task (owner, managers, project members, or department viewers)
+func (h *TaskHandler) GetTaskHistory(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	history, err := taskService.GetTaskHistory(actor, uint(taskID))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": taskHistoryResponse(history)})
+}
+
+// AssignTask hands a task to another user (task.assign in the owner's department, or as a lead of the task's project)
+func (h *TaskHandler) AssignTask(c *gin.Context) {
+	h.moveTask(c, services.TaskActionAssign, "Task assigned successfully")
+}
+
+// ReassignTask moves a task to another user (task.reassign in the owner's department, or as a lead of the task's project)
+func (h *TaskHandler) ReassignTask(c *gin.Context) {
+	h.moveTask(c, services.TaskActionReassign, "Task reassigned successfully")
+}
+
+// moveTask changes a task's owner after the access policy has approved the action
+func (h *TaskHandler) moveTask(c *gin.Context, action services.TaskAction, message string) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var reassignRequest struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&reassignRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	task, err := taskService.ReassignTask(actor, uint(taskID), reassignRequest.UserID, action)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"task": gin.H{
+			"id":          task.ID,
+			"title":       task.Title,
+			"status":      task.Status,
+			"priority":    task.Priority,
+			"user_id":     task.UserID,
+			"project_id":  task.ProjectID,
+			"assigned_at": task.AssignedAt,
+		},
+	})
+}
+
+// UpdateCollaborativeTaskStatus updates collaborative task status (lead, project lead/manager, or task.update_any in the lead's department)
 func (h *TaskHandler) UpdateCollaborativeTaskStatus(c *gin.Context) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -472,10 +628,15 @@ func (h *TaskHandler) UpdateCollaborativeTaskStatus(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
-	err = taskService.UpdateCollaborativeTaskStatus(uint(taskID), models.TaskStatus(updateRequest.Status))
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	err = taskService.UpdateCollaborativeTaskStatus(actor, uint(taskID), models.TaskStatus(updateRequest.Status))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update collaborative task status"})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -492,8 +653,13 @@ func (h *TaskHandler) GetTasksByStatus(c *gin.Context) {
 
 	userID, _ := c.Get("userID")
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetTasksByStatus(userID.(uint), models.TaskStatus(status))
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetTasksByStatus(userID.(uint), models.TaskStatus(status), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
 		return
@@ -506,6 +672,7 @@ func (h *TaskHandler) GetTasksByStatus(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"project_id":  task.ProjectID,
 			"assigned_at": task.AssignedAt,
 			"due_date":    task.DueDate,
@@ -526,8 +693,13 @@ func (h *TaskHandler) GetCollaborativeTasksByStatus(c *gin.Context) {
 
 	userID, _ := c.Get("userID")
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetCollaborativeTasksByStatus(userID.(uint), models.TaskStatus(status))
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetCollaborativeTasksByStatus(userID.(uint), models.TaskStatus(status), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch collaborative tasks"})
 		return
@@ -540,6 +712,7 @@ func (h *TaskHandler) GetCollaborativeTasksByStatus(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"project_id":  task.ProjectID,
 			"assigned_at": task.AssignedAt,
 			"due_date":    task.DueDate,
@@ -558,19 +731,45 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userID")
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
 
-	taskService := services.NewTaskService(h.DB)
-	err = taskService.DeleteTask(uint(taskID), userID.(uint))
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	err = taskService.DeleteTask(actor, uint(taskID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
 }
 
-// DeleteCollaborativeTask deletes a collaborative task (only by owner)
+// ForceDeleteTask deletes any task in the caller's departments (task.reassign)
+func (h *TaskHandler) ForceDeleteTask(c *gin.Context) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	err = taskService.ForceDeleteTask(actor, uint(taskID))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task deleted successfully"})
+}
+
+// DeleteCollaborativeTask deletes a collaborative task (lead with task.delete, or task.reassign in the lead's department)
 func (h *TaskHandler) DeleteCollaborativeTask(c *gin.Context) {
 	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -578,28 +777,47 @@ func (h *TaskHandler) DeleteCollaborativeTask(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userID")
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
 
-	taskService := services.NewTaskService(h.DB)
-	err = taskService.DeleteCollaborativeTask(uint(taskID), userID.(uint))
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	err = taskService.DeleteCollaborativeTask(actor, uint(taskID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Collaborative task deleted successfully"})
 }
 
-// GetTasksByDepartment returns all tasks for users in a specific department (Head/Admin only)
+// GetTasksByDepartment returns all tasks for users in a department the caller has access to, including its sub-departments
 func (h *TaskHandler) GetTasksByDepartment(c *gin.Context) {
-	department := c.Param("dept")
-	if department == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Department parameter is required"})
+	parsedDepartmentID, err := strconv.ParseUint(c.Param("dept"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
 		return
 	}
+	departmentID := uint(parsedDepartmentID)
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetTasksByDepartment(department)
+	scope, err := h.departmentScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve department access"})
+		return
+	}
+	if !scope.Allows(&departmentID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own departments"})
+		return
+	}
+
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetTasksByDepartment(departmentID, options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch department tasks"})
 		return
@@ -612,8 +830,11 @@ func (h *TaskHandler) GetTasksByDepartment(c *gin.Context) {
 			"title":       task.Title,
 			"description": task.Description,
 			"status":      task.Status,
+			"priority":    task.Priority,
 			"user_id":     task.UserID,
 			"user_name":   task.User.Username,
+			"user":        userProfile(&task.User),
+			"department":  task.User.DepartmentName(),
 			"project_id":  task.ProjectID,
 			"assigned_at": task.AssignedAt,
 			"due_date":    task.DueDate,
@@ -624,16 +845,32 @@ func (h *TaskHandler) GetTasksByDepartment(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"tasks": taskList})
 }
 
-// GetCollaborativeTasksByDepartment returns all collaborative tasks for users in a specific department (Head/Admin only)
+// GetCollaborativeTasksByDepartment returns all collaborative tasks led by users in a department the caller has access to, including its sub-departments
 func (h *TaskHandler) GetCollaborativeTasksByDepartment(c *gin.Context) {
-	department := c.Param("dept")
-	if department == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Department parameter is required"})
+	parsedDepartmentID, err := strconv.ParseUint(c.Param("dept"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
 		return
 	}
+	departmentID := uint(parsedDepartmentID)
 
-	taskService := services.NewTaskService(h.DB)
-	tasks, err := taskService.GetCollaborativeTasksByDepartment(department)
+	scope, err := h.departmentScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve department access"})
+		return
+	}
+	if !scope.Allows(&departmentID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own departments"})
+		return
+	}
+
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	tasks, err := taskService.GetCollaborativeTasksByDepartment(departmentID, options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch department collaborative tasks"})
 		return
@@ -646,8 +883,11 @@ func (h *TaskHandler) GetCollaborativeTasksByDepartment(c *gin.Context) {
 			"title":          task.Title,
 			"description":    task.Description,
 			"status":         task.Status,
+			"priority":       task.Priority,
 			"lead_user_id":   task.LeadUserID,
 			"lead_user_name": task.LeadUser.Username,
+			"lead_user":      userProfile(&task.LeadUser),
+			"department":     task.LeadUser.DepartmentName(),
 			"project_id":     task.ProjectID,
 			"assigned_at":    task.AssignedAt,
 			"due_date":       task.DueDate,
@@ -689,10 +929,15 @@ func (h *TaskHandler) BulkUpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
-	updatedCount, err := taskService.BulkUpdateTaskStatus(bulkUpdateRequest.TaskIDs, bulkUpdateRequest.Status)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	updatedCount, err := taskService.BulkUpdateTaskStatus(actor, bulkUpdateRequest.TaskIDs, bulkUpdateRequest.Status)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
@@ -703,10 +948,16 @@ func (h *TaskHandler) BulkUpdateTaskStatus(c *gin.Context) {
 	})
 }
 
-// GetTaskStatistics returns task statistics for managers and admins
+// GetTaskStatistics returns task statistics for the caller's departments
 func (h *TaskHandler) GetTaskStatistics(c *gin.Context) {
-	taskService := services.NewTaskService(h.DB)
-	stats, err := taskService.GetTaskStatistics()
+	scope, err := h.departmentScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve department access"})
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	stats, err := taskService.GetTaskStatistics(scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task statistics"})
 		return
@@ -715,7 +966,7 @@ func (h *TaskHandler) GetTaskStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"statistics": stats})
 }
 
-// GetProjectReport returns detailed project report with user performance
+// GetProjectReport returns detailed project report with user performance for the caller's departments
 func (h *TaskHandler) GetProjectReport(c *gin.Context) {
 	projectID, err := strconv.ParseUint(c.Param("projectId"), 10, 32)
 	if err != nil {
@@ -733,8 +984,14 @@ func (h *TaskHandler) GetProjectReport(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
-	report, err := taskService.GetProjectReport(uint(projectID), period)
+	scope, err := h.departmentScope(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve department access"})
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
+	report, err := taskService.GetProjectReport(uint(projectID), period, scope)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -743,7 +1000,7 @@ func (h *TaskHandler) GetProjectReport(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"report": report})
 }
 
-// GetUserReport returns detailed user report with project performance
+// GetUserReport returns detailed user report with project performance for a user in the caller's departments
 func (h *TaskHandler) GetUserReport(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("userId"), 10, 32)
 	if err != nil {
@@ -761,7 +1018,12 @@ func (h *TaskHandler) GetUserReport(c *gin.Context) {
 		return
 	}
 
-	taskService := services.NewTaskService(h.DB)
+	if err := h.checkDepartmentAccess(c, uint(userID)); err != nil {
+		h.departmentAccessError(c, err)
+		return
+	}
+
+	taskService := services.NewTaskService(workspaceDB(c, h.DB))
 	report, err := taskService.GetUserReport(uint(userID), period)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -770,3 +1032,86 @@ func (h *TaskHandler) GetUserReport(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"report": report})
 }
+
+// departmentScope returns the departments the current user may assign, view and report on
+func (h *TaskHandler) departmentScope(c *gin.Context) (services.DepartmentScope, error) {
+	user, _ := c.Get("user")
+	return services.NewDepartmentAccessService(workspaceDB(c, h.DB)).ScopeFor(user.(*models.User))
+}
+
+// checkAssignment makes sure the current user may give new work to the target user
+func (h *TaskHandler) checkAssignment(c *gin.Context, targetUserID uint) error {
+	user, _ := c.Get("user")
+	actor, err := services.NewActor(workspaceDB(c, h.DB), user.(*models.User))
+	if err != nil {
+		return err
+	}
+
+	target, err := services.NewUserService(workspaceDB(c, h.DB)).GetUserByID(targetUserID)
+	if err != nil {
+		return errors.New("target user not found")
+	}
+	return services.AuthorizeAssignment(actor, target)
+}
+
+// checkDepartmentAccess makes sure the target user is in one of the current user's departments
+func (h *TaskHandler) checkDepartmentAccess(c *gin.Context, targetUserID uint) error {
+	scope, err := h.departmentScope(c)
+	if err != nil {
+		return err
+	}
+	return services.NewDepartmentAccessService(workspaceDB(c, h.DB)).CheckUser(scope, targetUserID)
+}
+
+// departmentAccessError writes the response for a failed department check
+func (h *TaskHandler) departmentAccessError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrOutsideDepartment) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is outside of your departments"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// readMergePatch reads a JSON merge patch body. It writes the error response itself and returns false on failure.
+func readMergePatch(c *gin.Context) ([]byte, bool) {
+	switch c.ContentType() {
+	case "", "application/json", "application/merge-patch+json":
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Use application/merge-patch+json or application/json"})
+		return nil, false
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return nil, false
+	}
+	return body, true
+}
+
+// taskListOptions reads the ?priority= filter and ?sort= order of a task list. It writes the error
+// response itself and returns false when either is invalid.
+func taskListOptions(c *gin.Context) (services.TaskListOptions, bool) {
+	options, err := services.ParseTaskListOptions(c.Query("priority"), c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return options, false
+	}
+	return options, true
+}
+
+// taskHistoryResponse lists task history entries with the users who made them
+func taskHistoryResponse(history []models.TaskHistory) []gin.H {
+	entries := make([]gin.H, 0, len(history))
+	for _, entry := range history {
+		entries = append(entries, gin.H{
+			"id":         entry.ID,
+			"field":      entry.Field,
+			"old_value":  entry.OldValue,
+			"new_value":  entry.NewValue,
+			"changed_by": userProfile(&entry.Actor),
+			"changed_at": entry.ChangedAt,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TeamHandler struct {
+	DB *gorm.DB
+}
+
+func NewTeamHandler(db *gorm.DB) *TeamHandler {
+	return &TeamHandler{DB: db}
+}
+
+// UpdateUserManager sets or clears who a user reports to (user.manage)
+func (h *TeamHandler) UpdateUserManager(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var updateRequest struct {
+		ManagerID *uint `json:"manager_id"` // null removes the manager
+	}
+
+	if err := c.ShouldBindJSON(&updateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	teamService := services.NewTeamService(workspaceDB(c, h.DB))
+	user, err := teamService.SetManager(actor, uint(userID), updateRequest.ManagerID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User manager updated successfully",
+		"user": gin.H{
+			"id":         user.ID,
+			"username":   user.Username,
+			"manager_id": user.ManagerID,
+		},
+	})
+}
+
+// GetReports returns everyone who reports to a user, directly or indirectly.
+// ?direct=true limits the list to direct reports.
+func (h *TeamHandler) GetReports(c *gin.Context) {
+	managerID, ok := h.authorizeTeamView(c)
+	if !ok {
+		return
+	}
+
+	teamService := services.NewTeamService(workspaceDB(c, h.DB))
+	users, levels, err := teamService.GetReports(managerID, c.Query("direct") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	var reportList []gin.H
+	for _, user := range users {
+		reportList = append(reportList, gin.H{
+			"id":            user.ID,
+			"username":      user.Username,
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"level":         levels[user.ID], // 1 for direct reports
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"manager_id": managerID, "reports": reportList})
+}
+
+// GetTeamTasks returns the tasks of a user's reports, optionally filtered by ?status= and ?priority= and ordered by ?sort=
+func (h *TeamHandler) GetTeamTasks(c *gin.Context) {
+	managerID, ok := h.authorizeTeamView(c)
+	if !ok {
+		return
+	}
+	options, ok := taskListOptions(c)
+	if !ok {
+		return
+	}
+
+	teamService := services.NewTeamService(workspaceDB(c, h.DB))
+	tasks, err := teamService.GetTeamTasks(managerID, c.Query("direct") == "true", c.Query("status"), options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch team tasks"})
+		return
+	}
+
+	var taskList []gin.H
+	for _, task := range tasks {
+		taskList = append(taskList, gin.H{
+			"id":          task.ID,
+			"title":       task.Title,
+			"description": task.Description,
+			"status":      task.Status,
+			"priority":    task.Priority,
+			"user_id":     task.UserID,
+			"user_name":   task.User.Username,
+			"user":        userProfile(&task.User),
+			"project_id":  task.ProjectID,
+			"assigned_at": task.AssignedAt,
+			"due_date":    task.DueDate,
+			"created_at":  task.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": taskList})
+}
+
+// GetTeamWorkload returns open, overdue and finished work for each of a user's reports
+func (h *TeamHandler) GetTeamWorkload(c *gin.Context) {
+	managerID, ok := h.authorizeTeamView(c)
+	if !ok {
+		return
+	}
+
+	teamService := services.NewTeamService(workspaceDB(c, h.DB))
+	workload, err := teamService.GetTeamWorkload(managerID, c.Query("direct") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate team workload"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workload": workload})
+}
+
+// authorizeTeamView lets users see their own team and the teams of anyone below them;
+// user.manage holders can see any team. It writes the error response itself.
+func (h *TeamHandler) authorizeTeamView(c *gin.Context) (uint, bool) {
+	managerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, false
+	}
+
+	userID, _ := c.Get("userID")
+	permissions, _ := c.Get("permissions")
+	if uint(managerID) == userID.(uint) || permissions.(models.PermissionSet).Has(models.PermissionUserManage) {
+		return uint(managerID), true
+	}
+
+	if !services.NewTeamService(workspaceDB(c, h.DB)).IsManagerOf(userID.(uint), uint(managerID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own team and the teams below you"})
+		return 0, false
+	}
+	return uint(managerID), true
+}
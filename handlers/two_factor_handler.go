@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TwoFactorHandler struct {
+	DB *gorm.DB
+}
+
+func NewTwoFactorHandler(db *gorm.DB) *TwoFactorHandler {
+	return &TwoFactorHandler{DB: db}
+}
+
+// Setup starts 2FA enrollment and returns the secret for the authenticator app
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	twoFactorService := services.NewTwoFactorService(h.DB)
+	secret, uri, err := twoFactorService.BeginEnrollment(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+// Enable confirms enrollment with a code from the authenticator app
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	var enableRequest struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&enableRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	twoFactorService := services.NewTwoFactorService(h.DB)
+	codes, err := twoFactorService.ConfirmEnrollment(userID.(uint), enableRequest.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled successfully",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable turns off 2FA for the current user
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var disableRequest struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&disableRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	twoFactorService := services.NewTwoFactorService(h.DB)
+	if err := twoFactorService.Disable(userID.(uint), disableRequest.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled successfully"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var regenerateRequest struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&regenerateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	twoFactorService := services.NewTwoFactorService(h.DB)
+	codes, err := twoFactorService.RegenerateRecoveryCodes(userID.(uint), regenerateRequest.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"project-x/models"
 	"project-x/services"
@@ -21,10 +23,10 @@ func NewUserHandler(db *gorm.DB) *UserHandler {
 // CreateUser creates a new user (Admin only)
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var createUserRequest struct {
-		Username   string `json:"username" binding:"required"`
-		Password   string `json:"password" binding:"required"`
-		Role       string `json:"role" binding:"required"`
-		Department string `json:"department" binding:"required"`
+		Username     string `json:"username" binding:"required"`
+		Password     string `json:"password" binding:"required"`
+		Role         string `json:"role" binding:"required"`
+		DepartmentID uint   `json:"department_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&createUserRequest); err != nil {
@@ -32,26 +34,38 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	userService := services.NewUserService(workspaceDB(c, h.DB))
 	user, err := userService.CreateUser(
+		actor,
 		createUserRequest.Username,
 		createUserRequest.Password,
 		createUserRequest.Role,
-		createUserRequest.Department,
+		createUserRequest.DepartmentID,
 	)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
+			"created_at":    user.CreatedAt,
 		},
 	})
 }
@@ -64,7 +78,7 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
+	userService := services.NewUserService(workspaceDB(c, h.DB))
 	user, err := userService.GetUserByID(uint(userID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -73,19 +87,25 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
+			"created_at":    user.CreatedAt,
+			"updated_at":    user.UpdatedAt,
 		},
 	})
 }
 
 // ListUsers returns all users (Admin only)
 func (h *UserHandler) ListUsers(c *gin.Context) {
-	userService := services.NewUserService(h.DB)
+	userService := services.NewUserService(workspaceDB(c, h.DB))
 	users, err := userService.GetAllUsers()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
@@ -95,11 +115,17 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	var userList []gin.H
 	for _, user := range users {
 		userList = append(userList, gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(&user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
+			"created_at":    user.CreatedAt,
 		})
 	}
 
@@ -114,7 +140,7 @@ func (h *UserHandler) GetUsersByRole(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
+	userService := services.NewUserService(workspaceDB(c, h.DB))
 	users, err := userService.GetUsersByRole(role)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -124,27 +150,33 @@ func (h *UserHandler) GetUsersByRole(c *gin.Context) {
 	var userList []gin.H
 	for _, user := range users {
 		userList = append(userList, gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(&user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
+			"created_at":    user.CreatedAt,
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{"users": userList})
 }
 
-// GetUsersByDepartment returns users filtered by department
+// GetUsersByDepartment returns users in a department and its sub-departments
 func (h *UserHandler) GetUsersByDepartment(c *gin.Context) {
-	department := c.Param("department")
-	if department == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Department parameter is required"})
+	departmentID, err := strconv.ParseUint(c.Param("department"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
-	users, err := userService.GetUsersByDepartment(department)
+	userService := services.NewUserService(workspaceDB(c, h.DB))
+	users, err := userService.GetUsersByDepartment(uint(departmentID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
@@ -153,11 +185,17 @@ func (h *UserHandler) GetUsersByDepartment(c *gin.Context) {
 	var userList []gin.H
 	for _, user := range users {
 		userList = append(userList, gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
-			"created_at": user.CreatedAt,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(&user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
+			"created_at":    user.CreatedAt,
 		})
 	}
 
@@ -181,20 +219,31 @@ func (h *UserHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
-	user, err := userService.UpdateUserRole(uint(userID), updateRequest.Role)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	userService := services.NewUserService(workspaceDB(c, h.DB))
+	user, err := userService.UpdateUserRole(actor, uint(userID), updateRequest.Role)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User role updated successfully",
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
 		},
 	})
 }
@@ -208,7 +257,7 @@ func (h *UserHandler) UpdateUserDepartment(c *gin.Context) {
 	}
 
 	var updateRequest struct {
-		Department string `json:"department" binding:"required"`
+		DepartmentID uint `json:"department_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&updateRequest); err != nil {
@@ -216,24 +265,122 @@ func (h *UserHandler) UpdateUserDepartment(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
-	user, err := userService.UpdateUserDepartment(uint(userID), updateRequest.Department)
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	userService := services.NewUserService(workspaceDB(c, h.DB))
+	user, err := userService.UpdateUserDepartment(actor, uint(userID), &updateRequest.DepartmentID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeAccessError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "User department updated successfully",
 		"user": gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
+			"id":            user.ID,
+			"username":      user.Username,
+			"email":         user.Email,
+			"display_name":  user.Name(),
+			"avatar_url":    services.AvatarURL(user),
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
+			"manager_id":    user.ManagerID,
+			"active":        user.Active,
 		},
 	})
 }
 
+// ListDepartmentAccess returns the extra departments a user can act on (Admin only)
+func (h *UserHandler) ListDepartmentAccess(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	accessService := services.NewDepartmentAccessService(workspaceDB(c, h.DB))
+	grants, err := accessService.GetUserGrants(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch department access"})
+		return
+	}
+
+	var grantList []gin.H
+	for _, grant := range grants {
+		grantList = append(grantList, h.grantResponse(&grant))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"department_access": grantList})
+}
+
+// GrantDepartmentAccess lets a user assign, view and report on another department (Admin only)
+func (h *UserHandler) GrantDepartmentAccess(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var grantRequest struct {
+		DepartmentID   *uint `json:"department_id"`
+		AllDepartments bool  `json:"all_departments"` // Grants every department instead of one
+	}
+
+	if err := c.ShouldBindJSON(&grantRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if (grantRequest.DepartmentID == nil) != grantRequest.AllDepartments {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either department_id or all_departments"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	accessService := services.NewDepartmentAccessService(workspaceDB(c, h.DB))
+	grant, err := accessService.GrantAccess(actor, uint(userID), grantRequest.DepartmentID)
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Department access granted successfully",
+		"grant":   h.grantResponse(grant),
+	})
+}
+
+// RevokeDepartmentAccess removes a department grant from a user (Admin only)
+func (h *UserHandler) RevokeDepartmentAccess(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	grantID, err := strconv.ParseUint(c.Param("grantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid grant ID"})
+		return
+	}
+
+	accessService := services.NewDepartmentAccessService(workspaceDB(c, h.DB))
+	if err := accessService.RevokeAccess(uint(userID), uint(grantID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Department access revoked successfully"})
+}
+
 // UpdateUserPassword updates a user's password (Admin or self)
 func (h *UserHandler) UpdateUserPassword(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -242,17 +389,18 @@ func (h *UserHandler) UpdateUserPassword(c *gin.Context) {
 		return
 	}
 
-	// Check if user is updating their own password or is admin
+	// Check if user is updating their own password or manages users
 	currentUserID, _ := c.Get("userID")
-	currentUserRole, _ := c.Get("userRole")
+	permissions, _ := c.Get("permissions")
 
-	if currentUserID.(uint) != uint(userID) && currentUserRole != models.RoleAdmin {
+	if currentUserID.(uint) != uint(userID) && !permissions.(models.PermissionSet).Has(models.PermissionUserManage) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You can only update your own password"})
 		return
 	}
 
 	var updateRequest struct {
-		Password string `json:"password" binding:"required"`
+		Password        string `json:"password" binding:"required"`
+		CurrentPassword string `json:"current_password"` // Required when changing your own password
 	}
 
 	if err := c.ShouldBindJSON(&updateRequest); err != nil {
@@ -260,9 +408,29 @@ func (h *UserHandler) UpdateUserPassword(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
-	err = userService.UpdateUserPassword(uint(userID), updateRequest.Password)
+	userService := services.NewUserService(workspaceDB(c, h.DB))
+	if currentUserID.(uint) == uint(userID) {
+		if updateRequest.CurrentPassword == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "current_password is required"})
+			return
+		}
+		err = userService.ChangeOwnPassword(uint(userID), updateRequest.CurrentPassword, updateRequest.Password)
+	} else {
+		actor, ok := currentActor(c, workspaceDB(c, h.DB))
+		if !ok {
+			return
+		}
+		err = userService.ResetUserPassword(actor, uint(userID), updateRequest.Password)
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrWeakPassword) || errors.Is(err, services.ErrInvalidCurrentPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrAccessDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
@@ -270,6 +438,62 @@ func (h *UserHandler) UpdateUserPassword(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
+// UnlockUser clears a user's login lockout (Admin only)
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	userService := services.NewUserService(workspaceDB(c, h.DB))
+	user, err := userService.UnlockUser(actor, uint(userID))
+	if errors.Is(err, services.ErrAccessDenied) {
+		writeAccessError(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+		},
+	})
+}
+
+// ImpersonateUser issues a token that lets an admin act as another user (Admin only)
+func (h *UserHandler) ImpersonateUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	currentUserID, _ := c.Get("userID")
+
+	impersonationService := services.NewImpersonationService(workspaceDB(c, h.DB))
+	token, err := impersonationService.StartImpersonation(currentUserID.(uint), uint(userID), c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Impersonation started. Destructive actions are blocked and every request is audited",
+		"impersonation": token,
+	})
+}
+
 // GetUserStats returns statistics about a user
 func (h *UserHandler) GetUserStats(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -278,7 +502,7 @@ func (h *UserHandler) GetUserStats(c *gin.Context) {
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
+	userService := services.NewUserService(workspaceDB(c, h.DB))
 	stats, err := userService.GetUserStats(uint(userID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -288,20 +512,143 @@ func (h *UserHandler) GetUserStats(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"stats": stats})
 }
 
-// DeleteUser deletes a user (Admin only)
-func (h *UserHandler) DeleteUser(c *gin.Context) {
+// GetOffboardingPlan lists a user's open work by project, each group needing a successor
+// before the user can be deactivated (user.manage)
+func (h *UserHandler) GetOffboardingPlan(c *gin.Context) {
 	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	userService := services.NewUserService(h.DB)
-	err = userService.DeleteUser(uint(userID))
+	offboardingService := services.NewOffboardingService(workspaceDB(c, h.DB))
+	user, groups, err := offboardingService.GetOffboardingPlan(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"active":   user.Active,
+		"projects": h.offboardingResponse(groups),
+	})
+}
+
+// DeactivateUser hands a user's open work to the successors picked per project and deactivates
+// the account; history stays with the user (user.manage). Without a successor for every project
+// with open work it responds 409 with the groups still to cover.
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// The body is optional: users without open work need no successors
+	var deactivateRequest struct {
+		Successors []services.Successor `json:"successors"`
+	}
+	if err := c.ShouldBindJSON(&deactivateRequest); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	offboardingService := services.NewOffboardingService(workspaceDB(c, h.DB))
+	user, err := offboardingService.DeactivateUser(actor, uint(userID), deactivateRequest.Successors)
+	if errors.Is(err, services.ErrSuccessorsRequired) {
+		_, groups, planErr := offboardingService.GetOffboardingPlan(uint(userID))
+		if planErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load offboarding plan"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "projects": h.offboardingResponse(groups)})
+		return
+	}
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deactivated successfully",
+		"user": gin.H{
+			"id":             user.ID,
+			"username":       user.Username,
+			"active":         user.Active,
+			"deactivated_at": user.DeactivatedAt,
+		},
+	})
+}
+
+// ReactivateUser lets a deactivated user sign in again (user.manage)
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	actor, ok := currentActor(c, workspaceDB(c, h.DB))
+	if !ok {
+		return
+	}
+
+	offboardingService := services.NewOffboardingService(workspaceDB(c, h.DB))
+	user, err := offboardingService.ReactivateUser(actor, uint(userID))
+	if err != nil {
+		writeAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User reactivated successfully",
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"active":   user.Active,
+		},
+	})
+}
+
+// offboardingResponse formats open work grouped by project
+func (h *UserHandler) offboardingResponse(groups []services.OffboardingGroup) []gin.H {
+	projects := []gin.H{}
+	for _, group := range groups {
+		tasks := []gin.H{}
+		for _, task := range group.Tasks {
+			tasks = append(tasks, gin.H{"id": task.ID, "title": task.Title, "status": task.Status, "due_date": task.DueDate})
+		}
+		collaborativeTasks := []gin.H{}
+		for _, task := range group.CollaborativeTasks {
+			collaborativeTasks = append(collaborativeTasks, gin.H{"id": task.ID, "title": task.Title, "status": task.Status, "due_date": task.DueDate})
+		}
+
+		projects = append(projects, gin.H{
+			"project_id":          group.ProjectID,
+			"project_title":       group.ProjectTitle,
+			"tasks":               tasks,
+			"collaborative_tasks": collaborativeTasks,
+		})
+	}
+	return projects
+}
+
+// grantResponse formats a department access grant
+func (h *UserHandler) grantResponse(grant *models.DepartmentAccessGrant) gin.H {
+	return gin.H{
+		"id":              grant.ID,
+		"user_id":         grant.UserID,
+		"department_id":   grant.DepartmentID,
+		"department":      departmentName(grant.Department),
+		"all_departments": grant.CoversAllDepartments(),
+		"granted_by":      grant.GrantedBy,
+		"created_at":      grant.CreatedAt,
+	}
 }
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkspaceHandler struct {
+	DB *gorm.DB
+}
+
+func NewWorkspaceHandler(db *gorm.DB) *WorkspaceHandler {
+	return &WorkspaceHandler{DB: db}
+}
+
+// GetCurrentWorkspace returns the workspace the request acts inside (all authenticated users)
+func (h *WorkspaceHandler) GetCurrentWorkspace(c *gin.Context) {
+	workspaceID, _ := c.Get("workspaceID")
+
+	workspaceService := services.NewWorkspaceService(h.DB)
+	workspace, err := workspaceService.GetWorkspace(workspaceID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspace": h.workspaceResponse(workspace)})
+}
+
+// ListWorkspaces returns every workspace (workspace.manage, default workspace only)
+func (h *WorkspaceHandler) ListWorkspaces(c *gin.Context) {
+	if !h.authorizeManage(c) {
+		return
+	}
+
+	workspaceService := services.NewWorkspaceService(h.DB)
+	workspaces, err := workspaceService.ListWorkspaces()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workspaces"})
+		return
+	}
+
+	var workspaceList []gin.H
+	for _, workspace := range workspaces {
+		workspaceList = append(workspaceList, h.workspaceResponse(&workspace))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workspaces": workspaceList})
+}
+
+// CreateWorkspace creates an empty workspace (workspace.manage, default workspace only).
+// Its first users are created by sending X-Workspace-ID with POST /users.
+func (h *WorkspaceHandler) CreateWorkspace(c *gin.Context) {
+	if !h.authorizeManage(c) {
+		return
+	}
+
+	var createRequest struct {
+		Name string `json:"name" binding:"required"`
+		Slug string `json:"slug" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&createRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workspaceService := services.NewWorkspaceService(h.DB)
+	workspace, err := workspaceService.CreateWorkspace(createRequest.Name, createRequest.Slug)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Workspace created successfully",
+		"workspace": h.workspaceResponse(workspace),
+	})
+}
+
+// authorizeManage keeps workspace.manage from admins outside the default workspace.
+// It writes the error response itself.
+func (h *WorkspaceHandler) authorizeManage(c *gin.Context) bool {
+	user, _ := c.Get("user")
+	permissions, _ := c.Get("permissions")
+
+	allowed, err := services.NewWorkspaceService(h.DB).CanManage(user.(*models.User), permissions.(models.PermissionSet))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve workspace access"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Workspaces can only be managed from the default workspace"})
+		return false
+	}
+	return true
+}
+
+// workspaceResponse formats a workspace
+func (h *WorkspaceHandler) workspaceResponse(workspace *models.Workspace) gin.H {
+	return gin.H{
+		"id":         workspace.ID,
+		"name":       workspace.Name,
+		"slug":       workspace.Slug,
+		"created_at": workspace.CreatedAt,
+	}
+}
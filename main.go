@@ -3,10 +3,10 @@ package main
 import (
 	"fmt"
 	"log"
-	"os"
 	"project-x/config"
 	"project-x/models"
 	"project-x/routes"
+	"project-x/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/postgres"
@@ -17,6 +17,12 @@ func main() {
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Client IPs (used for login rate limits and sessions) come from X-Forwarded-For only
+	// when the request arrives through a configured proxy
+	if err := r.SetTrustedProxies(config.Get().TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Setup database connection
 	db, err := setupDatabase()
 	if err != nil {
@@ -24,28 +30,42 @@ func main() {
 	}
 
 	// Auto migrate database tables
-	if err := db.AutoMigrate(&models.User{}, &models.Task{}, &models.CollaborativeTask{}, &models.CollaborativeTaskParticipant{}, &models.Project{}, &models.UserProject{}); err != nil {
+	if err := db.AutoMigrate(models.All()...); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 	log.Println("✅ Database tables migrated successfully")
 
+	// Move free-text department names from older databases into the departments table
+	if err := services.MigrateLegacyDepartments(db); err != nil {
+		log.Fatal("Failed to migrate departments:", err)
+	}
+
+	// Create the default workspace and move data from before workspaces into it
+	if err := services.MigrateWorkspaces(db); err != nil {
+		log.Fatal("Failed to migrate workspaces:", err)
+	}
+
+	// Seed built-in roles and any permissions added since the last start
+	if err := services.NewPermissionService(db).SyncPermissions(); err != nil {
+		log.Fatal("Failed to sync roles and permissions:", err)
+	}
+
+	// Load token signing keys so a bad key setup fails at startup
+	if _, err := services.GetKeyRing(); err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
 	// Initialize routes
 	setupRoutes(r, db)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := config.Get().Port
 	log.Printf("🚀 Server starting on port %s", port)
 	r.Run(":" + port)
 }
 
 func setupDatabase() (*gorm.DB, error) {
-	config, err := config.LoadConfig()
-	if err != nil {
-		return nil, err
-	}
+	config := config.Get()
 
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
 		config.DBHost, config.DBUser, config.DBPassword, config.DBName, config.DBPort)
@@ -55,12 +75,21 @@ func setupDatabase() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	// Limit queries made for a request to the request's workspace
+	if err := services.RegisterWorkspaceScope(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
 func setupRoutes(r *gin.Engine, db *gorm.DB) {
 	routes.SetupAuthRoutes(r, db)
 	routes.SetupUserRoutes(r, db)
+	routes.SetupRoleRoutes(r, db)
+	routes.SetupDepartmentRoutes(r, db)
+	routes.SetupWorkspaceRoutes(r, db)
+	routes.SetupSCIMRoutes(r, db)
 	routes.SetupTaskRoutes(r, db)
 	routes.SetupProjectRoutes(r, db)
 	routes.SetupCollaborativeTaskRoutes(r, db)
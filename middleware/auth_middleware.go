@@ -1,18 +1,24 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"os"
+	"project-x/config"
 	"project-x/models"
+	"project-x/services"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 )
 
-// AuthMiddleware validates JWT token and sets user info in context
+// WorkspaceHeader lets callers allowed into several workspaces pick the one a request acts inside
+const WorkspaceHeader = "X-Workspace-ID"
+
+// AuthMiddleware validates a JWT or personal access token, sets user info in context and
+// limits the request's queries to the active workspace
 func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -30,54 +36,270 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		// Personal access tokens are looked up in the database, anything else must be a JWT
+		var userID uint
+		var impersonatorID *uint
+		var tokenWorkspaceID *uint
+		if strings.HasPrefix(tokenString, services.PersonalAccessTokenPrefix) {
+			accessToken, err := services.NewPersonalAccessTokenService(db).Authenticate(tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				c.Abort()
+				return
+			}
+
+			if !personalAccessTokenAllows(c, accessToken) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "token scope does not allow this request"})
+				c.Abort()
+				return
+			}
+
+			userID = accessToken.UserID
+			c.Set("tokenID", accessToken.ID)
+		} else {
+			keys, err := services.GetKeyRing()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "token verification unavailable"})
+				c.Abort()
+				return
+			}
+
+			// Parse and validate token
+			token, err := keys.Parse(tokenString, services.TokenTypeAccess, &models.Claims{})
+			if err != nil || !token.Valid {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				c.Abort()
+				return
+			}
+
+			claims, ok := token.Claims.(*models.Claims)
+			if !ok {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+				c.Abort()
+				return
+			}
+
+			// Reject tokens whose session was revoked or expired
+			session, err := services.NewSessionService(db).GetActiveSession(claims.SessionID)
+			if err != nil || !sessionMatchesClaims(session, claims) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+				c.Abort()
+				return
+			}
+
+			// The admin behind an impersonation token must still be allowed to impersonate
+			if claims.IsImpersonation() {
+				var impersonator models.User
+				if err := db.First(&impersonator, *claims.ImpersonatorID).Error; err != nil || !impersonator.Active ||
+					!services.NewPermissionService(db).RoleHasPermission(impersonator.Role, models.PermissionUserImpersonate) {
+					c.JSON(http.StatusUnauthorized, gin.H{"error": "impersonation no longer allowed"})
+					c.Abort()
+					return
+				}
+				impersonatorID = claims.ImpersonatorID
+			}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			userID = claims.UserID
+			tokenWorkspaceID = &claims.WorkspaceID
+			c.Set("sessionID", claims.SessionID)
+		}
+
+		// Get user from database
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(*models.Claims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		// Deactivation revokes sessions and tokens; this also covers anything issued since
+		if !user.Active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "account deactivated"})
 			c.Abort()
 			return
 		}
 
-		// Get user from database
-		var user models.User
-		if err := db.First(&user, claims.UserID).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		// Tokens stop working when their user is moved to another workspace
+		if tokenWorkspaceID != nil && *tokenWorkspaceID != user.WorkspaceID {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token was issued for another workspace"})
+			c.Abort()
+			return
+		}
+
+		// Admins and managers must enroll in 2FA before using anything outside /auth
+		if impersonatorID == nil && config.Get().RequireTwoFactorForPrivileged && user.Role.RequiresTwoFactor() &&
+			!user.TwoFactorEnabled && !strings.HasPrefix(c.FullPath(), "/auth/") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication enrollment required"})
+			c.Abort()
+			return
+		}
+
+		permissions, err := services.NewPermissionService(db).GetRolePermissions(user.Role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load permissions"})
 			c.Abort()
 			return
 		}
 
+		workspaceID, ok := resolveWorkspace(c, db, &user, permissions)
+		if !ok {
+			return
+		}
+		c.Request = c.Request.WithContext(services.WithWorkspace(c.Request.Context(), workspaceID))
+
 		// Set user info in context
 		c.Set("user", &user)
 		c.Set("userID", user.ID)
 		c.Set("userRole", user.Role)
+		c.Set("permissions", permissions)
+		c.Set("workspaceID", workspaceID)
+
+		if impersonatorID != nil {
+			handleImpersonatedRequest(c, db, *impersonatorID, user.ID)
+			return
+		}
 
 		c.Next()
 	}
 }
 
-// RequireRole middleware checks if user has required role
-func RequireRole(requiredRole models.Role) gin.HandlerFunc {
+// resolveWorkspace picks the request's workspace from the X-Workspace-ID header, falling back
+// to the user's own. It writes the error response itself.
+func resolveWorkspace(c *gin.Context, db *gorm.DB, user *models.User, permissions models.PermissionSet) (uint, bool) {
+	var requestedID *uint
+	if header := c.GetHeader(WorkspaceHeader); header != "" {
+		parsed, err := strconv.ParseUint(header, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + WorkspaceHeader + " header"})
+			c.Abort()
+			return 0, false
+		}
+		id := uint(parsed)
+		requestedID = &id
+	}
+
+	workspaceID, err := services.NewWorkspaceService(db).ResolveWorkspace(user, permissions, requestedID)
+	switch {
+	case errors.Is(err, services.ErrWorkspaceNotFound), errors.Is(err, services.ErrWorkspaceAccessDenied):
+		// Unknown and foreign workspaces look the same so IDs cannot be probed
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrWorkspaceAccessDenied.Error()})
+		c.Abort()
+		return 0, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve workspace"})
+		c.Abort()
+		return 0, false
+	}
+	return workspaceID, true
+}
+
+// impersonationBlockedRoutes are non-DELETE routes that must not be used while acting as another user
+var impersonationBlockedRoutes = map[string]bool{
+	"POST /auth/logout-all":                    true,
+	"POST /auth/2fa/setup":                     true,
+	"POST /auth/2fa/enable":                    true,
+	"POST /auth/2fa/disable":                   true,
+	"POST /auth/2fa/recovery-codes":            true,
+	"POST /users":                              true,
+	"POST /users/invitations/:inviteId/resend": true,
+	"POST /users/invitations":                  true,
+	"POST /users/:id/impersonate":              true,
+	"POST /users/:id/department-access":        true,
+	"POST /users/:id/tokens":                   true,
+	"POST /users/:id/unlock":                   true,
+	"POST /users/:id/reactivate":               true,
+	"PATCH /users/:id/password":                true,
+	"PATCH /users/me":                          true,
+	"PUT /users/me/avatar":                     true,
+	"PATCH /users/:id/role":                    true,
+	"PATCH /users/:id/department":              true,
+	"PATCH /users/:id/manager":                 true,
+	"POST /api/tasks/bulk-update":              true,
+	"PATCH /api/projects/:id/status":           true,
+	"POST /roles":                              true,
+	"POST /departments":                        true,
+	"POST /workspaces":                         true,
+	"POST /workspaces/current/scim-tokens":     true,
+	"PUT /departments/:id":                     true,
+	"PUT /roles/:name/permissions":             true,
+}
+
+// impersonationBlocked reports whether a request must be refused while acting as another user:
+// every DELETE and the routes in impersonationBlockedRoutes
+func impersonationBlocked(method, fullPath string) bool {
+	return method == http.MethodDelete || impersonationBlockedRoutes[method+" "+fullPath]
+}
+
+// handleImpersonatedRequest blocks destructive requests made while impersonating
+// and writes an audit entry for every request
+func handleImpersonatedRequest(c *gin.Context, db *gorm.DB, impersonatorID, impersonatedUserID uint) {
+	c.Set("impersonatorID", impersonatorID)
+	c.Set("impersonatedUserID", impersonatedUserID)
+	c.Header("X-Impersonated-By", strconv.FormatUint(uint64(impersonatorID), 10))
+
+	if impersonationBlocked(c.Request.Method, c.FullPath()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "this action is not allowed while impersonating"})
+		c.Abort()
+	} else {
+		c.Next()
+	}
+
+	sessionID, _ := c.Get("sessionID")
+	if err := services.NewImpersonationService(db).RecordRequest(
+		sessionID.(uint),
+		impersonatorID,
+		impersonatedUserID,
+		c.Request.Method,
+		c.Request.URL.Path,
+		c.Writer.Status(),
+		c.ClientIP(),
+	); err != nil {
+		log.Println("Failed to record impersonated request:", err)
+	}
+}
+
+// sessionMatchesClaims makes sure a token is used with the session it was issued for:
+// normal tokens belong to the session owner, impersonation tokens to the admin who started them
+func sessionMatchesClaims(session *models.Session, claims *models.Claims) bool {
+	if !claims.IsImpersonation() {
+		return session.UserID == claims.UserID && session.ImpersonatedUserID == nil
+	}
+
+	return session.UserID == *claims.ImpersonatorID &&
+		session.ImpersonatedUserID != nil &&
+		*session.ImpersonatedUserID == claims.UserID
+}
+
+// personalAccessTokenAllows checks a personal access token's scopes against the route being called.
+// Tokens can never be used for /auth or to manage other tokens.
+func personalAccessTokenAllows(c *gin.Context, accessToken *models.PersonalAccessToken) bool {
+	path := c.FullPath()
+	if strings.HasPrefix(path, "/auth") || strings.HasPrefix(path, "/users/:id/tokens") || strings.HasPrefix(path, "/workspaces/current/scim-tokens") {
+		return false
+	}
+
+	// "/api/tasks/..." -> "tasks", "/users/..." -> "users"
+	routeGroup := strings.TrimPrefix(strings.TrimPrefix(path, "/api"), "/")
+	routeGroup, _, _ = strings.Cut(routeGroup, "/")
+
+	method := c.Request.Method
+	readOnly := method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+
+	return services.TokenAllows(accessToken.ScopeList(), routeGroup, readOnly)
+}
+
+// RequirePermission middleware checks that the user's role grants a permission
+func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("userRole")
+		permissions, exists := c.Get("permissions")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 			c.Abort()
 			return
 		}
 
-		role := userRole.(models.Role)
-		if !hasPermission(role, requiredRole) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		if !permissions.(models.PermissionSet).Has(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions", "required_permission": permission})
 			c.Abort()
 			return
 		}
@@ -86,39 +308,63 @@ func RequireRole(requiredRole models.Role) gin.HandlerFunc {
 	}
 }
 
-// hasPermission checks if user role has permission for required role
-// Role hierarchy: admin > manager > head > employee
-func hasPermission(userRole, requiredRole models.Role) bool {
-	roleHierarchy := map[models.Role]int{
-		models.RoleEmployee: 1,
-		models.RoleHead:     2,
-		models.RoleManager:  3,
-		models.RoleAdmin:    4,
-	}
+// RequireWorkspaceManager middleware - roles and departments are shared by every workspace, so
+// only workspace managers in the default workspace may change them
+func RequireWorkspaceManager(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
+		permissions, _ := c.Get("permissions")
 
-	userLevel := roleHierarchy[userRole]
-	requiredLevel := roleHierarchy[requiredRole]
+		allowed, err := services.NewWorkspaceService(db).CanManage(user.(*models.User), permissions.(models.PermissionSet))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve workspace access"})
+			c.Abort()
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "roles and departments can only be changed from the default workspace"})
+			c.Abort()
+			return
+		}
 
-	return userLevel >= requiredLevel
+		c.Next()
+	}
 }
 
-// RequireAdmin middleware - only admin can access
-func RequireAdmin() gin.HandlerFunc {
-	return RequireRole(models.RoleAdmin)
-}
+// RequireSelf middleware - only the user named by the :id parameter may continue
+func RequireSelf() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			c.Abort()
+			return
+		}
 
-// RequireManagerOrHigher middleware - manager or admin can access
-func RequireManagerOrHigher() gin.HandlerFunc {
-	return RequireRole(models.RoleManager)
-}
+		requestedUserID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			c.Abort()
+			return
+		}
+
+		if userID.(uint) != uint(requestedUserID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+			c.Abort()
+			return
+		}
 
-// RequireHeadOrHigher middleware - head, manager, or admin can access
-func RequireHeadOrHigher() gin.HandlerFunc {
-	return RequireRole(models.RoleHead)
+		c.Next()
+	}
 }
 
-// RequireSelfOrAdmin middleware - user can access their own data or admin can access any
-func RequireSelfOrAdmin() gin.HandlerFunc {
+// RequireSelfOrPermission middleware - user can access their own data, holders of the permission can access any
+func RequireSelfOrPermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("userID")
 		if !exists {
@@ -127,15 +373,15 @@ func RequireSelfOrAdmin() gin.HandlerFunc {
 			return
 		}
 
-		userRole, exists := c.Get("userRole")
+		permissions, exists := c.Get("permissions")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 			c.Abort()
 			return
 		}
 
-		// Admin can access any user's data
-		if userRole.(models.Role) == models.RoleAdmin {
+		// Holders of the permission can access any user's data
+		if permissions.(models.PermissionSet).Has(permission) {
 			c.Next()
 			return
 		}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"project-x/models"
+	"testing"
+)
+
+func TestImpersonationBlocked(t *testing.T) {
+	cases := []struct {
+		method, path string
+		blocked      bool
+	}{
+		{http.MethodGet, "/api/tasks", false},
+		{http.MethodGet, "/users/:id", false},
+		{http.MethodPatch, "/api/tasks/:id", false},
+		{http.MethodPost, "/api/tasks", false},
+		{http.MethodDelete, "/api/tasks/:id", true},
+		{http.MethodDelete, "/users/me/avatar", true},
+		{http.MethodPost, "/users/:id/impersonate", true},
+		{http.MethodPost, "/users/:id/tokens", true},
+		{http.MethodPatch, "/users/:id/password", true},
+		{http.MethodPatch, "/users/:id/role", true},
+		{http.MethodPatch, "/users/me", true},
+		{http.MethodPost, "/auth/2fa/disable", true},
+		{http.MethodPost, "/auth/logout-all", true},
+		{http.MethodPut, "/roles/:name/permissions", true},
+		{http.MethodGet, "/roles", false}, // Only the listed method is blocked
+	}
+	for _, tc := range cases {
+		if got := impersonationBlocked(tc.method, tc.path); got != tc.blocked {
+			t.Errorf("%s %s: blocked = %v, want %v", tc.method, tc.path, got, tc.blocked)
+		}
+	}
+}
+
+func TestSessionMatchesClaims(t *testing.T) {
+	adminID, userID := uint(1), uint(2)
+	own := &models.Session{UserID: userID}
+	impersonation := &models.Session{UserID: adminID, ImpersonatedUserID: &userID}
+
+	cases := []struct {
+		name    string
+		session *models.Session
+		claims  *models.Claims
+		want    bool
+	}{
+		{"own token on own session", own, &models.Claims{UserID: userID}, true},
+		{"own token on another user's session", own, &models.Claims{UserID: adminID}, false},
+		{"normal token on an impersonation session", impersonation, &models.Claims{UserID: userID}, false},
+		{"impersonation token on its session", impersonation, &models.Claims{UserID: userID, ImpersonatorID: &adminID}, true},
+		{"impersonation token on the user's own session", own, &models.Claims{UserID: userID, ImpersonatorID: &adminID}, false},
+		{"impersonation token naming another admin", impersonation, &models.Claims{UserID: userID, ImpersonatorID: &userID}, false},
+	}
+	for _, tc := range cases {
+		if got := sessionMatchesClaims(tc.session, tc.claims); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
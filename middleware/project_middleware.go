@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequireProjectPermission middleware allows the request when the user's role grants the
+// permission everywhere, or their role in the project named by the URL parameter grants it
+func RequireProjectPermission(db *gorm.DB, param, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectID, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project ID"})
+			c.Abort()
+			return
+		}
+
+		authorizeProjectAccess(c, db, uint(projectID), permission)
+	}
+}
+
+// authorizeProjectAccess loads the caller's project membership, stores their project role
+// in context and checks the permission against the global and project roles. Projects of
+// other workspaces are not found.
+func authorizeProjectAccess(c *gin.Context, db *gorm.DB, projectID uint, permission string) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		c.Abort()
+		return
+	}
+	permissions, _ := c.Get("permissions")
+
+	// Memberships carry no workspace, so check the project itself in the request's workspace
+	db = db.WithContext(c.Request.Context())
+	var project models.Project
+	if err := db.Select("id").First(&project, projectID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		c.Abort()
+		return
+	}
+
+	var membership models.UserProject
+	isMember := db.Where("user_id = ? AND project_id = ?", userID, projectID).First(&membership).Error == nil
+	if isMember {
+		c.Set("projectRole", membership.Role)
+	}
+
+	if permissions.(models.PermissionSet).Has(permission) ||
+		(isMember && services.ProjectRoleHasPermission(membership.Role, permission)) {
+		c.Next()
+		return
+	}
+
+	if !isMember {
+		c.JSON(http.StatusForbidden, gin.H{"error": "project members only"})
+	} else {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient project permissions", "required_permission": permission})
+	}
+	c.Abort()
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitWindow tracks requests made by one client within the current window
+type rateLimitWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// ipRateLimiter is a fixed-window, in-memory request counter keyed by client IP
+type ipRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateLimitWindow
+}
+
+// allow records a request and reports whether it is within the limit,
+// along with how long the client must wait when it is not
+func (l *ipRateLimiter) allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+
+	// Drop expired windows so the map does not grow without bound
+	if len(l.clients) > 10000 {
+		for key, w := range l.clients {
+			if now.After(w.resetAt) {
+				delete(l.clients, key)
+			}
+		}
+	}
+
+	w, exists := l.clients[ip]
+	if !exists || now.After(w.resetAt) {
+		l.clients[ip] = &rateLimitWindow{count: 1, resetAt: now.Add(l.window)}
+		return true, 0
+	}
+
+	if w.count >= l.limit {
+		return false, w.resetAt.Sub(now)
+	}
+
+	w.count++
+	return true, 0
+}
+
+// RateLimitByIP middleware limits each client IP to `limit` requests per `window`
+func RateLimitByIP(limit int, window time.Duration) gin.HandlerFunc {
+	limiter := &ipRateLimiter{
+		limit:   limit,
+		window:  window,
+		clients: make(map[string]*rateLimitWindow),
+	}
+
+	return func(c *gin.Context) {
+		allowed, retryAfter := limiter.allow(c.ClientIP())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
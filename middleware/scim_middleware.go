@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"project-x/models"
+	"project-x/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SCIMAuthMiddleware authenticates a SCIM provisioning client by its bearer token and limits the
+// request's queries to the token's workspace. Errors use the SCIM error format.
+func SCIMAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !strings.HasPrefix(tokenString, services.SCIMTokenPrefix) {
+			abortSCIM(c, http.StatusUnauthorized, "SCIM bearer token required")
+			return
+		}
+
+		token, err := services.NewSCIMService(db).Authenticate(tokenString)
+		if err != nil {
+			abortSCIM(c, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		// The admin behind a token must still be allowed to provision users
+		var creator models.User
+		if err := db.First(&creator, token.CreatedBy).Error; err != nil || !creator.Active ||
+			!services.NewPermissionService(db).RoleHasPermission(creator.Role, models.PermissionSCIMManage) {
+			abortSCIM(c, http.StatusUnauthorized, "token owner can no longer manage provisioning")
+			return
+		}
+
+		c.Request = c.Request.WithContext(services.WithWorkspace(c.Request.Context(), token.WorkspaceID))
+		c.Set("scimTokenID", token.ID)
+		c.Set("scimTokenCreatedBy", token.CreatedBy)
+		c.Set("workspaceID", token.WorkspaceID)
+
+		c.Next()
+	}
+}
+
+// abortSCIM writes a SCIM error response and stops the request
+func abortSCIM(c *gin.Context, status int, detail string) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(status, gin.H{
+		"schemas": []string{services.SCIMErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	})
+	c.Abort()
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ChecklistItem is a lightweight to-do under a task or collaborative task. Checking items off
+// rolls up into the task's progress.
+type ChecklistItem struct {
+	ID          uint       `gorm:"primaryKey"`
+	WorkspaceID uint       `gorm:"index"`
+	TaskKind    string     `gorm:"not null;index:idx_checklist_items_task"` // See the TaskKind constants
+	TaskID      uint       `gorm:"not null;index:idx_checklist_items_task"`
+	Title       string     `gorm:"not null"`
+	Position    int        `gorm:"not null;default:0"` // Order within the task's checklist
+	Done        bool       `gorm:"not null;default:false"`
+	DoneBy      *uint      // Who checked the item off; nil while open
+	DoneAt      *time.Time // When the item was checked off; nil while open
+	CreatedBy   uint       `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
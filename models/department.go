@@ -0,0 +1,17 @@
+package models
+
+import "gorm.io/gorm"
+
+// Department is a unit of the organisation. Departments form a tree through ParentID;
+// access to and reports on a department cover all of its sub-departments.
+type Department struct {
+	gorm.Model
+	Name       string `gorm:"not null;uniqueIndex"`
+	ParentID   *uint  `gorm:"index"` // Nil for top-level departments
+	HeadUserID *uint  `gorm:"index"` // Designated head, who can act on the whole subtree
+
+	// Relationships
+	Parent   *Department  `gorm:"foreignKey:ParentID;constraint:OnDelete:RESTRICT"`
+	Children []Department `gorm:"foreignKey:ParentID"`
+	Head     *User        `gorm:"foreignKey:HeadUserID;constraint:-"` // No FK: users already reference departments
+}
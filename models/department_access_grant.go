@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// DepartmentAccessGrant lets a user act on a department other than their own, including
+// its sub-departments. A grant without a department opens every department.
+type DepartmentAccessGrant struct {
+	gorm.Model
+	UserID       uint  `gorm:"not null;index"`
+	DepartmentID *uint `gorm:"index"` // Nil grants every department
+	GrantedBy    uint  `gorm:"not null"`
+
+	// Relationships
+	User       User        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Department *Department `gorm:"foreignKey:DepartmentID;constraint:OnDelete:CASCADE"`
+}
+
+// CoversAllDepartments reports whether the grant opens every department
+func (g *DepartmentAccessGrant) CoversAllDepartments() bool {
+	return g.DepartmentID == nil
+}
@@ -0,0 +1,15 @@
+package models
+
+import "gorm.io/gorm"
+
+// ImpersonationLog records each request made while an admin acts as another user
+type ImpersonationLog struct {
+	gorm.Model
+	SessionID          uint   `gorm:"not null;index"`
+	ImpersonatorID     uint   `gorm:"not null;index"`
+	ImpersonatedUserID uint   `gorm:"not null;index"`
+	Method             string `gorm:"not null"`
+	Path               string `gorm:"not null"`
+	StatusCode         int    `gorm:"not null"`
+	IPAddress          string
+}
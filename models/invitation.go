@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invitation lets a new user join with a preset role and department and choose their own credentials
+type Invitation struct {
+	gorm.Model
+	WorkspaceID    uint       `gorm:"index"` // Workspace the new user joins
+	Email          string     `gorm:"index"` // Where the token is sent; empty only on older rows
+	TokenHash      string     `gorm:"not null;uniqueIndex"`
+	Role           Role       `gorm:"not null"`
+	DepartmentID   *uint      `gorm:"index"` // Always set for new invitations; nullable so older rows can be migrated
+	InvitedBy      uint       `gorm:"not null;index"`
+	ExpiresAt      time.Time  `gorm:"not null;index"`
+	AcceptedAt     *time.Time `gorm:"index"`
+	AcceptedUserID *uint      `gorm:"index"`
+	RevokedAt      *time.Time `gorm:"index"`
+
+	// Relationships
+	Inviter    User        `gorm:"foreignKey:InvitedBy;constraint:OnDelete:CASCADE"`
+	Department *Department `gorm:"foreignKey:DepartmentID;constraint:OnDelete:CASCADE"`
+}
+
+// Status returns pending, accepted, revoked or expired
+func (i *Invitation) Status() string {
+	switch {
+	case i.AcceptedAt != nil:
+		return "accepted"
+	case i.RevokedAt != nil:
+		return "revoked"
+	case time.Now().After(i.ExpiresAt):
+		return "expired"
+	default:
+		return "pending"
+	}
+}
@@ -0,0 +1,6 @@
+package models
+
+// All lists every model, in migration order
+func All() []interface{} {
+	return []interface{}{&Workspace{}, &Department{}, &User{}, &Task{}, &CollaborativeTask{}, &CollaborativeTaskParticipant{}, &Project{}, &UserProject{}, &Session{}, &RecoveryCode{}, &PersonalAccessToken{}, &OIDCAuthRequest{}, &Invitation{}, &PasswordResetToken{}, &PasswordHistory{}, &ImpersonationLog{}, &Permission{}, &RoleDefinition{}, &RolePermission{}, &DepartmentAccessGrant{}, &SCIMToken{}, &TaskHistory{}, &ChecklistItem{}, &TaskDependency{}}
+}
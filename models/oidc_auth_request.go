@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OIDCAuthRequest holds the state of an in-flight OIDC authorization-code login
+type OIDCAuthRequest struct {
+	gorm.Model
+	State        string    `gorm:"not null;uniqueIndex"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"` // PKCE verifier, sent when exchanging the code
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
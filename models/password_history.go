@@ -0,0 +1,13 @@
+package models
+
+import "gorm.io/gorm"
+
+// PasswordHistory keeps previous password hashes so recent passwords cannot be reused
+type PasswordHistory struct {
+	gorm.Model
+	UserID       uint   `gorm:"not null;index"`
+	PasswordHash string `gorm:"not null"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
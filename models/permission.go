@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Named permissions checked by routes and services
+const (
+	PermissionTaskCreate                = "task.create"                            // Create tasks and collaborative tasks for yourself
+	PermissionTaskAssign                = "task.assign"                            // Create tasks for other users
+	PermissionTaskDelete                = "task.delete"                            // Delete tasks and collaborative tasks
+	PermissionTaskReassign              = "task.reassign"                          // Reassign or force delete any task
+	PermissionTaskUpdateAny             = "task.update_any"                        // Change the status of other users' tasks
+	PermissionTaskReopen                = "task.reopen"                            // Move completed or cancelled tasks back to an open status
+	PermissionTaskBulkUpdate            = "task.bulk_update"                       // Update many task statuses at once
+	PermissionTaskViewAll               = "task.view_all"                          // List tasks across users
+	PermissionTaskViewDepartment        = "task.view_department"                   // List tasks by department
+	PermissionCollaborativeParticipants = "collaborative_task.manage_participants" // Add and remove participants
+	PermissionProjectCreate             = "project.create"
+	PermissionProjectView               = "project.view" // Granted globally: any project. Project members can always view their own.
+	PermissionProjectManageMembers      = "project.manage_members"
+	PermissionProjectUpdateStatus       = "project.update_status"
+	PermissionProjectDelete             = "project.delete"
+	PermissionReportView                = "report.view"           // Task statistics and reports
+	PermissionDepartmentAccessAll       = "department.access_all" // Assign, view and report across every department
+	PermissionDepartmentManage          = "department.manage"     // Create, edit and delete departments
+	PermissionUserManage                = "user.manage"           // Create, update, invite and delete users
+	PermissionUserImpersonate           = "user.impersonate"
+	PermissionRoleManage                = "role.manage"
+	PermissionWorkspaceManage           = "workspace.manage" // Create workspaces; from the default workspace, act inside any of them
+	PermissionSCIMManage                = "scim.manage"      // Create and revoke SCIM provisioning tokens
+)
+
+// Permission is an entry in the permission catalog. New permissions are added at
+// startup and granted once to the built-in roles that have them by default.
+type Permission struct {
+	Name        string `gorm:"primaryKey"`
+	Description string `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+// RoleDefinition is a role users can be given. Built-in roles are seeded at startup;
+// custom roles (e.g. "auditor") are created by admins.
+type RoleDefinition struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        Role   `gorm:"uniqueIndex;not null"`
+	Description string `gorm:"not null"`
+	BuiltIn     bool   `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	// Relationships
+	Permissions []RolePermission `gorm:"foreignKey:RoleName;references:Name;constraint:OnDelete:CASCADE"`
+}
+
+func (RoleDefinition) TableName() string {
+	return "roles"
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleName   Role   `gorm:"primaryKey"`
+	Permission string `gorm:"primaryKey"`
+	CreatedAt  time.Time
+}
+
+// PermissionSet is the set of permissions held by the current user
+type PermissionSet map[string]bool
+
+// Has reports whether the set contains a permission
+func (p PermissionSet) Has(permission string) bool {
+	return p[permission]
+}
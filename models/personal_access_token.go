@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken is a long-lived API token for scripts and CI integrations
+type PersonalAccessToken struct {
+	gorm.Model
+	UserID      uint       `gorm:"not null;index"`
+	Name        string     `gorm:"not null"`
+	TokenHash   string     `gorm:"not null;uniqueIndex"` // SHA-256 of the token
+	TokenPrefix string     `gorm:"not null"`             // First characters, shown so users can tell tokens apart
+	Scopes      string     `gorm:"not null"`             // Comma-separated, e.g. "tasks:write,projects:read"
+	ExpiresAt   *time.Time `gorm:"index"`                // Optional expiry
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time `gorm:"index"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// IsActive reports whether the token can still be used
+func (t *PersonalAccessToken) IsActive() bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || time.Now().Before(*t.ExpiresAt)
+}
+
+// ScopeList returns the token's scopes as a slice
+func (t *PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return nil
+	}
+	return strings.Split(t.Scopes, ",")
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SCIMToken authenticates an identity provider that provisions users into one workspace over SCIM
+type SCIMToken struct {
+	gorm.Model
+	WorkspaceID uint   `gorm:"index"` // Workspace the identity provider provisions into
+	Name        string `gorm:"not null"`
+	TokenHash   string `gorm:"not null;uniqueIndex"` // SHA-256 of the token
+	TokenPrefix string `gorm:"not null"`             // First characters, shown so admins can tell tokens apart
+	CreatedBy   uint   `gorm:"not null;index"`       // Admin who created the token; recorded as the actor of SCIM deactivations
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time `gorm:"index"`
+
+	// Relationships
+	Creator User `gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE"`
+}
+
+// IsActive reports whether the token can still be used
+func (t *SCIMToken) IsActive() bool {
+	return t.RevokedAt == nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session represents a login session backed by a rotating refresh token
+type Session struct {
+	gorm.Model
+	UserID            uint       `gorm:"not null;index"`
+	RefreshTokenHash  string     `gorm:"not null;uniqueIndex"` // SHA-256 of the current refresh token
+	PreviousTokenHash string     `gorm:"index"`                // Kept to detect reuse of a rotated token
+	ExpiresAt         time.Time  `gorm:"not null;index"`
+	LastUsedAt        time.Time  `gorm:"not null"`
+	RevokedAt         *time.Time `gorm:"index"`
+	UserAgent         string
+	IPAddress         string
+
+	// Set when an admin (UserID) is acting as another user
+	ImpersonatedUserID *uint `gorm:"index"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// TaskDependency records that one task or collaborative task blocks another: the blocked task
+// cannot start while the blocker is open. Links may cross projects.
+type TaskDependency struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"index"`
+	BlockerKind string `gorm:"not null;uniqueIndex:idx_task_dependencies_link"` // See the TaskKind constants
+	BlockerID   uint   `gorm:"not null;uniqueIndex:idx_task_dependencies_link"`
+	BlockedKind string `gorm:"not null;uniqueIndex:idx_task_dependencies_link;index:idx_task_dependencies_blocked"`
+	BlockedID   uint   `gorm:"not null;uniqueIndex:idx_task_dependencies_link;index:idx_task_dependencies_blocked"`
+	CreatedBy   uint   `gorm:"not null"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Kinds of task a TaskHistory entry belongs to
+const (
+	TaskKindTask          = "task"
+	TaskKindCollaborative = "collaborative_task"
+)
+
+// TaskHistory records one changed field of a task or collaborative task
+type TaskHistory struct {
+	ID          uint      `gorm:"primaryKey"`
+	WorkspaceID uint      `gorm:"index"`
+	TaskKind    string    `gorm:"not null;index:idx_task_histories_task"` // See the TaskKind constants
+	TaskID      uint      `gorm:"not null;index:idx_task_histories_task"`
+	Field       string    `gorm:"not null"` // JSON name of the field, e.g. "due_date"
+	OldValue    *string   // Nil when the field was empty
+	NewValue    *string   // Nil when the field was cleared
+	ChangedBy   uint      `gorm:"not null;index"`
+	ChangedAt   time.Time `gorm:"not null;index"`
+
+	// Relationships
+	Actor User `gorm:"foreignKey:ChangedBy;constraint:OnDelete:CASCADE"`
+}
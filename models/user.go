@@ -25,6 +25,18 @@ const (
 	TaskStatusCancelled  TaskStatus = "cancelled"
 )
 
+// Priority is shared by tasks and collaborative tasks
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+)
+
+// Priorities lists the valid priorities from lowest to highest
+var Priorities = []Priority{PriorityLow, PriorityMedium, PriorityHigh}
+
 type ProjectStatus string
 
 const (
@@ -36,12 +48,49 @@ const (
 
 type User struct {
 	gorm.Model
-	Username   string `gorm:"unique;not null;index"`
-	Password   string `gorm:"not null"`
-	Role       Role   `gorm:"not null;index"`
-	Department string `gorm:"not null;index"`
+	WorkspaceID  uint   `gorm:"index"` // Set from the request's workspace on create
+	Username     string `gorm:"unique;not null;index"`
+	Password     string `gorm:"not null"`
+	Role         Role   `gorm:"not null;index"`
+	DepartmentID *uint  `gorm:"index"` // Nil only for SSO and SCIM users without a matching department
+	ManagerID    *uint  `gorm:"index"` // Who this user reports to; nil at the top of the reporting line
+
+	// Profile
+	Email         *string `gorm:"uniqueIndex"`            // Optional; unique when set
+	EmailVerified bool    `gorm:"not null;default:false"` // Set only for addresses from the identity provider; SSO logins link only to these
+	DisplayName   string  // Human-readable name, falls back to the username
+	AvatarKey     string  // Storage key of the uploaded avatar, empty when there is none
+	Timezone      string  `gorm:"not null;default:'UTC'"` // IANA name, e.g. "Europe/Berlin"
+	Locale        string  `gorm:"not null;default:'en'"`  // BCP 47 tag, e.g. "en" or "de-DE"
+
+	// Deactivated users keep their history but cannot sign in or be given new work
+	Active        bool       `gorm:"not null;default:true;index"`
+	DeactivatedAt *time.Time `gorm:"index"`
+	DeactivatedBy *uint
+
+	// Login protection
+	FailedLoginAttempts int        `gorm:"not null;default:0"`
+	LockoutCount        int        `gorm:"not null;default:0"` // Consecutive lockouts, used to grow the lockout window
+	LockedUntil         *time.Time `gorm:"index"`
+	LastFailedLoginAt   *time.Time
+
+	// Two-factor authentication (TOTP)
+	TwoFactorEnabled      bool   `gorm:"not null;default:false"`
+	TwoFactorSecret       string // Base32 TOTP secret, set during enrollment
+	TwoFactorLastUsedStep int64  `gorm:"not null;default:0"` // Prevents reusing a code within its time step
+	TwoFactorChallenge    string // Hash of the ID of the outstanding login challenge, cleared when it is used
+
+	// Identifier the SCIM provisioning client knows the user by
+	ExternalID string `gorm:"index"`
+
+	// Single sign-on identity, set when the user signs in through OIDC
+	OIDCIssuer  string  `gorm:"uniqueIndex:idx_users_oidc_identity"`
+	OIDCSubject *string `gorm:"uniqueIndex:idx_users_oidc_identity"`
 
 	// Relationships
+	Department         *Department         `gorm:"foreignKey:DepartmentID;constraint:OnDelete:SET NULL"`
+	Manager            *User               `gorm:"foreignKey:ManagerID;constraint:OnDelete:SET NULL"`
+	DirectReports      []User              `gorm:"foreignKey:ManagerID"`
 	Tasks              []Task              `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	CollaborativeTasks []CollaborativeTask `gorm:"foreignKey:LeadUserID;constraint:OnDelete:CASCADE"`   // Tasks where user is the lead
 	Projects           []Project           `gorm:"many2many:user_projects;constraint:OnDelete:CASCADE"` // Many-to-many relationship
@@ -53,13 +102,21 @@ type User struct {
 
 type Task struct {
 	gorm.Model
+	WorkspaceID uint       `gorm:"index"`
 	Title       string     `gorm:"not null;index"`
 	Description string     `gorm:"not null"`
 	Status      TaskStatus `gorm:"not null;default:'pending';index"`
+	Priority    Priority   `gorm:"not null;default:'medium';index"`
 	UserID      uint       `gorm:"not null;index"`
 	ProjectID   *uint      `gorm:"index"` // Optional: task can belong to a project
 	AssignedAt  time.Time  `gorm:"not null;index"`
-	DueDate     *time.Time `gorm:"index"` // Optional due date
+	DueDate     *time.Time `gorm:"index"`                  // Optional due date
+	StartedAt   *time.Time `gorm:"index"`                  // First moved to in_progress; stamped by the status workflow
+	CompletedAt *time.Time `gorm:"index"`                  // Set while completed; stamped by the status workflow
+	Progress    int        `gorm:"not null;default:0"`     // 0-100 percentage, rolled up from subtasks and checklist items
+	ParentKind  string     `gorm:"index:idx_tasks_parent"` // Set on subtasks: the TaskKind of the parent
+	ParentID    *uint      `gorm:"index:idx_tasks_parent"` // Set on subtasks: the parent task or collaborative task
+	Position    int        `gorm:"not null;default:0"`     // Order among the parent's subtasks
 
 	// Relationships
 	User    User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -68,16 +125,19 @@ type Task struct {
 
 type CollaborativeTask struct {
 	gorm.Model
+	WorkspaceID uint       `gorm:"index"`
 	Title       string     `gorm:"not null;index"`
 	Description string     `gorm:"not null"`
 	Status      TaskStatus `gorm:"not null;default:'pending';index"`
 	LeadUserID  uint       `gorm:"not null;index"` // Main person responsible
 	ProjectID   *uint      `gorm:"index"`          // Optional: collaborative task can belong to a project
 	AssignedAt  time.Time  `gorm:"not null;index"`
-	DueDate     *time.Time `gorm:"index"`                  // Optional due date
-	Priority    string     `gorm:"default:'medium';index"` // high, medium, low
-	Progress    int        `gorm:"default:0;index"`        // 0-100 percentage
+	DueDate     *time.Time `gorm:"index"` // Optional due date
+	Priority    Priority   `gorm:"default:'medium';index"`
+	Progress    int        `gorm:"default:0;index"`        // 0-100 percentage, rolled up once the task has subtasks or checklist items
 	Complexity  string     `gorm:"default:'medium';index"` // simple, medium, complex
+	StartedAt   *time.Time `gorm:"index"`                  // First moved to in_progress; stamped by the status workflow
+	CompletedAt *time.Time `gorm:"index"`                  // Set while completed; stamped by the status workflow
 
 	// Relationships
 	LeadUser     User                           `gorm:"foreignKey:LeadUserID;constraint:OnDelete:SET NULL"`
@@ -103,6 +163,7 @@ type CollaborativeTaskParticipant struct {
 
 type Project struct {
 	gorm.Model
+	WorkspaceID uint          `gorm:"index"`
 	Title       string        `gorm:"not null;index"`
 	Description string        `gorm:"not null"`
 	Status      ProjectStatus `gorm:"not null;default:'active';index"`
@@ -117,20 +178,96 @@ type Project struct {
 	CollaborativeTasks []CollaborativeTask `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
 }
 
+// DepartmentName returns the name of the user's department, or "" when it is not loaded
+func (u *User) DepartmentName() string {
+	if u.Department == nil {
+		return ""
+	}
+	return u.Department.Name
+}
+
+// Name returns the display name, or the username when no display name is set
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+// EmailAddress returns the email address, or "" when none is set
+func (u *User) EmailAddress() string {
+	if u.Email == nil {
+		return ""
+	}
+	return *u.Email
+}
+
+// Valid reports whether the priority is one of the known priorities
+func (p Priority) Valid() bool {
+	return p.Weight() > 0
+}
+
+// Weight ranks a priority for sorting and weighted statistics: low 1, medium 2 and high 3.
+// Unknown priorities weigh 0.
+func (p Priority) Weight() int {
+	for i, priority := range Priorities {
+		if priority == p {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// IsLocked reports whether the account is currently locked out
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
 // UserProject represents the many-to-many relationship between users and projects
 type UserProject struct {
 	UserID    uint      `gorm:"primaryKey;index"`
 	ProjectID uint      `gorm:"primaryKey;index"`
 	JoinedAt  time.Time `gorm:"not null;index"`
-	Role      string    `gorm:"not null;default:'member';index"` // member, lead, contributor, etc.
+	Role      string    `gorm:"not null;default:'member';index"` // See the ProjectRole constants
 
 	// Relationships
 	User    User    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Project Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
 }
 
+// Project roles stored on UserProject.Role. They grant permissions inside one project only.
+const (
+	ProjectRoleManager = "manager" // Given to the project creator
+	ProjectRoleLead    = "lead"
+	ProjectRoleMember  = "member"
+)
+
+// RequiresTwoFactor reports whether the role must use two-factor authentication when enforcement is on
+func (r Role) RequiresTwoFactor() bool {
+	return r == RoleAdmin || r == RoleManager
+}
+
 type Claims struct {
-	UserID uint `json:"userId"`
-	Role   Role `json:"role"`
+	UserID    uint `json:"userId"`
+	Role      Role `json:"role"`
+	SessionID uint `json:"sid"`
+
+	// Workspace the user belonged to when the token was issued
+	WorkspaceID uint `json:"wid"`
+
+	// Set only on impersonation tokens: the admin acting as UserID
+	ImpersonatorID *uint `json:"impersonatorId,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IsImpersonation reports whether the token was issued for an admin acting as another user
+func (c *Claims) IsImpersonation() bool {
+	return c.ImpersonatorID != nil
+}
+
+// ChallengeClaims identify a user who passed the password step but still owes a second factor
+type ChallengeClaims struct {
+	UserID  uint   `json:"userId"`
+	Purpose string `json:"purpose"`
 	jwt.RegisteredClaims
 }
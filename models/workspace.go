@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// Workspace is a tenant. Users, projects, tasks and collaborative tasks belong to exactly
+// one workspace and are never visible from another.
+type Workspace struct {
+	gorm.Model
+	Name string `gorm:"not null"`
+	Slug string `gorm:"not null;uniqueIndex"` // URL friendly identifier, "default" for the workspace created on first start
+}
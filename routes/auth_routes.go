@@ -1,7 +1,9 @@
 package routes
 
 import (
+	"project-x/config"
 	"project-x/handlers"
+	"project-x/middleware"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -9,9 +11,38 @@ import (
 
 func SetupAuthRoutes(r *gin.Engine, db *gorm.DB) {
 	authHandler := handlers.NewAuthHandler(db)
+	twoFactorHandler := handlers.NewTwoFactorHandler(db)
+	oidcHandler := handlers.NewOIDCHandler(db)
+	invitationHandler := handlers.NewInvitationHandler(db)
+	passwordResetHandler := handlers.NewPasswordResetHandler(db)
+	cfg := config.Get()
+
+	// Public verification keys for other services
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	authGroup := r.Group("/auth")
 	{
-		authGroup.POST("/login", authHandler.Login)
+		authGroup.POST("/login", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), authHandler.Login)
+		authGroup.POST("/2fa/verify", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), authHandler.VerifyTwoFactor)
+		authGroup.POST("/refresh", authHandler.Refresh)
+		authGroup.POST("/accept-invite", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), invitationHandler.AcceptInvitation)
+
+		// Self-service password reset
+		authGroup.POST("/password-reset/request", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), passwordResetHandler.RequestReset)
+		authGroup.POST("/password-reset/confirm", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), passwordResetHandler.ConfirmReset)
+
+		// Single sign-on through the company identity provider
+		authGroup.GET("/oidc/login", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), oidcHandler.Login)
+		authGroup.GET("/oidc/callback", middleware.RateLimitByIP(cfg.LoginRateLimit, cfg.LoginRateWindow), oidcHandler.Callback)
+
+		// Session management - authenticated users only
+		authGroup.POST("/logout", middleware.AuthMiddleware(db), authHandler.Logout)
+		authGroup.POST("/logout-all", middleware.AuthMiddleware(db), authHandler.LogoutAll)
+
+		// Two-factor enrollment - authenticated users only
+		authGroup.POST("/2fa/setup", middleware.AuthMiddleware(db), twoFactorHandler.Setup)
+		authGroup.POST("/2fa/enable", middleware.AuthMiddleware(db), twoFactorHandler.Enable)
+		authGroup.POST("/2fa/disable", middleware.AuthMiddleware(db), twoFactorHandler.Disable)
+		authGroup.POST("/2fa/recovery-codes", middleware.AuthMiddleware(db), twoFactorHandler.RegenerateRecoveryCodes)
 	}
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"project-x/handlers"
 	"project-x/middleware"
+	"project-x/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,29 +11,55 @@ import (
 
 func SetupCollaborativeTaskRoutes(r *gin.Engine, db *gorm.DB) {
 	collaborativeTaskHandler := handlers.NewCollaborativeTaskHandler(db)
+	subtaskHandler := handlers.NewSubtaskHandler(db)
+	dependencyHandler := handlers.NewDependencyHandler(db)
 
 	collaborativeTaskGroup := r.Group("/api/collaborative-tasks")
 	collaborativeTaskGroup.Use(middleware.AuthMiddleware(db))
 	{
-		// Create collaborative task (Head/Manager/Admin only)
-		collaborativeTaskGroup.POST("", middleware.RequireHeadOrHigher(), collaborativeTaskHandler.CreateCollaborativeTask)
+		// Create collaborative task
+		collaborativeTaskGroup.POST("", middleware.RequirePermission(models.PermissionTaskCreate), collaborativeTaskHandler.CreateCollaborativeTask)
 
 		// Get user's collaborative tasks (all participants)
 		collaborativeTaskGroup.GET("", collaborativeTaskHandler.GetUserCollaborativeTasks)
 
-		// Get collaborative task details (participants only)
+		// Get collaborative task details (anyone who may view it)
 		collaborativeTaskGroup.GET("/:id", collaborativeTaskHandler.GetCollaborativeTaskDetails)
 
-		// Get collaborative task statistics (participants only)
+		// Edit collaborative task with a JSON merge patch (contributors may patch the progress alone)
+		collaborativeTaskGroup.PATCH("/:id", collaborativeTaskHandler.EditCollaborativeTask)
+
+		// Get the recorded changes of a collaborative task
+		collaborativeTaskGroup.GET("/:id/history", collaborativeTaskHandler.GetCollaborativeTaskHistory)
+
+		// Get collaborative task statistics (anyone who may view it)
 		collaborativeTaskGroup.GET("/:id/statistics", collaborativeTaskHandler.GetCollaborativeTaskStatistics)
 
-		// Add participant to collaborative task (Lead/Manager/Admin only)
-		collaborativeTaskGroup.POST("/:id/participants", middleware.RequireHeadOrHigher(), collaborativeTaskHandler.AddParticipant)
+		// Add participant to collaborative task (lead, project leads, or manage_participants in the lead's department)
+		collaborativeTaskGroup.POST("/:id/participants", collaborativeTaskHandler.AddParticipant)
 
-		// Remove participant from collaborative task (Lead/Manager/Admin only)
-		collaborativeTaskGroup.DELETE("/:id/participants/:userId", middleware.RequireHeadOrHigher(), collaborativeTaskHandler.RemoveParticipant)
+		// Remove participant from collaborative task (same rules as adding)
+		collaborativeTaskGroup.DELETE("/:id/participants/:userId", collaborativeTaskHandler.RemoveParticipant)
 
-		// Update task progress (participants only)
+		// Update task progress (lead and contributors, project leads, or task.update_any in the lead's department)
 		collaborativeTaskGroup.PATCH("/:id/progress", collaborativeTaskHandler.UpdateTaskProgress)
+
+		// Subtasks (viewers may list them; adding and reordering needs the edit access)
+		collaborativeTaskGroup.GET("/:id/subtasks", subtaskHandler.GetCollaborativeTaskSubtasks)
+		collaborativeTaskGroup.POST("/:id/subtasks", subtaskHandler.CreateCollaborativeTaskSubtask)
+		collaborativeTaskGroup.PUT("/:id/subtasks/order", subtaskHandler.ReorderCollaborativeTaskSubtasks)
+
+		// Checklist (contributors may check items off; other changes need the edit access)
+		collaborativeTaskGroup.GET("/:id/checklist", subtaskHandler.GetCollaborativeTaskChecklist)
+		collaborativeTaskGroup.POST("/:id/checklist", subtaskHandler.AddCollaborativeTaskChecklistItem)
+		collaborativeTaskGroup.PUT("/:id/checklist/order", subtaskHandler.ReorderCollaborativeTaskChecklist)
+		collaborativeTaskGroup.PATCH("/:id/checklist/:itemId", subtaskHandler.UpdateCollaborativeTaskChecklistItem)
+		collaborativeTaskGroup.DELETE("/:id/checklist/:itemId", subtaskHandler.DeleteCollaborativeTaskChecklistItem)
+		collaborativeTaskGroup.POST("/:id/checklist/:itemId/convert", subtaskHandler.ConvertCollaborativeTaskChecklistItem)
+
+		// Dependencies (viewers may list them; linking needs the edit access to the blocked task)
+		collaborativeTaskGroup.GET("/:id/dependencies", dependencyHandler.GetCollaborativeTaskDependencies)
+		collaborativeTaskGroup.POST("/:id/dependencies", dependencyHandler.AddCollaborativeTaskDependency)
+		collaborativeTaskGroup.DELETE("/:id/dependencies/:dependencyId", dependencyHandler.RemoveCollaborativeTaskDependency)
 	}
 }
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"project-x/handlers"
+	"project-x/middleware"
+	"project-x/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupDepartmentRoutes(r *gin.Engine, db *gorm.DB) {
+	departmentHandler := handlers.NewDepartmentHandler(db)
+
+	departmentGroup := r.Group("/departments")
+	departmentGroup.Use(middleware.AuthMiddleware(db))
+	{
+		// Browsing the department tree (all authenticated users)
+		departmentGroup.GET("", departmentHandler.ListDepartments)
+		departmentGroup.GET("/:id", departmentHandler.GetDepartment)
+
+		// Reports roll up through sub-departments; limited to the caller's departments
+		departmentGroup.GET("/:id/report", middleware.RequirePermission(models.PermissionReportView), departmentHandler.GetDepartmentReport)
+
+		// Department management; departments are shared by every workspace
+		departmentGroup.POST("", middleware.RequirePermission(models.PermissionDepartmentManage), middleware.RequireWorkspaceManager(db), departmentHandler.CreateDepartment)
+		departmentGroup.PUT("/:id", middleware.RequirePermission(models.PermissionDepartmentManage), middleware.RequireWorkspaceManager(db), departmentHandler.UpdateDepartment)
+		departmentGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionDepartmentManage), middleware.RequireWorkspaceManager(db), departmentHandler.DeleteDepartment)
+	}
+}
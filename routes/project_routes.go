@@ -3,6 +3,7 @@ package routes
 import (
 	"project-x/handlers"
 	"project-x/middleware"
+	"project-x/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -14,31 +15,34 @@ func SetupProjectRoutes(router *gin.Engine, db *gorm.DB) {
 	// Project routes group
 	projects := router.Group("/api/projects")
 	{
-		// Create project (Manager/Admin only)
-		projects.POST("/", middleware.AuthMiddleware(db), middleware.RequireManagerOrHigher(), projectHandler.CreateProject)
+		// Create project
+		projects.POST("/", middleware.AuthMiddleware(db), middleware.RequirePermission(models.PermissionProjectCreate), projectHandler.CreateProject)
 
 		// Get user's projects (all authenticated users)
 		projects.GET("/my-projects", middleware.AuthMiddleware(db), projectHandler.GetUserProjects)
 
 		// Get project details (project members only)
-		projects.GET("/:id", middleware.AuthMiddleware(db), projectHandler.GetProjectDetails)
+		projects.GET("/:id", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectView), projectHandler.GetProjectDetails)
 
 		// Get project members (project members only)
-		projects.GET("/:id/members", middleware.AuthMiddleware(db), projectHandler.GetProjectMembers)
+		projects.GET("/:id/members", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectView), projectHandler.GetProjectMembers)
 
-		// Add user to project (Manager/Admin only)
-		projects.POST("/:id/members", middleware.AuthMiddleware(db), middleware.RequireManagerOrHigher(), projectHandler.AddUserToProject)
+		// Add user to project (project leads can manage their own project)
+		projects.POST("/:id/members", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectManageMembers), projectHandler.AddUserToProject)
 
-		// Remove user from project (Manager/Admin only)
-		projects.DELETE("/:id/members/:userId", middleware.AuthMiddleware(db), middleware.RequireManagerOrHigher(), projectHandler.RemoveUserFromProject)
+		// Remove user from project (project leads can manage their own project)
+		projects.DELETE("/:id/members/:userId", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectManageMembers), projectHandler.RemoveUserFromProject)
 
-		// Update project status (Manager/Admin only)
-		projects.PATCH("/:id/status", middleware.AuthMiddleware(db), middleware.RequireManagerOrHigher(), projectHandler.UpdateProjectStatus)
+		// Update project status
+		projects.PATCH("/:id/status", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectUpdateStatus), projectHandler.UpdateProjectStatus)
 
 		// Get project statistics (project members only)
-		projects.GET("/:id/statistics", middleware.AuthMiddleware(db), projectHandler.GetProjectStatistics)
+		projects.GET("/:id/statistics", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectView), projectHandler.GetProjectStatistics)
 
-		// Delete project (Admin only)
-		projects.DELETE("/:id", middleware.AuthMiddleware(db), middleware.RequireAdmin(), projectHandler.DeleteProject)
+		// Get the dependency graph of the project's tasks (project members only)
+		projects.GET("/:id/dependency-graph", middleware.AuthMiddleware(db), middleware.RequireProjectPermission(db, "id", models.PermissionProjectView), projectHandler.GetDependencyGraph)
+
+		// Delete project
+		projects.DELETE("/:id", middleware.AuthMiddleware(db), middleware.RequirePermission(models.PermissionProjectDelete), projectHandler.DeleteProject)
 	}
 }
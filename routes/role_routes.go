@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"project-x/handlers"
+	"project-x/middleware"
+	"project-x/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupRoleRoutes(r *gin.Engine, db *gorm.DB) {
+	roleHandler := handlers.NewRoleHandler(db)
+
+	roleGroup := r.Group("/roles")
+	roleGroup.Use(middleware.AuthMiddleware(db), middleware.RequirePermission(models.PermissionRoleManage))
+	{
+		roleGroup.GET("/permissions", roleHandler.ListPermissions)
+		roleGroup.GET("", roleHandler.ListRoles)
+		roleGroup.GET("/:name", roleHandler.GetRole)
+
+		// Roles are shared by every workspace
+		roleGroup.POST("", middleware.RequireWorkspaceManager(db), roleHandler.CreateRole)
+		roleGroup.PUT("/:name/permissions", middleware.RequireWorkspaceManager(db), roleHandler.UpdateRolePermissions)
+		roleGroup.DELETE("/:name", middleware.RequireWorkspaceManager(db), roleHandler.DeleteRole)
+	}
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"project-x/handlers"
+	"project-x/middleware"
+	"project-x/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SetupSCIMRoutes serves SCIM 2.0 to identity providers, authenticated with a provisioning token
+func SetupSCIMRoutes(r *gin.Engine, db *gorm.DB) {
+	scimHandler := handlers.NewSCIMHandler(db)
+
+	scimGroup := r.Group(services.SCIMBasePath)
+	scimGroup.Use(middleware.SCIMAuthMiddleware(db))
+	{
+		// Discovery
+		scimGroup.GET("/ServiceProviderConfig", scimHandler.GetServiceProviderConfig)
+		scimGroup.GET("/ResourceTypes", scimHandler.GetResourceTypes)
+
+		// Users
+		scimGroup.GET("/Users", scimHandler.ListUsers)
+		scimGroup.POST("/Users", scimHandler.CreateUser)
+		scimGroup.GET("/Users/:id", scimHandler.GetUser)
+		scimGroup.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scimGroup.PATCH("/Users/:id", scimHandler.PatchUser)
+		scimGroup.DELETE("/Users/:id", scimHandler.DeleteUser)
+
+		// Groups: roles and departments
+		scimGroup.GET("/Groups", scimHandler.ListGroups)
+		scimGroup.POST("/Groups", scimHandler.CreateGroup)
+		scimGroup.GET("/Groups/:id", scimHandler.GetGroup)
+		scimGroup.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scimGroup.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scimGroup.DELETE("/Groups/:id", scimHandler.DeleteGroup)
+	}
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"project-x/handlers"
 	"project-x/middleware"
+	"project-x/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,48 +11,70 @@ import (
 
 func SetupTaskRoutes(r *gin.Engine, db *gorm.DB) {
 	taskHandler := handlers.NewTaskHandler(db)
+	subtaskHandler := handlers.NewSubtaskHandler(db)
+	dependencyHandler := handlers.NewDependencyHandler(db)
 
 	taskGroup := r.Group("/api/tasks")
 	taskGroup.Use(middleware.AuthMiddleware(db))
 	{
-		// Basic task endpoints - Head/Manager/Admin only (exclude Employee)
-		taskGroup.POST("", middleware.RequireHeadOrHigher(), taskHandler.CreateTask)
+		// Basic task endpoints
+		taskGroup.POST("", middleware.RequirePermission(models.PermissionTaskCreate), taskHandler.CreateTask)
 		taskGroup.GET("", taskHandler.GetUserTasks) // All users can view their own tasks
 		taskGroup.GET("/status/:status", taskHandler.GetTasksByStatus)
-		taskGroup.PATCH("/:id/status", taskHandler.UpdateTaskStatus)                       // All users can update their own task status
-		taskGroup.DELETE("/:id", middleware.RequireHeadOrHigher(), taskHandler.DeleteTask) // Head+ can delete tasks
+		taskGroup.PATCH("/:id/status", taskHandler.UpdateTaskStatus) // Owners, project leads and task.update_any in the owner's department
+		taskGroup.PATCH("/:id", taskHandler.EditTask)                // JSON merge patch, same access as the status
+		taskGroup.GET("/:id/history", taskHandler.GetTaskHistory)    // Owners, managers, project members and department viewers
+		taskGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionTaskDelete), taskHandler.DeleteTask)
 
-		// Collaborative task endpoints - Head/Manager/Admin only (exclude Employee)
-		taskGroup.POST("/collaborative", middleware.RequireHeadOrHigher(), taskHandler.CreateCollaborativeTask)
+		// Collaborative task endpoints
+		taskGroup.POST("/collaborative", middleware.RequirePermission(models.PermissionTaskCreate), taskHandler.CreateCollaborativeTask)
 		taskGroup.GET("/collaborative", taskHandler.GetUserCollaborativeTasks)
 		taskGroup.GET("/collaborative/status/:status", taskHandler.GetCollaborativeTasksByStatus)
 		taskGroup.PATCH("/collaborative/:id/status", taskHandler.UpdateCollaborativeTaskStatus)
-		taskGroup.DELETE("/collaborative/:id", middleware.RequireHeadOrHigher(), taskHandler.DeleteCollaborativeTask) // Head+ can delete collaborative tasks
+		taskGroup.DELETE("/collaborative/:id", middleware.RequirePermission(models.PermissionTaskDelete), taskHandler.DeleteCollaborativeTask)
 
 		// Project task endpoints - project members only
-		taskGroup.GET("/project/:projectId", taskHandler.GetProjectTasks)
-		taskGroup.GET("/project/:projectId/collaborative", taskHandler.GetProjectCollaborativeTasks)
-
-		// Admin/Manager task assignment endpoints
-		taskGroup.POST("/assign", middleware.RequireManagerOrHigher(), taskHandler.CreateTaskForUser)
-		taskGroup.POST("/collaborative/assign", middleware.RequireManagerOrHigher(), taskHandler.CreateCollaborativeTaskForUser)
-
-		// Department-based endpoints - Manager/Admin only (since Manager > Head)
-		taskGroup.GET("/department/:dept", middleware.RequireManagerOrHigher(), taskHandler.GetTasksByDepartment)
-		taskGroup.GET("/department/:dept/collaborative", middleware.RequireManagerOrHigher(), taskHandler.GetCollaborativeTasksByDepartment)
-
-		// Management endpoints - Manager+ can access
-		taskGroup.GET("/all", middleware.RequireManagerOrHigher(), taskHandler.GetUserTasks)                  // Manager+ can see all tasks
-		taskGroup.PATCH("/:id/assign", middleware.RequireManagerOrHigher(), taskHandler.UpdateTaskStatus)     // Manager+ can assign tasks
-		taskGroup.POST("/bulk-update", middleware.RequireManagerOrHigher(), taskHandler.BulkUpdateTaskStatus) // Manager+ can bulk update
-		taskGroup.GET("/statistics", middleware.RequireManagerOrHigher(), taskHandler.GetTaskStatistics)      // Manager+ can see statistics
-
-		// Report endpoints - Manager+ can access
-		taskGroup.GET("/reports/project/:projectId", middleware.RequireManagerOrHigher(), taskHandler.GetProjectReport) // Project report
-		taskGroup.GET("/reports/user/:userId", middleware.RequireManagerOrHigher(), taskHandler.GetUserReport)          // User report
-
-		// Admin-only endpoints
-		taskGroup.DELETE("/:id/force", middleware.RequireAdmin(), taskHandler.DeleteTask)         // Admin only can force delete
-		taskGroup.PATCH("/:id/reassign", middleware.RequireAdmin(), taskHandler.UpdateTaskStatus) // Admin only can reassign
+		taskGroup.GET("/project/:projectId", middleware.RequireProjectPermission(db, "projectId", models.PermissionProjectView), taskHandler.GetProjectTasks)
+		taskGroup.GET("/project/:projectId/collaborative", middleware.RequireProjectPermission(db, "projectId", models.PermissionProjectView), taskHandler.GetProjectCollaborativeTasks)
+
+		// Task assignment endpoints - managers assign to their reports, task.assign within departments
+		taskGroup.POST("/assign", taskHandler.CreateTaskForUser)
+		taskGroup.POST("/collaborative/assign", taskHandler.CreateCollaborativeTaskForUser)
+
+		// Department-based endpoints
+		taskGroup.GET("/department/:dept", middleware.RequirePermission(models.PermissionTaskViewDepartment), taskHandler.GetTasksByDepartment)
+		taskGroup.GET("/department/:dept/collaborative", middleware.RequirePermission(models.PermissionTaskViewDepartment), taskHandler.GetCollaborativeTasksByDepartment)
+
+		// Management endpoints
+		taskGroup.GET("/all", middleware.RequirePermission(models.PermissionTaskViewAll), taskHandler.GetUserTasks)
+		taskGroup.POST("/bulk-update", middleware.RequirePermission(models.PermissionTaskBulkUpdate), taskHandler.BulkUpdateTaskStatus)
+		taskGroup.GET("/statistics", middleware.RequirePermission(models.PermissionReportView), taskHandler.GetTaskStatistics)
+
+		// Report endpoints
+		taskGroup.GET("/reports/project/:projectId", middleware.RequirePermission(models.PermissionReportView), taskHandler.GetProjectReport)
+		taskGroup.GET("/reports/user/:userId", middleware.RequirePermission(models.PermissionReportView), taskHandler.GetUserReport)
+
+		// Assignment and reassignment - the task access policy checks project roles and departments
+		taskGroup.PATCH("/:id/assign", taskHandler.AssignTask)
+		taskGroup.PATCH("/:id/reassign", taskHandler.ReassignTask)
+		taskGroup.DELETE("/:id/force", middleware.RequirePermission(models.PermissionTaskReassign), taskHandler.ForceDeleteTask)
+
+		// Subtasks and checklists - viewers may list them, owners check items off, the rest needs the edit access
+		taskGroup.GET("/:id/subtasks", subtaskHandler.GetTaskSubtasks)
+		taskGroup.POST("/:id/subtasks", subtaskHandler.CreateTaskSubtask)
+		taskGroup.PUT("/:id/subtasks/order", subtaskHandler.ReorderTaskSubtasks)
+		taskGroup.POST("/:id/promote", subtaskHandler.PromoteSubtask) // Turn a subtask into a top-level task
+		taskGroup.GET("/:id/checklist", subtaskHandler.GetTaskChecklist)
+		taskGroup.POST("/:id/checklist", subtaskHandler.AddTaskChecklistItem)
+		taskGroup.PUT("/:id/checklist/order", subtaskHandler.ReorderTaskChecklist)
+		taskGroup.PATCH("/:id/checklist/:itemId", subtaskHandler.UpdateTaskChecklistItem)
+		taskGroup.DELETE("/:id/checklist/:itemId", subtaskHandler.DeleteTaskChecklistItem)
+		taskGroup.POST("/:id/checklist/:itemId/convert", subtaskHandler.ConvertTaskChecklistItem)
+
+		// Dependencies - viewers may list them; linking needs the edit access to the blocked task
+		// and the view access to the blocker
+		taskGroup.GET("/:id/dependencies", dependencyHandler.GetTaskDependencies)
+		taskGroup.POST("/:id/dependencies", dependencyHandler.AddTaskDependency)
+		taskGroup.DELETE("/:id/dependencies/:dependencyId", dependencyHandler.RemoveTaskDependency)
 	}
 }
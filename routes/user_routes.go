@@ -3,6 +3,8 @@ package routes
 import (
 	"project-x/handlers"
 	"project-x/middleware"
+	"project-x/models"
+	"project-x/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,22 +12,65 @@ import (
 
 func SetupUserRoutes(r *gin.Engine, db *gorm.DB) {
 	userHandler := handlers.NewUserHandler(db)
+	tokenHandler := handlers.NewPersonalAccessTokenHandler(db)
+	invitationHandler := handlers.NewInvitationHandler(db)
+	teamHandler := handlers.NewTeamHandler(db)
+	profileHandler := handlers.NewProfileHandler(db)
+
+	// Avatars in local storage are served from disk
+	if storage, ok := services.GetAvatarStorage().(*services.LocalStorage); ok {
+		r.Static(storage.BaseURL, storage.Dir)
+	}
 
 	userGroup := r.Group("/users")
 	userGroup.Use(middleware.AuthMiddleware(db))
 	{
-		// Admin only routes
-		userGroup.POST("", middleware.RequireAdmin(), userHandler.CreateUser)
-		userGroup.GET("", middleware.RequireAdmin(), userHandler.ListUsers)
-		userGroup.GET("/role/:role", middleware.RequireAdmin(), userHandler.GetUsersByRole)
-		userGroup.GET("/department/:department", middleware.RequireAdmin(), userHandler.GetUsersByDepartment)
-		userGroup.PATCH("/:id/role", middleware.RequireAdmin(), userHandler.UpdateUserRole)
-		userGroup.PATCH("/:id/department", middleware.RequireAdmin(), userHandler.UpdateUserDepartment)
-		userGroup.DELETE("/:id", middleware.RequireAdmin(), userHandler.DeleteUser)
-
-		// Routes accessible by admin or the user themselves
-		userGroup.GET("/:id", middleware.RequireSelfOrAdmin(), userHandler.GetUser)
-		userGroup.GET("/:id/stats", middleware.RequireSelfOrAdmin(), userHandler.GetUserStats)
-		userGroup.PATCH("/:id/password", middleware.RequireSelfOrAdmin(), userHandler.UpdateUserPassword)
+		// The current user's own profile
+		userGroup.GET("/me", profileHandler.GetProfile)
+		userGroup.PATCH("/me", profileHandler.UpdateProfile)
+		userGroup.PUT("/me/avatar", profileHandler.UploadAvatar)
+		userGroup.DELETE("/me/avatar", profileHandler.DeleteAvatar)
+
+		// User management routes
+		userGroup.POST("", middleware.RequirePermission(models.PermissionUserManage), userHandler.CreateUser)
+		userGroup.GET("", middleware.RequirePermission(models.PermissionUserManage), userHandler.ListUsers)
+		userGroup.GET("/role/:role", middleware.RequirePermission(models.PermissionUserManage), userHandler.GetUsersByRole)
+		userGroup.GET("/department/:department", middleware.RequirePermission(models.PermissionUserManage), userHandler.GetUsersByDepartment)
+		userGroup.PATCH("/:id/role", middleware.RequirePermission(models.PermissionUserManage), userHandler.UpdateUserRole)
+		userGroup.PATCH("/:id/department", middleware.RequirePermission(models.PermissionUserManage), userHandler.UpdateUserDepartment)
+		userGroup.POST("/:id/unlock", middleware.RequirePermission(models.PermissionUserManage), userHandler.UnlockUser)
+		userGroup.POST("/:id/impersonate", middleware.RequirePermission(models.PermissionUserImpersonate), userHandler.ImpersonateUser)
+
+		// Offboarding - users are deactivated, never deleted, so their history stays
+		userGroup.GET("/:id/offboarding", middleware.RequirePermission(models.PermissionUserManage), userHandler.GetOffboardingPlan)
+		userGroup.DELETE("/:id", middleware.RequirePermission(models.PermissionUserManage), userHandler.DeactivateUser)
+		userGroup.POST("/:id/reactivate", middleware.RequirePermission(models.PermissionUserManage), userHandler.ReactivateUser)
+
+		// Reporting lines - users see their own team and the teams below them
+		userGroup.PATCH("/:id/manager", middleware.RequirePermission(models.PermissionUserManage), teamHandler.UpdateUserManager)
+		userGroup.GET("/:id/reports", teamHandler.GetReports)
+		userGroup.GET("/:id/reports/tasks", teamHandler.GetTeamTasks)
+		userGroup.GET("/:id/reports/workload", teamHandler.GetTeamWorkload)
+
+		// Cross-department access grants
+		userGroup.GET("/:id/department-access", middleware.RequirePermission(models.PermissionUserManage), userHandler.ListDepartmentAccess)
+		userGroup.POST("/:id/department-access", middleware.RequirePermission(models.PermissionUserManage), userHandler.GrantDepartmentAccess)
+		userGroup.DELETE("/:id/department-access/:grantId", middleware.RequirePermission(models.PermissionUserManage), userHandler.RevokeDepartmentAccess)
+
+		// Invitations
+		userGroup.POST("/invitations", middleware.RequirePermission(models.PermissionUserManage), invitationHandler.CreateInvitation)
+		userGroup.GET("/invitations", middleware.RequirePermission(models.PermissionUserManage), invitationHandler.ListInvitations)
+		userGroup.POST("/invitations/:inviteId/resend", middleware.RequirePermission(models.PermissionUserManage), invitationHandler.ResendInvitation)
+		userGroup.DELETE("/invitations/:inviteId", middleware.RequirePermission(models.PermissionUserManage), invitationHandler.RevokeInvitation)
+
+		// Routes accessible by user managers or the user themselves
+		userGroup.GET("/:id", middleware.RequireSelfOrPermission(models.PermissionUserManage), userHandler.GetUser)
+		userGroup.GET("/:id/stats", middleware.RequireSelfOrPermission(models.PermissionUserManage), userHandler.GetUserStats)
+		userGroup.PATCH("/:id/password", middleware.RequireSelfOrPermission(models.PermissionUserManage), userHandler.UpdateUserPassword)
+
+		// Personal access tokens - only the user themselves may create one, user managers may list and revoke
+		userGroup.GET("/:id/tokens", middleware.RequireSelfOrPermission(models.PermissionUserManage), tokenHandler.ListTokens)
+		userGroup.POST("/:id/tokens", middleware.RequireSelf(), tokenHandler.CreateToken)
+		userGroup.DELETE("/:id/tokens/:tokenId", middleware.RequireSelfOrPermission(models.PermissionUserManage), tokenHandler.RevokeToken)
 	}
 }
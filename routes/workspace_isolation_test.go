@@ -0,0 +1,181 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"project-x/models"
+	"project-x/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the Postgres database named by TEST_DATABASE_URL, skipping the test when
+// it is unset. The test runs in a transaction that is rolled back when it finishes.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := services.RegisterWorkspaceScope(db); err != nil {
+		t.Fatalf("register workspace scope: %v", err)
+	}
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	if err := services.MigrateWorkspaces(db); err != nil {
+		t.Fatalf("migrate workspaces: %v", err)
+	}
+	if err := services.NewPermissionService(db).SyncPermissions(); err != nil {
+		t.Fatalf("sync permissions: %v", err)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() {
+		tx.Rollback()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return tx
+}
+
+// testRouter serves every route against the database
+func testRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	SetupAuthRoutes(r, db)
+	SetupUserRoutes(r, db)
+	SetupRoleRoutes(r, db)
+	SetupDepartmentRoutes(r, db)
+	SetupWorkspaceRoutes(r, db)
+	SetupSCIMRoutes(r, db)
+	SetupTaskRoutes(r, db)
+	SetupProjectRoutes(r, db)
+	SetupCollaborativeTaskRoutes(r, db)
+	return r
+}
+
+// serve sends a JSON request and returns the recorded response
+func serve(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	db := testDB(t)
+	r := testRouter(db)
+
+	acme := models.Workspace{Name: "Acme", Slug: "acme-isolation"}
+	globex := models.Workspace{Name: "Globex", Slug: "globex-isolation"}
+	for _, workspace := range []*models.Workspace{&acme, &globex} {
+		if err := db.Create(workspace).Error; err != nil {
+			t.Fatalf("create workspace: %v", err)
+		}
+	}
+
+	// An admin of Acme, which is not the default workspace, holds every permission inside it
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Correct-Horse-42"), bcrypt.MinCost)
+	admin := models.User{WorkspaceID: acme.ID, Username: "acme-admin", Password: string(hash), Role: models.RoleAdmin}
+	owner := models.User{WorkspaceID: globex.ID, Username: "globex-owner", Password: string(hash), Role: models.RoleEmployee}
+	for _, user := range []*models.User{&admin, &owner} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	now := time.Now()
+	project := models.Project{WorkspaceID: globex.ID, Title: "Globex launch", Description: "-", CreatedBy: owner.ID, StartDate: now}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	if err := db.Create(&models.UserProject{UserID: owner.ID, ProjectID: project.ID, Role: models.ProjectRoleLead}).Error; err != nil {
+		t.Fatalf("add project member: %v", err)
+	}
+	task := models.Task{WorkspaceID: globex.ID, Title: "Globex task", Description: "-", UserID: owner.ID, ProjectID: &project.ID, AssignedAt: now}
+	collaborativeTask := models.CollaborativeTask{WorkspaceID: globex.ID, Title: "Globex collaboration", Description: "-", LeadUserID: owner.ID, ProjectID: &project.ID, AssignedAt: now}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if err := db.Create(&collaborativeTask).Error; err != nil {
+		t.Fatalf("create collaborative task: %v", err)
+	}
+
+	login := serve(r, http.MethodPost, "/auth/login", "", gin.H{"username": admin.Username, "password": "Correct-Horse-42"})
+	var tokens struct {
+		Token string `json:"token"`
+	}
+	if login.Code != http.StatusOK || json.Unmarshal(login.Body.Bytes(), &tokens) != nil || tokens.Token == "" {
+		t.Fatalf("login: %d %s", login.Code, login.Body.String())
+	}
+
+	requests := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{http.MethodGet, fmt.Sprintf("/api/tasks/%d/history", task.ID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/tasks/%d/subtasks", task.ID), nil},
+		{http.MethodPatch, fmt.Sprintf("/api/tasks/%d", task.ID), gin.H{"title": "Taken over"}},
+		{http.MethodPatch, fmt.Sprintf("/api/tasks/%d/status", task.ID), gin.H{"status": models.TaskStatusInProgress}},
+		{http.MethodDelete, fmt.Sprintf("/api/tasks/%d", task.ID), nil},
+		{http.MethodDelete, fmt.Sprintf("/api/tasks/%d/force", task.ID), nil},
+
+		{http.MethodGet, fmt.Sprintf("/api/projects/%d", project.ID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/projects/%d/statistics", project.ID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/projects/%d/members", project.ID), nil},
+		{http.MethodPatch, fmt.Sprintf("/api/projects/%d/status", project.ID), gin.H{"status": models.ProjectStatusCancelled}},
+		{http.MethodDelete, fmt.Sprintf("/api/projects/%d", project.ID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/tasks/project/%d", project.ID), nil},
+
+		{http.MethodGet, fmt.Sprintf("/api/collaborative-tasks/%d", collaborativeTask.ID), nil},
+		{http.MethodGet, fmt.Sprintf("/api/collaborative-tasks/%d/statistics", collaborativeTask.ID), nil},
+		{http.MethodPatch, fmt.Sprintf("/api/collaborative-tasks/%d", collaborativeTask.ID), gin.H{"title": "Taken over"}},
+		{http.MethodPatch, fmt.Sprintf("/api/tasks/collaborative/%d/status", collaborativeTask.ID), gin.H{"status": models.TaskStatusInProgress}},
+		{http.MethodDelete, fmt.Sprintf("/api/tasks/collaborative/%d", collaborativeTask.ID), nil},
+	}
+	for _, req := range requests {
+		w := serve(r, req.method, req.path, tokens.Token, req.body)
+		if w.Code != http.StatusNotFound && w.Code != http.StatusForbidden {
+			t.Errorf("%s %s from another workspace: got %d %s, want 404 or 403", req.method, req.path, w.Code, w.Body.String())
+		}
+	}
+
+	// Nothing in Globex changed
+	var taskAfter models.Task
+	if err := db.First(&taskAfter, task.ID).Error; err != nil || taskAfter.Title != task.Title || taskAfter.Status != models.TaskStatusPending {
+		t.Errorf("task after requests: %+v, %v", taskAfter, err)
+	}
+	var projectAfter models.Project
+	if err := db.First(&projectAfter, project.ID).Error; err != nil || projectAfter.Status != models.ProjectStatusActive {
+		t.Errorf("project after requests: %+v, %v", projectAfter, err)
+	}
+	var collaborativeTaskAfter models.CollaborativeTask
+	if err := db.First(&collaborativeTaskAfter, collaborativeTask.ID).Error; err != nil || collaborativeTaskAfter.Title != collaborativeTask.Title {
+		t.Errorf("collaborative task after requests: %+v, %v", collaborativeTaskAfter, err)
+	}
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"project-x/handlers"
+	"project-x/middleware"
+	"project-x/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func SetupWorkspaceRoutes(r *gin.Engine, db *gorm.DB) {
+	workspaceHandler := handlers.NewWorkspaceHandler(db)
+	scimTokenHandler := handlers.NewSCIMTokenHandler(db)
+
+	workspaceGroup := r.Group("/workspaces")
+	workspaceGroup.Use(middleware.AuthMiddleware(db))
+	{
+		// The workspace the request acts inside (all authenticated users)
+		workspaceGroup.GET("/current", workspaceHandler.GetCurrentWorkspace)
+
+		// Workspace management
+		workspaceGroup.GET("", middleware.RequirePermission(models.PermissionWorkspaceManage), workspaceHandler.ListWorkspaces)
+		workspaceGroup.POST("", middleware.RequirePermission(models.PermissionWorkspaceManage), workspaceHandler.CreateWorkspace)
+
+		// SCIM provisioning tokens of the current workspace
+		workspaceGroup.GET("/current/scim-tokens", middleware.RequirePermission(models.PermissionSCIMManage), scimTokenHandler.ListTokens)
+		workspaceGroup.POST("/current/scim-tokens", middleware.RequirePermission(models.PermissionSCIMManage), scimTokenHandler.CreateToken)
+		workspaceGroup.DELETE("/current/scim-tokens/:tokenId", middleware.RequirePermission(models.PermissionSCIMManage), scimTokenHandler.RevokeToken)
+	}
+}
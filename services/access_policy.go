@@ -0,0 +1,299 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrAccessDenied is returned when the access policy forbids a write on an object
+var ErrAccessDenied = errors.New("access denied")
+
+// TaskAction is a write the access policy decides on for tasks and collaborative tasks
+type TaskAction string
+
+const (
+	TaskActionUpdateStatus       TaskAction = "update_status"
+	TaskActionEdit               TaskAction = "edit"
+	TaskActionViewHistory        TaskAction = "view_history"
+	TaskActionView               TaskAction = "view"
+	TaskActionAssign             TaskAction = "assign"
+	TaskActionReassign           TaskAction = "reassign"
+	TaskActionDelete             TaskAction = "delete"
+	TaskActionForceDelete        TaskAction = "force_delete"
+	TaskActionUpdateProgress     TaskAction = "update_progress"
+	TaskActionManageParticipants TaskAction = "manage_participants"
+)
+
+// Collaborative task participant roles allowed to report progress
+var progressParticipantRoles = map[string]bool{"lead": true, "contributor": true}
+
+// Actor is what the access policy knows about the user making a request
+type Actor struct {
+	UserID       uint
+	Permissions  models.PermissionSet
+	Departments  DepartmentScope
+	ProjectRoles map[uint]string // Project ID -> the actor's role in that project
+	Reports      map[uint]bool   // Everyone below the actor in the reporting line
+}
+
+// NewActor loads the permissions, department scope and project roles of a user
+func NewActor(db *gorm.DB, user *models.User) (*Actor, error) {
+	permissions, err := NewPermissionService(db).GetRolePermissions(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	departments, err := NewDepartmentAccessService(db).ScopeFor(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var memberships []models.UserProject
+	if err := db.Where("user_id = ?", user.ID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	projectRoles := make(map[uint]string, len(memberships))
+	for _, membership := range memberships {
+		projectRoles[membership.ProjectID] = membership.Role
+	}
+
+	reportIDs, err := NewTeamService(db).ReportIDs(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	reports := make(map[uint]bool, len(reportIDs))
+	for _, id := range reportIDs {
+		reports[id] = true
+	}
+
+	return &Actor{
+		UserID:       user.ID,
+		Permissions:  permissions,
+		Departments:  departments,
+		ProjectRoles: projectRoles,
+		Reports:      reports,
+	}, nil
+}
+
+// LoadActor loads a user by id and what the access policy knows about them
+func LoadActor(db *gorm.DB, userID uint) (*Actor, error) {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return NewActor(db, &user)
+}
+
+// manages reports whether a user is below the actor in the reporting line
+func (a *Actor) manages(userID uint) bool {
+	return a.Reports[userID]
+}
+
+// grantsInProject reports whether the actor's role in a project grants a permission
+func (a *Actor) grantsInProject(projectID *uint, permission string) bool {
+	if projectID == nil {
+		return false
+	}
+	projectRole, isMember := a.ProjectRoles[*projectID]
+	return isMember && ProjectRoleHasPermission(projectRole, permission)
+}
+
+// grantsForDepartment reports whether the actor's global role grants a permission
+// over work owned by someone in the department
+func (a *Actor) grantsForDepartment(permission string, department *uint) bool {
+	return a.Permissions.Has(permission) && a.Departments.Allows(department)
+}
+
+// AuthorizeAssignment checks giving new work to a user: anyone below the actor in the
+// reporting line, or anyone in the actor's departments when they hold task.assign
+func AuthorizeAssignment(actor *Actor, target *models.User) error {
+	if target.ID == actor.UserID || actor.manages(target.ID) ||
+		actor.grantsForDepartment(models.PermissionTaskAssign, target.DepartmentID) {
+		return nil
+	}
+	if actor.Permissions.Has(models.PermissionTaskAssign) {
+		return fmt.Errorf("target user is %w", ErrOutsideDepartment)
+	}
+	return fmt.Errorf("%w: you can only assign work to people who report to you", ErrAccessDenied)
+}
+
+// AuthorizeTask checks a write on a task. The task's User must be loaded.
+// Managers in the owner's reporting line may update, assign and reassign.
+//
+//	update_status: owner, owner's manager, project lead/manager, or task.update_any in the owner's department
+//	edit:          as update_status
+//	view_history:  as edit, plus project members and task.view_department in the owner's department
+//	view:          as view_history
+//	assign:        owner's manager, project lead/manager, or task.assign in the owner's department
+//	reassign:      owner's manager, project lead/manager, or task.reassign in the owner's department
+//	delete:        owner with task.delete
+//	force_delete:  task.reassign in the owner's department
+func AuthorizeTask(actor *Actor, task *models.Task, action TaskAction) error {
+	isOwner := task.UserID == actor.UserID
+	isManager := actor.manages(task.UserID)
+	department := task.User.DepartmentID
+
+	var allowed bool
+	switch action {
+	case TaskActionUpdateStatus, TaskActionEdit:
+		allowed = isOwner || isManager ||
+			actor.grantsInProject(task.ProjectID, models.PermissionTaskAssign) ||
+			actor.grantsForDepartment(models.PermissionTaskUpdateAny, department)
+	case TaskActionViewHistory, TaskActionView:
+		allowed = isOwner || isManager ||
+			actor.grantsInProject(task.ProjectID, models.PermissionProjectView) ||
+			actor.grantsForDepartment(models.PermissionTaskUpdateAny, department) ||
+			actor.grantsForDepartment(models.PermissionTaskViewDepartment, department)
+	case TaskActionAssign:
+		allowed = isManager ||
+			actor.grantsInProject(task.ProjectID, models.PermissionTaskAssign) ||
+			actor.grantsForDepartment(models.PermissionTaskAssign, department)
+	case TaskActionReassign:
+		allowed = isManager ||
+			actor.grantsInProject(task.ProjectID, models.PermissionTaskReassign) ||
+			actor.grantsForDepartment(models.PermissionTaskReassign, department)
+	case TaskActionDelete:
+		allowed = isOwner && actor.Permissions.Has(models.PermissionTaskDelete)
+	case TaskActionForceDelete:
+		allowed = actor.grantsForDepartment(models.PermissionTaskReassign, department)
+	}
+
+	if !allowed {
+		return fmt.Errorf("%w: you cannot %s this task", ErrAccessDenied, actionDescription(action))
+	}
+	return nil
+}
+
+// AuthorizeCollaborativeTask checks a write on a collaborative task. The task's LeadUser
+// and Participants must be loaded.
+//
+//	update_status:       lead, lead's manager, project lead/manager, or task.update_any in the lead's department
+//	edit:                as update_status
+//	view_history:        as edit, plus participants, project members and task.view_department in the lead's department
+//	view:                as view_history
+//	update_progress:     as update_status, plus lead and contributor participants
+//	manage_participants: lead, lead's manager, project lead/manager, or collaborative_task.manage_participants in the lead's department
+//	delete:              lead with task.delete, or task.reassign in the lead's department
+func AuthorizeCollaborativeTask(actor *Actor, task *models.CollaborativeTask, action TaskAction) error {
+	isLead := task.LeadUserID == actor.UserID
+	isManager := actor.manages(task.LeadUserID)
+	department := task.LeadUser.DepartmentID
+
+	participantRole := ""
+	for _, participant := range task.Participants {
+		if participant.UserID == actor.UserID {
+			participantRole = participant.Role
+			break
+		}
+	}
+
+	var allowed bool
+	switch action {
+	case TaskActionUpdateStatus, TaskActionEdit:
+		allowed = isLead || isManager ||
+			actor.grantsInProject(task.ProjectID, models.PermissionTaskAssign) ||
+			actor.grantsForDepartment(models.PermissionTaskUpdateAny, department)
+	case TaskActionViewHistory, TaskActionView:
+		allowed = isLead || isManager || participantRole != "" ||
+			actor.grantsInProject(task.ProjectID, models.PermissionProjectView) ||
+			actor.grantsForDepartment(models.PermissionTaskUpdateAny, department) ||
+			actor.grantsForDepartment(models.PermissionTaskViewDepartment, department)
+	case TaskActionUpdateProgress:
+		allowed = isLead || isManager || progressParticipantRoles[participantRole] ||
+			actor.grantsInProject(task.ProjectID, models.PermissionTaskAssign) ||
+			actor.grantsForDepartment(models.PermissionTaskUpdateAny, department)
+	case TaskActionManageParticipants:
+		allowed = isLead || isManager ||
+			actor.grantsInProject(task.ProjectID, models.PermissionProjectManageMembers) ||
+			actor.grantsForDepartment(models.PermissionCollaborativeParticipants, department)
+	case TaskActionDelete:
+		allowed = (isLead && actor.Permissions.Has(models.PermissionTaskDelete)) ||
+			actor.grantsForDepartment(models.PermissionTaskReassign, department)
+	}
+
+	if !allowed {
+		return fmt.Errorf("%w: you cannot %s this collaborative task", ErrAccessDenied, actionDescription(action))
+	}
+	return nil
+}
+
+// AuthorizeProject checks a project write: the permission must be granted globally
+// or by the actor's role in the project
+func AuthorizeProject(actor *Actor, projectID uint, permission string) error {
+	if actor.Permissions.Has(permission) || actor.grantsInProject(&projectID, permission) {
+		return nil
+	}
+	return fmt.Errorf("%w: missing %s for this project", ErrAccessDenied, permission)
+}
+
+// AuthorizeTaskProject checks moving a task into a project: members of the project may, as may
+// holders of task.assign, globally or through their project role
+func AuthorizeTaskProject(actor *Actor, projectID uint) error {
+	if _, isMember := actor.ProjectRoles[projectID]; isMember {
+		return nil
+	}
+	if actor.Permissions.Has(models.PermissionTaskAssign) {
+		return nil
+	}
+	return fmt.Errorf("%w: you are not a member of the target project", ErrAccessDenied)
+}
+
+// AuthorizeDepartmentGrant checks giving a user access to a department, or to every department
+// when departmentID is nil. Nobody can hand out access to departments outside their own scope, and
+// only holders of department.access_all can hand out every department.
+func AuthorizeDepartmentGrant(actor *Actor, departmentID *uint) error {
+	if departmentID == nil {
+		if !actor.Permissions.Has(models.PermissionDepartmentAccessAll) {
+			return fmt.Errorf("%w: granting every department requires %s", ErrAccessDenied, models.PermissionDepartmentAccessAll)
+		}
+		return nil
+	}
+	if !actor.Departments.Allows(departmentID) {
+		return fmt.Errorf("%w: you cannot grant a department outside your own", ErrAccessDenied)
+	}
+	return nil
+}
+
+// AuthorizeProjectMemberChange checks adding or removing a member with the given project role.
+// Members who manage the project through their project role cannot touch higher project roles.
+func AuthorizeProjectMemberChange(actor *Actor, projectID uint, memberRole string) error {
+	if actor.Permissions.Has(models.PermissionProjectManageMembers) {
+		return nil
+	}
+	if !actor.grantsInProject(&projectID, models.PermissionProjectManageMembers) {
+		return fmt.Errorf("%w: missing %s for this project", ErrAccessDenied, models.PermissionProjectManageMembers)
+	}
+	if !ProjectRoleOutranks(actor.ProjectRoles[projectID], memberRole) {
+		return fmt.Errorf("%w: you cannot manage members with a higher project role than your own", ErrAccessDenied)
+	}
+	return nil
+}
+
+// actionDescription turns an action into words for error messages
+func actionDescription(action TaskAction) string {
+	switch action {
+	case TaskActionUpdateStatus:
+		return "change the status of"
+	case TaskActionEdit:
+		return "edit"
+	case TaskActionViewHistory:
+		return "view the history of"
+	case TaskActionView:
+		return "view"
+	case TaskActionAssign:
+		return "assign"
+	case TaskActionReassign:
+		return "reassign"
+	case TaskActionDelete, TaskActionForceDelete:
+		return "delete"
+	case TaskActionUpdateProgress:
+		return "update the progress of"
+	case TaskActionManageParticipants:
+		return "manage the participants of"
+	}
+	return string(action)
+}
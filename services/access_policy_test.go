@@ -0,0 +1,432 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"testing"
+)
+
+const (
+	ownerID uint = iota + 1
+	otherEmployeeID
+	observerID
+	projectLeadID
+	projectMemberID
+	projectManagerID
+	headID
+	otherHeadID
+	managerID
+	otherManagerID
+	adminID
+)
+
+const (
+	testProjectID uint = 10
+
+	engineeringID uint = 1
+	salesID       uint = 2
+)
+
+// defaultPermissions returns the permissions a built-in role gets from the catalog
+func defaultPermissions(role models.Role) models.PermissionSet {
+	permissions := make(models.PermissionSet)
+	for _, entry := range permissionCatalog {
+		if role == models.RoleAdmin {
+			permissions[entry.Name] = true
+			continue
+		}
+		for _, defaultRole := range entry.DefaultRoles {
+			if defaultRole == role {
+				permissions[entry.Name] = true
+			}
+		}
+	}
+	return permissions
+}
+
+func newTestActor(userID uint, role models.Role, departmentID uint, projectRole string) *Actor {
+	actor := &Actor{
+		UserID:       userID,
+		Permissions:  defaultPermissions(role),
+		Departments:  DepartmentScope{DepartmentIDs: []uint{departmentID}},
+		ProjectRoles: map[uint]string{},
+	}
+	if role == models.RoleAdmin {
+		actor.Departments = DepartmentScope{All: true}
+	}
+	if projectRole != "" {
+		actor.ProjectRoles[testProjectID] = projectRole
+	}
+	return actor
+}
+
+var testActors = map[string]*Actor{
+	"owner":           newTestActor(ownerID, models.RoleEmployee, engineeringID, models.ProjectRoleMember),
+	"other employee":  newTestActor(otherEmployeeID, models.RoleEmployee, engineeringID, ""),
+	"observer":        newTestActor(observerID, models.RoleEmployee, engineeringID, ""),
+	"project lead":    newTestActor(projectLeadID, models.RoleEmployee, salesID, models.ProjectRoleLead),
+	"project member":  newTestActor(projectMemberID, models.RoleEmployee, engineeringID, models.ProjectRoleMember),
+	"project manager": newTestActor(projectManagerID, models.RoleEmployee, salesID, models.ProjectRoleManager),
+	"head":            newTestActor(headID, models.RoleHead, engineeringID, ""),
+	"other head":      newTestActor(otherHeadID, models.RoleHead, salesID, ""),
+	"manager":         newTestActor(managerID, models.RoleManager, engineeringID, ""),
+	"other manager":   newTestActor(otherManagerID, models.RoleManager, salesID, ""),
+	"admin":           newTestActor(adminID, models.RoleAdmin, 0, ""),
+}
+
+func projectTask() *models.Task {
+	projectID := testProjectID
+	return &models.Task{
+		UserID:    ownerID,
+		User:      models.User{DepartmentID: uintPtr(engineeringID)},
+		ProjectID: &projectID,
+	}
+}
+
+func TestAuthorizeTask(t *testing.T) {
+	allowed := map[TaskAction][]string{
+		TaskActionUpdateStatus: {"owner", "project lead", "project manager", "head", "manager", "admin"},
+		TaskActionEdit:         {"owner", "project lead", "project manager", "head", "manager", "admin"},
+		TaskActionViewHistory:  {"owner", "project lead", "project member", "project manager", "head", "manager", "admin"},
+		TaskActionView:         {"owner", "project lead", "project member", "project manager", "head", "manager", "admin"},
+		TaskActionAssign:       {"project lead", "project manager", "manager", "admin"},
+		TaskActionReassign:     {"project lead", "project manager", "admin"},
+		TaskActionDelete:       {},
+		TaskActionForceDelete:  {"admin"},
+	}
+
+	for action, allowedActors := range allowed {
+		for name, actor := range testActors {
+			want := contains(allowedActors, name)
+			err := AuthorizeTask(actor, projectTask(), action)
+			if (err == nil) != want {
+				t.Errorf("%s %s: got err %v, want allowed=%v", name, action, err, want)
+			}
+			if err != nil && !errors.Is(err, ErrAccessDenied) {
+				t.Errorf("%s %s: error %v does not wrap ErrAccessDenied", name, action, err)
+			}
+		}
+	}
+}
+
+func TestAuthorizeTaskDeleteByOwnerWithPermission(t *testing.T) {
+	task := projectTask()
+	task.UserID = headID
+
+	if err := AuthorizeTask(testActors["head"], task, TaskActionDelete); err != nil {
+		t.Errorf("head deleting own task: %v", err)
+	}
+	if err := AuthorizeTask(testActors["manager"], task, TaskActionDelete); err == nil {
+		t.Error("manager deleted a task they do not own")
+	}
+}
+
+func TestAuthorizeTaskOutsideProject(t *testing.T) {
+	task := projectTask()
+	task.ProjectID = nil
+
+	for _, name := range []string{"project lead", "project manager"} {
+		if err := AuthorizeTask(testActors[name], task, TaskActionReassign); err == nil {
+			t.Errorf("%s reassigned a task outside their project", name)
+		}
+	}
+	if err := AuthorizeTask(testActors["owner"], task, TaskActionUpdateStatus); err != nil {
+		t.Errorf("owner updating task outside a project: %v", err)
+	}
+}
+
+func TestAuthorizeTaskDepartmentGrants(t *testing.T) {
+	actor := newTestActor(otherManagerID, models.RoleManager, salesID, "")
+	actor.Departments = DepartmentScope{DepartmentIDs: []uint{salesID, engineeringID}}
+
+	if err := AuthorizeTask(actor, projectTask(), TaskActionAssign); err != nil {
+		t.Errorf("manager with an engineering grant: %v", err)
+	}
+}
+
+func TestAuthorizeCollaborativeTask(t *testing.T) {
+	projectID := testProjectID
+	task := &models.CollaborativeTask{
+		LeadUserID: ownerID,
+		LeadUser:   models.User{DepartmentID: uintPtr(engineeringID)},
+		ProjectID:  &projectID,
+		Participants: []models.CollaborativeTaskParticipant{
+			{UserID: ownerID, Role: "lead"},
+			{UserID: otherEmployeeID, Role: "contributor"},
+			{UserID: observerID, Role: "observer"},
+		},
+	}
+
+	allowed := map[TaskAction][]string{
+		TaskActionUpdateStatus:       {"owner", "project lead", "project manager", "head", "manager", "admin"},
+		TaskActionUpdateProgress:     {"owner", "other employee", "project lead", "project manager", "head", "manager", "admin"},
+		TaskActionEdit:               {"owner", "project lead", "project manager", "head", "manager", "admin"},
+		TaskActionViewHistory:        {"owner", "other employee", "observer", "project lead", "project member", "project manager", "head", "manager", "admin"},
+		TaskActionView:               {"owner", "other employee", "observer", "project lead", "project member", "project manager", "head", "manager", "admin"},
+		TaskActionManageParticipants: {"owner", "project lead", "project manager", "head", "manager", "admin"},
+		TaskActionDelete:             {"admin"},
+	}
+
+	for action, allowedActors := range allowed {
+		for name, actor := range testActors {
+			want := contains(allowedActors, name)
+			err := AuthorizeCollaborativeTask(actor, task, action)
+			if (err == nil) != want {
+				t.Errorf("%s %s: got err %v, want allowed=%v", name, action, err, want)
+			}
+		}
+	}
+}
+
+func TestAuthorizeProject(t *testing.T) {
+	allowed := map[string][]string{
+		models.PermissionProjectUpdateStatus:  {"project manager", "manager", "other manager", "admin"},
+		models.PermissionProjectManageMembers: {"project lead", "project manager", "manager", "other manager", "admin"},
+		models.PermissionProjectDelete:        {"admin"},
+	}
+
+	for permission, allowedActors := range allowed {
+		for name, actor := range testActors {
+			want := contains(allowedActors, name)
+			err := AuthorizeProject(actor, testProjectID, permission)
+			if (err == nil) != want {
+				t.Errorf("%s %s: got err %v, want allowed=%v", name, permission, err, want)
+			}
+		}
+	}
+}
+
+func TestAuthorizeTaskProject(t *testing.T) {
+	allowedActors := []string{"owner", "project lead", "project member", "project manager", "manager", "other manager", "admin"}
+	for name, actor := range testActors {
+		want := contains(allowedActors, name)
+		if err := AuthorizeTaskProject(actor, testProjectID); (err == nil) != want {
+			t.Errorf("%s: got err %v, want allowed=%v", name, err, want)
+		}
+	}
+}
+
+func TestAuthorizeProjectMemberChange(t *testing.T) {
+	tests := []struct {
+		actor      string
+		memberRole string
+		want       bool
+	}{
+		{"project lead", models.ProjectRoleMember, true},
+		{"project lead", models.ProjectRoleLead, true},
+		{"project lead", models.ProjectRoleManager, false},
+		{"project manager", models.ProjectRoleManager, true},
+		{"project member", models.ProjectRoleMember, false},
+		{"head", models.ProjectRoleMember, false},
+		{"manager", models.ProjectRoleManager, true},
+		{"admin", models.ProjectRoleManager, true},
+	}
+
+	for _, tt := range tests {
+		err := AuthorizeProjectMemberChange(testActors[tt.actor], testProjectID, tt.memberRole)
+		if (err == nil) != tt.want {
+			t.Errorf("%s changing a %s: got err %v, want allowed=%v", tt.actor, tt.memberRole, err, tt.want)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func uintPtr(value uint) *uint {
+	return &value
+}
+
+func TestManagerReportingLine(t *testing.T) {
+	// otherManager sits in sales but ownerID reports to them
+	actor := newTestActor(otherManagerID, models.RoleManager, salesID, "")
+	actor.Reports = map[uint]bool{ownerID: true}
+
+	for _, action := range []TaskAction{TaskActionUpdateStatus, TaskActionAssign, TaskActionReassign} {
+		if err := AuthorizeTask(actor, projectTask(), action); err != nil {
+			t.Errorf("manager of the owner %s: %v", action, err)
+		}
+	}
+	if err := AuthorizeTask(actor, projectTask(), TaskActionForceDelete); err == nil {
+		t.Error("manager of the owner force-deleted without task.reassign in the department")
+	}
+}
+
+func TestAuthorizeAssignment(t *testing.T) {
+	employee := newTestActor(otherEmployeeID, models.RoleEmployee, engineeringID, "")
+	employee.Reports = map[uint]bool{observerID: true}
+	owner := &models.User{DepartmentID: uintPtr(engineeringID)}
+	owner.ID = ownerID
+	report := &models.User{DepartmentID: uintPtr(salesID)}
+	report.ID = observerID
+
+	tests := []struct {
+		name   string
+		actor  *Actor
+		target *models.User
+		want   error
+	}{
+		{"self", testActors["owner"], owner, nil},
+		{"employee to a report in another department", employee, report, nil},
+		{"employee to a peer", employee, owner, ErrAccessDenied},
+		{"manager within department", testActors["manager"], owner, nil},
+		{"manager outside department", testActors["manager"], report, ErrOutsideDepartment},
+		{"admin anywhere", testActors["admin"], report, nil},
+	}
+
+	for _, tt := range tests {
+		err := AuthorizeAssignment(tt.actor, tt.target)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: got err %v, want allowed", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: got err %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeRoleGrant(t *testing.T) {
+	userManager := newTestActor(managerID, models.RoleManager, engineeringID, "")
+	userManager.Permissions[models.PermissionUserManage] = true
+	permissionService := NewPermissionService(nil) // The admin role's permissions come from the catalog
+
+	if err := permissionService.AuthorizeRoleGrant(userManager, models.RoleAdmin); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("granting admin without its permissions: got %v, want ErrAccessDenied", err)
+	}
+	if err := permissionService.AuthorizeRoleGrant(testActors["admin"], models.RoleAdmin); err != nil {
+		t.Errorf("admin granting admin: got %v", err)
+	}
+
+	admin := &models.User{Username: "root", Role: models.RoleAdmin}
+	if err := permissionService.AuthorizeUserAdministration(userManager, admin); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("resetting an admin: got %v, want ErrAccessDenied", err)
+	}
+
+	if err := authorizePermissionGrant(userManager, []string{models.PermissionReportView, models.PermissionRoleManage}); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("putting role.manage into a role: got %v, want ErrAccessDenied", err)
+	}
+	if err := authorizePermissionGrant(userManager, []string{models.PermissionReportView, models.PermissionUserManage}); err != nil {
+		t.Errorf("granting held permissions: got %v", err)
+	}
+}
+
+func TestAuthorizeDepartmentGrant(t *testing.T) {
+	userManager := newTestActor(managerID, models.RoleManager, engineeringID, "")
+	userManager.Permissions[models.PermissionUserManage] = true
+
+	tests := []struct {
+		name       string
+		actor      *Actor
+		department *uint
+		want       error
+	}{
+		{"own department", userManager, uintPtr(engineeringID), nil},
+		{"department outside the scope", userManager, uintPtr(salesID), ErrAccessDenied},
+		{"every department without department.access_all", userManager, nil, ErrAccessDenied},
+		{"every department as admin", testActors["admin"], nil, nil},
+		{"any department as admin", testActors["admin"], uintPtr(salesID), nil},
+	}
+
+	for _, tt := range tests {
+		err := AuthorizeDepartmentGrant(tt.actor, tt.department)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s: got err %v, want allowed", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: got err %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeUserAdministration(t *testing.T) {
+	userManager := newTestActor(managerID, models.RoleManager, engineeringID, "")
+	userManager.Permissions[models.PermissionUserManage] = true
+	admin := &models.User{Username: "root", Role: models.RoleAdmin}
+	permissionService := NewPermissionService(nil) // The admin role's permissions come from the catalog
+
+	tests := []struct {
+		name  string
+		actor *Actor
+		want  error
+	}{
+		{"user manager", userManager, ErrAccessDenied},
+		{"head", testActors["head"], ErrAccessDenied},
+		{"admin", testActors["admin"], nil},
+	}
+
+	for _, tt := range tests {
+		err := permissionService.AuthorizeUserAdministration(tt.actor, admin)
+		if tt.want == nil && err != nil {
+			t.Errorf("%s administering an admin: got err %v, want allowed", tt.name, err)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s administering an admin: got err %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestUserAdministrationRefusesOutrankedUsers(t *testing.T) {
+	db := testDB(t)
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	department := models.Department{Name: "Outranked test department"}
+	if err := db.Create(&department).Error; err != nil {
+		t.Fatalf("create department: %v", err)
+	}
+	admin := models.User{WorkspaceID: workspaceID, Username: "outranked-admin", Password: "x", Role: models.RoleAdmin}
+	manager := models.User{WorkspaceID: workspaceID, Username: "outranked-manager", Password: "x", Role: models.RoleManager}
+	for _, user := range []*models.User{&admin, &manager} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+
+	userManager := newTestActor(manager.ID, models.RoleManager, department.ID, "")
+	userManager.Permissions[models.PermissionUserManage] = true
+
+	attempts := map[string]func() error{
+		"move department": func() error {
+			_, err := NewUserService(db).UpdateUserDepartment(userManager, admin.ID, &department.ID)
+			return err
+		},
+		"unlock": func() error {
+			_, err := NewUserService(db).UnlockUser(userManager, admin.ID)
+			return err
+		},
+		"become their manager": func() error {
+			_, err := NewTeamService(db).SetManager(userManager, admin.ID, &manager.ID)
+			return err
+		},
+		"deactivate": func() error {
+			_, err := NewOffboardingService(db).DeactivateUser(userManager, admin.ID, nil)
+			return err
+		},
+		"reactivate": func() error {
+			if err := db.Model(&admin).Update("active", false).Error; err != nil {
+				return err
+			}
+			defer db.Model(&admin).Update("active", true)
+			_, err := NewOffboardingService(db).ReactivateUser(userManager, admin.ID)
+			return err
+		},
+	}
+	for name, attempt := range attempts {
+		if err := attempt(); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("%s: got %v, want ErrAccessDenied", name, err)
+		}
+	}
+
+	var after models.User
+	if err := db.First(&after, admin.ID).Error; err != nil || after.DepartmentID != nil || after.ManagerID != nil || !after.Active {
+		t.Errorf("admin after attempts: %+v, %v", after, err)
+	}
+}
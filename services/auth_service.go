@@ -2,13 +2,15 @@ package services
 
 import (
 	"errors"
-	"os"
+	"project-x/config"
 	"project-x/models"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AuthService struct {
@@ -19,38 +21,311 @@ func NewAuthService(db *gorm.DB) *AuthService {
 	return &AuthService{DB: db}
 }
 
-func (s *AuthService) Login(username, password string) (string, error) {
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// LoginResult holds either a token pair or, when 2FA is enabled, a challenge token
+type LoginResult struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+	*TokenPair
+}
+
+const twoFactorChallengePurpose = "2fa_challenge"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account is temporarily locked")
+	ErrAccountDeactivated = errors.New("account is deactivated")
+)
+
+// dummyPasswordHash is compared against when the user does not exist,
+// so that login takes the same time whether or not the username is known
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+func (s *AuthService) Login(username, password, userAgent, ipAddress string) (*LoginResult, error) {
 	// Check user credentials
 	var user models.User
 	if err := s.DB.Where("username = ?", username).First(&user).Error; err != nil {
-		return "", errors.New("user not found")
+		compareDummyPassword(password)
+		return nil, ErrInvalidCredentials
+	}
+
+	// Locked accounts get the wrong-password response and still pay the bcrypt cost, so
+	// neither the response nor its timing tells an unknown username from a locked account
+	if user.IsLocked() {
+		compareDummyPassword(password)
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid password")
+		if err := s.recordFailedLogin(&user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.completeFirstFactor(&user, userAgent, ipAddress)
+}
+
+// completeFirstFactor continues a login once the password or the identity provider has vouched
+// for the user. Deactivated accounts are refused, users with 2FA get a short-lived challenge and
+// everyone else gets a session.
+func (s *AuthService) completeFirstFactor(user *models.User, userAgent, ipAddress string) (*LoginResult, error) {
+	// Checked after the first factor so the response does not reveal which accounts were deactivated
+	if !user.Active {
+		return nil, ErrAccountDeactivated
+	}
+
+	if user.TwoFactorEnabled {
+		challengeToken, err := s.issueChallengeToken(user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{TwoFactorRequired: true, ChallengeToken: challengeToken}, nil
+	}
+
+	if err := s.resetFailedLogins(user); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(user, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{TokenPair: tokens}, nil
+}
+
+// VerifyTwoFactor completes a two-step login by exchanging a challenge token and code for a token pair
+func (s *AuthService) VerifyTwoFactor(challengeToken, code, userAgent, ipAddress string) (*TokenPair, error) {
+	keys, err := GetKeyRing()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &models.ChallengeClaims{}
+	token, err := keys.Parse(challengeToken, TokenTypeChallenge, claims)
+	if err != nil || !token.Valid || claims.Purpose != twoFactorChallengePurpose || claims.ID == "" {
+		return nil, errors.New("invalid or expired challenge token")
+	}
+
+	// A challenge is spent by its first attempt, right or wrong, so a captured token cannot be replayed
+	result := s.DB.Model(&models.User{}).
+		Where("id = ? AND two_factor_challenge = ?", claims.UserID, hashToken(claims.ID)).
+		Update("two_factor_challenge", "")
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, errors.New("invalid or expired challenge token")
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if !user.Active {
+		return nil, ErrAccountDeactivated
+	}
+
+	if user.IsLocked() {
+		return nil, ErrAccountLocked
+	}
+
+	if err := NewTwoFactorService(s.DB).VerifySecondFactor(&user, code); err != nil {
+		if err := s.recordFailedLogin(&user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.resetFailedLogins(&user); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(&user, userAgent, ipAddress)
+}
+
+// startSession creates a session for an authenticated user and issues its tokens
+func (s *AuthService) startSession(user *models.User, userAgent, ipAddress string) (*TokenPair, error) {
+	session, refreshToken, err := NewSessionService(s.DB).CreateSession(user.ID, userAgent, ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokenPair(user, session, refreshToken)
+}
+
+// recordFailedLogin counts a failed attempt and locks the account once the limit is reached.
+// Each consecutive lockout doubles the lockout window, up to the configured maximum. The
+// count is incremented in the database and the lock decided from the stored value, so
+// concurrent failures cannot overwrite each other's attempts.
+func (s *AuthService) recordFailedLogin(user *models.User) error {
+	cfg := config.Get()
+	now := time.Now()
+
+	err := s.DB.Model(user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}, {Name: "lockout_count"}}}).
+		Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("failed_login_attempts + 1"),
+			"last_failed_login_at":  now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	if user.FailedLoginAttempts < cfg.MaxLoginAttempts {
+		return nil
+	}
+
+	// Only one of several concurrent failures past the limit starts the lockout
+	return s.DB.Model(&models.User{}).
+		Where("id = ? AND failed_login_attempts >= ?", user.ID, cfg.MaxLoginAttempts).
+		Updates(map[string]interface{}{
+			"failed_login_attempts": 0,
+			"lockout_count":         gorm.Expr("lockout_count + 1"),
+			"locked_until":          now.Add(lockoutDuration(cfg, user.LockoutCount)),
+		}).Error
+}
+
+// lockoutDuration returns how long to lock an account that has been locked lockoutCount
+// times before
+func lockoutDuration(cfg *config.Config, lockoutCount int) time.Duration {
+	lockout := cfg.LockoutBaseDuration << lockoutCount
+	if lockout <= 0 || lockout > cfg.LockoutMaxDuration {
+		return cfg.LockoutMaxDuration
+	}
+	return lockout
+}
+
+// resetFailedLogins clears the failed-attempt counters after a successful login
+func (s *AuthService) resetFailedLogins(user *models.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return nil
+	}
+
+	return s.DB.Model(user).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"lockout_count":         0,
+		"locked_until":          nil,
+	}).Error
+}
+
+// compareDummyPassword runs a bcrypt comparison that always fails
+func compareDummyPassword(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+// Refresh rotates a refresh token and issues a new access token for the same session
+func (s *AuthService) Refresh(refreshToken string) (*TokenPair, error) {
+	session, newRefreshToken, err := NewSessionService(s.DB).RotateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
 	}
 
+	var user models.User
+	if err := s.DB.First(&user, session.UserID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.Active {
+		return nil, ErrAccountDeactivated
+	}
+
+	return issueTokenPair(&user, session, newRefreshToken)
+}
+
+// Logout revokes the given session
+func (s *AuthService) Logout(sessionID uint) error {
+	return NewSessionService(s.DB).RevokeSession(sessionID)
+}
+
+// LogoutAll revokes every session of a user
+func (s *AuthService) LogoutAll(userID uint) error {
+	return NewSessionService(s.DB).RevokeAllUserSessions(userID)
+}
+
+func issueTokenPair(user *models.User, session *models.Session, refreshToken string) (*TokenPair, error) {
+	expiresAt := time.Now().Add(config.Get().AccessTokenTTL)
+
 	// Generate JWT token with proper claims
-	token, err := generateToken(&user)
+	token, err := generateToken(user, session.ID, expiresAt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// issueChallengeToken issues a short-lived token proving the password step succeeded. Only the
+// most recent challenge is accepted, and only once.
+func (s *AuthService) issueChallengeToken(user *models.User) (string, error) {
+	keys, err := GetKeyRing()
 	if err != nil {
 		return "", err
 	}
 
-	return token, nil
+	challengeID, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.DB.Model(user).Update("two_factor_challenge", hashToken(challengeID)).Error; err != nil {
+		return "", err
+	}
+
+	claims := &models.ChallengeClaims{
+		UserID:  user.ID,
+		Purpose: twoFactorChallengePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID,
+			Issuer:    keys.Issuer(),
+			Audience:  jwt.ClaimStrings{keys.Audience(TokenTypeChallenge)},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Get().TwoFactorChallengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return keys.Sign(TokenTypeChallenge, claims)
 }
 
-func generateToken(user *models.User) (string, error) {
+// generateToken issues an access token; impersonatorID is set only for admin "act as" tokens
+func generateToken(user *models.User, sessionID uint, expiresAt time.Time, impersonatorID *uint) (string, error) {
+	keys, err := GetKeyRing()
+	if err != nil {
+		return "", err
+	}
+
 	claims := &models.Claims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:         user.ID,
+		Role:           user.Role,
+		SessionID:      sessionID,
+		WorkspaceID:    user.WorkspaceID,
+		ImpersonatorID: impersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			Issuer:    keys.Issuer(),
+			Audience:  jwt.ClaimStrings{keys.Audience(TokenTypeAccess)},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return keys.Sign(TokenTypeAccess, claims)
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"project-x/config"
+	"project-x/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	cfg := &config.Config{LockoutBaseDuration: 5 * time.Minute, LockoutMaxDuration: time.Hour}
+	cases := []struct {
+		lockoutCount int
+		want         time.Duration
+	}{
+		{0, 5 * time.Minute},
+		{1, 10 * time.Minute},
+		{3, 40 * time.Minute},
+		{4, time.Hour},  // 80 minutes, capped
+		{70, time.Hour}, // Overflows, capped
+	}
+	for _, tc := range cases {
+		if got := lockoutDuration(cfg, tc.lockoutCount); got != tc.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", tc.lockoutCount, got, tc.want)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	db := testDB(t)
+	cfg := config.Get()
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("Correct-Horse-42"), bcrypt.MinCost)
+	user := models.User{WorkspaceID: workspaceID, Username: "lockout-user", Password: string(hash), Role: models.RoleEmployee}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	authService := NewAuthService(db)
+	for i := 0; i < cfg.MaxLoginAttempts; i++ {
+		if _, err := authService.Login(user.Username, "wrong-password", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failed attempt %d: got %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+
+	var locked models.User
+	db.First(&locked, user.ID)
+	if !locked.IsLocked() || locked.LockoutCount != 1 || locked.FailedLoginAttempts != 0 {
+		t.Fatalf("after %d failures: got locked until %v, lockout count %d, attempts %d",
+			cfg.MaxLoginAttempts, locked.LockedUntil, locked.LockoutCount, locked.FailedLoginAttempts)
+	}
+	if remaining := time.Until(*locked.LockedUntil); remaining > lockoutDuration(cfg, 0) || remaining < lockoutDuration(cfg, 0)-time.Minute {
+		t.Errorf("first lockout: %s left, want about %s", remaining, lockoutDuration(cfg, 0))
+	}
+
+	// A locked account answers the right password like a wrong one
+	if _, err := authService.Login(user.Username, "Correct-Horse-42", "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("right password while locked: got %v, want ErrInvalidCredentials", err)
+	}
+
+	// Once the lockout ends the right password works and clears the counters
+	db.Model(&locked).Update("locked_until", time.Now().Add(-time.Second))
+	if result, err := authService.Login(user.Username, "Correct-Horse-42", "test", "127.0.0.1"); err != nil || result.TokenPair == nil {
+		t.Fatalf("login after the lockout: got %+v, %v", result, err)
+	}
+	var unlocked models.User
+	db.First(&unlocked, user.ID)
+	if unlocked.LockedUntil != nil || unlocked.LockoutCount != 0 || unlocked.FailedLoginAttempts != 0 {
+		t.Errorf("after a successful login: got %+v", unlocked)
+	}
+}
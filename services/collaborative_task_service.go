@@ -32,17 +32,9 @@ func (s *CollaborativeTaskService) CreateCollaborativeTask(title, description st
 		}
 	}
 
-	// Validate priority
-	validPriorities := []string{"high", "medium", "low"}
-	validPriority := false
-	for _, p := range validPriorities {
-		if p == priority {
-			validPriority = true
-			break
-		}
-	}
-	if !validPriority {
-		return nil, errors.New("invalid priority")
+	taskPriority, err := ParsePriority(priority)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate complexity
@@ -66,7 +58,7 @@ func (s *CollaborativeTaskService) CreateCollaborativeTask(title, description st
 		ProjectID:   projectID,
 		AssignedAt:  time.Now(),
 		DueDate:     dueDate,
-		Priority:    priority,
+		Priority:    taskPriority,
 		Complexity:  complexity,
 		Progress:    0,
 	}
@@ -92,11 +84,14 @@ func (s *CollaborativeTaskService) CreateCollaborativeTask(title, description st
 }
 
 // AddParticipant adds a user to a collaborative task
-func (s *CollaborativeTaskService) AddParticipant(taskID, userID uint, role, contribution string) error {
-	// Verify task exists
-	var task models.CollaborativeTask
-	if err := s.DB.First(&task, taskID).Error; err != nil {
-		return errors.New("collaborative task not found")
+func (s *CollaborativeTaskService) AddParticipant(actor *Actor, taskID, userID uint, role, contribution string) error {
+	// Verify task exists and the actor may manage its participants
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return err
+	}
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionManageParticipants); err != nil {
+		return err
 	}
 
 	// Verify user exists
@@ -104,6 +99,9 @@ func (s *CollaborativeTaskService) AddParticipant(taskID, userID uint, role, con
 	if err := s.DB.First(&user, userID).Error; err != nil {
 		return errors.New("user not found")
 	}
+	if !user.Active {
+		return ErrUserDeactivated
+	}
 
 	// Check if user is already a participant
 	var existingParticipant models.CollaborativeTaskParticipant
@@ -137,13 +135,16 @@ func (s *CollaborativeTaskService) AddParticipant(taskID, userID uint, role, con
 }
 
 // RemoveParticipant removes a user from a collaborative task
-func (s *CollaborativeTaskService) RemoveParticipant(taskID, userID uint) error {
-	// Check if user is the lead (cannot remove lead)
-	var task models.CollaborativeTask
-	if err := s.DB.First(&task, taskID).Error; err != nil {
-		return errors.New("collaborative task not found")
+func (s *CollaborativeTaskService) RemoveParticipant(actor *Actor, taskID, userID uint) error {
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return err
+	}
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionManageParticipants); err != nil {
+		return err
 	}
 
+	// Check if user is the lead (cannot remove lead)
 	if task.LeadUserID == userID {
 		return errors.New("cannot remove the lead user from the task")
 	}
@@ -161,54 +162,72 @@ func (s *CollaborativeTaskService) RemoveParticipant(taskID, userID uint) error
 }
 
 // UpdateTaskProgress updates the progress of a collaborative task
-func (s *CollaborativeTaskService) UpdateTaskProgress(taskID uint, progress int) error {
+func (s *CollaborativeTaskService) UpdateTaskProgress(actor *Actor, taskID uint, progress int) error {
 	if progress < 0 || progress > 100 {
 		return errors.New("progress must be between 0 and 100")
 	}
 
-	// Update progress
-	if err := s.DB.Model(&models.CollaborativeTask{}).Where("id = ?", taskID).Update("progress", progress).Error; err != nil {
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
 		return err
 	}
-
-	// If progress is 100%, mark task as completed
-	if progress == 100 {
-		if err := s.DB.Model(&models.CollaborativeTask{}).Where("id = ?", taskID).Update("status", models.TaskStatusCompleted).Error; err != nil {
-			return err
-		}
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionUpdateProgress); err != nil {
+		return err
+	}
+	rolledUp, err := hasChildren(s.DB, models.TaskKindCollaborative, task.ID)
+	if err != nil {
+		return err
+	}
+	if rolledUp {
+		return ErrProgressRolledUp
 	}
 
-	return nil
+	var changes taskChanges
+	changes.add("progress", "progress", task.Progress, progress)
+
+	// Reported work starts the task and 100% completes it
+	GetTaskWorkflow(models.TaskKindCollaborative).followProgress(&changes, task, progress)
+
+	return applyTaskChanges(s.DB, &models.CollaborativeTask{}, models.TaskKindCollaborative, task.ID, actor.UserID, changes)
 }
 
-// GetCollaborativeTaskWithDetails returns a collaborative task with all related data
-func (s *CollaborativeTaskService) GetCollaborativeTaskWithDetails(taskID uint) (*models.CollaborativeTask, error) {
+// GetCollaborativeTaskWithDetails returns a collaborative task with all related data (anyone who may view it)
+func (s *CollaborativeTaskService) GetCollaborativeTaskWithDetails(actor *Actor, taskID uint) (*models.CollaborativeTask, error) {
 	var task models.CollaborativeTask
 	err := s.DB.Preload("LeadUser").
 		Preload("Project").
 		Preload("Participants.User").
 		First(&task, taskID).Error
-	return &task, err
+	if err != nil {
+		return nil, ErrTaskNotFound
+	}
+	if err := AuthorizeCollaborativeTask(actor, &task, TaskActionView); err != nil {
+		return nil, err
+	}
+	return &task, nil
 }
 
 // GetUserCollaborativeTasks returns all collaborative tasks where user is a participant
-func (s *CollaborativeTaskService) GetUserCollaborativeTasks(userID uint) ([]models.CollaborativeTask, error) {
+func (s *CollaborativeTaskService) GetUserCollaborativeTasks(userID uint, options TaskListOptions) ([]models.CollaborativeTask, error) {
 	var tasks []models.CollaborativeTask
 	err := s.DB.Joins("JOIN collaborative_task_participants ON collaborative_tasks.id = collaborative_task_participants.collaborative_task_id").
 		Where("collaborative_task_participants.user_id = ?", userID).
 		Preload("LeadUser").
 		Preload("Project").
 		Preload("Participants.User").
-		Order("collaborative_tasks.created_at DESC").
+		Scopes(options.scope("collaborative_tasks")).
 		Find(&tasks).Error
 	return tasks, err
 }
 
 // GetCollaborativeTaskStatistics returns statistics for a collaborative task
-func (s *CollaborativeTaskService) GetCollaborativeTaskStatistics(taskID uint) (map[string]interface{}, error) {
-	var task models.CollaborativeTask
-	if err := s.DB.Preload("Participants").First(&task, taskID).Error; err != nil {
-		return nil, errors.New("collaborative task not found")
+func (s *CollaborativeTaskService) GetCollaborativeTaskStatistics(actor *Actor, taskID uint) (map[string]interface{}, error) {
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionView); err != nil {
+		return nil, err
 	}
 
 	// Count participants by role
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrOutsideDepartment is returned when a user acts on a department they have no access to
+var ErrOutsideDepartment = errors.New("outside of your departments")
+
+// DepartmentScope lists the departments a user may view, assign and report on.
+// DepartmentIDs already includes every sub-department of the departments granted.
+type DepartmentScope struct {
+	All           bool
+	DepartmentIDs []uint
+}
+
+// Allows reports whether the scope includes a department. Users without a
+// department are only covered by an unrestricted scope.
+func (d DepartmentScope) Allows(departmentID *uint) bool {
+	if d.All {
+		return true
+	}
+	if departmentID == nil {
+		return false
+	}
+	for _, allowed := range d.DepartmentIDs {
+		if allowed == *departmentID {
+			return true
+		}
+	}
+	return false
+}
+
+// Tasks is a GORM scope that keeps tasks owned by users in the scope
+func (d DepartmentScope) Tasks(db *gorm.DB) *gorm.DB {
+	if d.All {
+		return db
+	}
+	return db.Where("tasks.user_id IN (SELECT id FROM users WHERE department_id IN ?)", d.DepartmentIDs)
+}
+
+// CollaborativeTasks is a GORM scope that keeps collaborative tasks led by users in the scope
+func (d DepartmentScope) CollaborativeTasks(db *gorm.DB) *gorm.DB {
+	if d.All {
+		return db
+	}
+	return db.Where("collaborative_tasks.lead_user_id IN (SELECT id FROM users WHERE department_id IN ?)", d.DepartmentIDs)
+}
+
+// Users is a GORM scope that keeps users in the scope
+func (d DepartmentScope) Users(db *gorm.DB) *gorm.DB {
+	if d.All {
+		return db
+	}
+	return db.Where("users.department_id IN ?", d.DepartmentIDs)
+}
+
+type DepartmentAccessService struct {
+	DB *gorm.DB
+}
+
+func NewDepartmentAccessService(db *gorm.DB) *DepartmentAccessService {
+	return &DepartmentAccessService{DB: db}
+}
+
+// ScopeFor returns the departments a user may act on: their own, the ones they head and any
+// granted ones, each with all of its sub-departments. Roles with department.access_all are not restricted.
+func (s *DepartmentAccessService) ScopeFor(user *models.User) (DepartmentScope, error) {
+	if NewPermissionService(s.DB).RoleHasPermission(user.Role, models.PermissionDepartmentAccessAll) {
+		return DepartmentScope{All: true}, nil
+	}
+
+	var grants []models.DepartmentAccessGrant
+	if err := s.DB.Where("user_id = ?", user.ID).Find(&grants).Error; err != nil {
+		return DepartmentScope{}, err
+	}
+
+	var rootIDs []uint
+	if user.DepartmentID != nil {
+		rootIDs = append(rootIDs, *user.DepartmentID)
+	}
+	for _, grant := range grants {
+		if grant.CoversAllDepartments() {
+			return DepartmentScope{All: true}, nil
+		}
+		rootIDs = append(rootIDs, *grant.DepartmentID)
+	}
+
+	var headedIDs []uint
+	if err := s.DB.Model(&models.Department{}).Where("head_user_id = ?", user.ID).Pluck("id", &headedIDs).Error; err != nil {
+		return DepartmentScope{}, err
+	}
+	rootIDs = append(rootIDs, headedIDs...)
+
+	departmentIDs, err := NewDepartmentService(s.DB).SubtreeIDs(rootIDs)
+	if err != nil {
+		return DepartmentScope{}, err
+	}
+
+	return DepartmentScope{DepartmentIDs: departmentIDs}, nil
+}
+
+// CheckUser returns ErrOutsideDepartment when the target user is not in the scope
+func (s *DepartmentAccessService) CheckUser(scope DepartmentScope, targetUserID uint) error {
+	if scope.All {
+		return nil
+	}
+
+	var target models.User
+	if err := s.DB.Select("id", "department_id").First(&target, targetUserID).Error; err != nil {
+		return errors.New("user not found")
+	}
+	if !scope.Allows(target.DepartmentID) {
+		return ErrOutsideDepartment
+	}
+	return nil
+}
+
+// GetUserGrants returns the extra departments granted to a user
+func (s *DepartmentAccessService) GetUserGrants(userID uint) ([]models.DepartmentAccessGrant, error) {
+	var grants []models.DepartmentAccessGrant
+	if err := s.DB.Where("user_id = ?", userID).Preload("Department").Order("created_at").Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// GrantAccess gives a user access to another department and its sub-departments,
+// or to every department when departmentID is nil. The actor must be allowed to administer
+// the user and may only hand out departments they can act on themselves.
+func (s *DepartmentAccessService) GrantAccess(actor *Actor, userID uint, departmentID *uint) (*models.DepartmentAccessGrant, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, &user); err != nil {
+		return nil, err
+	}
+	if err := AuthorizeDepartmentGrant(actor, departmentID); err != nil {
+		return nil, err
+	}
+
+	existing := s.DB.Where("user_id = ?", userID)
+	if departmentID != nil {
+		if !NewDepartmentService(s.DB).Exists(*departmentID) {
+			return nil, ErrDepartmentNotFound
+		}
+		if user.DepartmentID != nil && *user.DepartmentID == *departmentID {
+			return nil, errors.New("user already belongs to this department")
+		}
+		existing = existing.Where("department_id = ?", *departmentID)
+	} else {
+		existing = existing.Where("department_id IS NULL")
+	}
+
+	var count int64
+	if err := existing.Model(&models.DepartmentAccessGrant{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("access already granted")
+	}
+
+	grant := &models.DepartmentAccessGrant{
+		UserID:       userID,
+		DepartmentID: departmentID,
+		GrantedBy:    actor.UserID,
+	}
+	if err := s.DB.Create(grant).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Preload("Department").First(grant, grant.ID).Error; err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// RevokeAccess removes a department grant from a user
+func (s *DepartmentAccessService) RevokeAccess(userID, grantID uint) error {
+	result := s.DB.Unscoped().Where("id = ? AND user_id = ?", grantID, userID).Delete(&models.DepartmentAccessGrant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("grant not found")
+	}
+	return nil
+}
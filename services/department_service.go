@@ -0,0 +1,412 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"project-x/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrDepartmentNotFound is returned when a department ID or name does not exist
+var ErrDepartmentNotFound = errors.New("department not found")
+
+type DepartmentService struct {
+	DB *gorm.DB
+}
+
+func NewDepartmentService(db *gorm.DB) *DepartmentService {
+	return &DepartmentService{DB: db}
+}
+
+// ListDepartments returns every department with its head
+func (s *DepartmentService) ListDepartments() ([]models.Department, error) {
+	var departments []models.Department
+	if err := s.DB.Preload("Head").Order("name").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+	return departments, nil
+}
+
+// GetDepartment returns a department with its parent, direct children and head
+func (s *DepartmentService) GetDepartment(departmentID uint) (*models.Department, error) {
+	var department models.Department
+	err := s.DB.Preload("Parent").
+		Preload("Children", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Head").
+		First(&department, departmentID).Error
+	if err != nil {
+		return nil, ErrDepartmentNotFound
+	}
+	return &department, nil
+}
+
+// FindByName looks up a department by name, ignoring case and surrounding spaces
+func (s *DepartmentService) FindByName(name string) (*models.Department, error) {
+	var department models.Department
+	if err := s.DB.Where("LOWER(name) = LOWER(?)", strings.TrimSpace(name)).First(&department).Error; err != nil {
+		return nil, ErrDepartmentNotFound
+	}
+	return &department, nil
+}
+
+// Exists reports whether a department exists
+func (s *DepartmentService) Exists(departmentID uint) bool {
+	var count int64
+	s.DB.Model(&models.Department{}).Where("id = ?", departmentID).Count(&count)
+	return count > 0
+}
+
+// CreateDepartment creates a department under an optional parent with an optional head
+func (s *DepartmentService) CreateDepartment(name string, parentID, headUserID *uint) (*models.Department, error) {
+	department := &models.Department{}
+	if err := s.applyChanges(department, name, parentID, headUserID); err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Create(department).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetDepartment(department.ID)
+}
+
+// UpdateDepartment renames, moves or changes the head of a department.
+// A department cannot be moved under itself or one of its sub-departments.
+func (s *DepartmentService) UpdateDepartment(departmentID uint, name string, parentID, headUserID *uint) (*models.Department, error) {
+	var department models.Department
+	if err := s.DB.First(&department, departmentID).Error; err != nil {
+		return nil, ErrDepartmentNotFound
+	}
+
+	if parentID != nil {
+		subtree, err := s.SubtreeIDs([]uint{departmentID})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range subtree {
+			if id == *parentID {
+				return nil, errors.New("a department cannot be moved under itself or its sub-departments")
+			}
+		}
+	}
+
+	if err := s.applyChanges(&department, name, parentID, headUserID); err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.Model(&department).Select("name", "parent_id", "head_user_id").Updates(&department).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetDepartment(department.ID)
+}
+
+// DeleteDepartment deletes a department that has no users and no sub-departments
+func (s *DepartmentService) DeleteDepartment(departmentID uint) error {
+	var department models.Department
+	if err := s.DB.First(&department, departmentID).Error; err != nil {
+		return ErrDepartmentNotFound
+	}
+
+	var childCount, userCount int64
+	s.DB.Model(&models.Department{}).Where("parent_id = ?", departmentID).Count(&childCount)
+	if childCount > 0 {
+		return errors.New("department still has sub-departments")
+	}
+	s.DB.Model(&models.User{}).Where("department_id = ?", departmentID).Count(&userCount)
+	if userCount > 0 {
+		return errors.New("department still has users")
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("department_id = ?", departmentID).Delete(&models.DepartmentAccessGrant{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&department).Error
+	})
+}
+
+// SubtreeIDs returns the given departments and all of their sub-departments
+func (s *DepartmentService) SubtreeIDs(rootIDs []uint) ([]uint, error) {
+	if len(rootIDs) == 0 {
+		return nil, nil
+	}
+
+	var departments []models.Department
+	if err := s.DB.Select("id", "parent_id").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, department := range departments {
+		if department.ParentID != nil {
+			children[*department.ParentID] = append(children[*department.ParentID], department.ID)
+		}
+	}
+
+	seen := make(map[uint]bool)
+	var subtree []uint
+	queue := append([]uint(nil), rootIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		subtree = append(subtree, id)
+		queue = append(queue, children[id]...)
+	}
+
+	return subtree, nil
+}
+
+// GetDepartmentReport returns user and task counts for a department and each of its
+// sub-departments. Every node's totals include the nodes below it; the priority breakdown
+// covers the whole subtree.
+func (s *DepartmentService) GetDepartmentReport(departmentID uint) (map[string]interface{}, error) {
+	subtree, err := s.SubtreeIDs([]uint{departmentID})
+	if err != nil {
+		return nil, err
+	}
+	if !s.Exists(departmentID) {
+		return nil, ErrDepartmentNotFound
+	}
+
+	var departments []models.Department
+	if err := s.DB.Where("id IN ?", subtree).Order("name").Find(&departments).Error; err != nil {
+		return nil, err
+	}
+
+	var userCounts []struct {
+		DepartmentID uint
+		Count        int64
+	}
+	if err := s.DB.Model(&models.User{}).
+		Select("department_id, COUNT(*) AS count").
+		Where("department_id IN ?", subtree).
+		Group("department_id").
+		Scan(&userCounts).Error; err != nil {
+		return nil, err
+	}
+
+	type statusCount struct {
+		DepartmentID uint
+		Status       models.TaskStatus
+		Count        int64
+	}
+	var taskCounts, collaborativeCounts []statusCount
+	if err := s.DB.Model(&models.Task{}).
+		Select("users.department_id, tasks.status, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = tasks.user_id").
+		Where("users.department_id IN ?", subtree).
+		Group("users.department_id, tasks.status").
+		Scan(&taskCounts).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.CollaborativeTask{}).
+		Select("users.department_id, collaborative_tasks.status, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = collaborative_tasks.lead_user_id").
+		Where("users.department_id IN ?", subtree).
+		Group("users.department_id, collaborative_tasks.status").
+		Scan(&collaborativeCounts).Error; err != nil {
+		return nil, err
+	}
+
+	// Priority breakdown of the whole subtree
+	priorities := priorityCounts{}
+	if err := priorities.add(s.DB.Model(&models.Task{}).
+		Joins("JOIN users ON users.id = tasks.user_id").
+		Where("users.department_id IN ?", subtree), "tasks"); err != nil {
+		return nil, err
+	}
+	if err := priorities.add(s.DB.Model(&models.CollaborativeTask{}).
+		Joins("JOIN users ON users.id = collaborative_tasks.lead_user_id").
+		Where("users.department_id IN ?", subtree), "collaborative_tasks"); err != nil {
+		return nil, err
+	}
+
+	// Direct counts per department, rolled up below
+	directUsers := make(map[uint]int64)
+	for _, row := range userCounts {
+		directUsers[row.DepartmentID] = row.Count
+	}
+	directTasks := make(map[uint]map[models.TaskStatus]int64)
+	directCollaborative := make(map[uint]map[models.TaskStatus]int64)
+	for _, rows := range []struct {
+		counts []statusCount
+		into   map[uint]map[models.TaskStatus]int64
+	}{{taskCounts, directTasks}, {collaborativeCounts, directCollaborative}} {
+		for _, row := range rows.counts {
+			if rows.into[row.DepartmentID] == nil {
+				rows.into[row.DepartmentID] = make(map[models.TaskStatus]int64)
+			}
+			rows.into[row.DepartmentID][row.Status] = row.Count
+		}
+	}
+
+	children := make(map[uint][]models.Department)
+	for _, department := range departments {
+		if department.ParentID != nil {
+			children[*department.ParentID] = append(children[*department.ParentID], department)
+		}
+	}
+
+	var buildNode func(department models.Department) (map[string]interface{}, int64, map[models.TaskStatus]int64, map[models.TaskStatus]int64)
+	buildNode = func(department models.Department) (map[string]interface{}, int64, map[models.TaskStatus]int64, map[models.TaskStatus]int64) {
+		users := directUsers[department.ID]
+		tasks := make(map[models.TaskStatus]int64)
+		collaborative := make(map[models.TaskStatus]int64)
+		for status, count := range directTasks[department.ID] {
+			tasks[status] += count
+		}
+		for status, count := range directCollaborative[department.ID] {
+			collaborative[status] += count
+		}
+
+		subDepartments := []map[string]interface{}{}
+		for _, child := range children[department.ID] {
+			childNode, childUsers, childTasks, childCollaborative := buildNode(child)
+			subDepartments = append(subDepartments, childNode)
+			users += childUsers
+			for status, count := range childTasks {
+				tasks[status] += count
+			}
+			for status, count := range childCollaborative {
+				collaborative[status] += count
+			}
+		}
+
+		totalTasks := sumCounts(tasks) + sumCounts(collaborative)
+		completedTasks := tasks[models.TaskStatusCompleted] + collaborative[models.TaskStatusCompleted]
+		completionRate := 0.0
+		if totalTasks > 0 {
+			completionRate = float64(completedTasks) / float64(totalTasks) * 100
+		}
+
+		node := map[string]interface{}{
+			"department_id":       department.ID,
+			"name":                department.Name,
+			"head_user_id":        department.HeadUserID,
+			"direct_users":        directUsers[department.ID],
+			"users":               users,
+			"regular_tasks":       statusSummary(tasks),
+			"collaborative_tasks": statusSummary(collaborative),
+			"total_tasks":         totalTasks,
+			"completed_tasks":     completedTasks,
+			"completion_rate":     completionRate,
+			"sub_departments":     subDepartments,
+		}
+		return node, users, tasks, collaborative
+	}
+
+	for _, department := range departments {
+		if department.ID == departmentID {
+			report, _, _, _ := buildNode(department)
+			report["priority"] = priorities.breakdown()
+			return report, nil
+		}
+	}
+	return nil, ErrDepartmentNotFound
+}
+
+// applyChanges validates and sets the editable fields of a department
+func (s *DepartmentService) applyChanges(department *models.Department, name string, parentID, headUserID *uint) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("department name is required")
+	}
+
+	var existing models.Department
+	if err := s.DB.Where("LOWER(name) = LOWER(?) AND id <> ?", name, department.ID).First(&existing).Error; err == nil {
+		return errors.New("a department with this name already exists")
+	}
+
+	if parentID != nil && !s.Exists(*parentID) {
+		return errors.New("parent department not found")
+	}
+
+	if headUserID != nil {
+		var head models.User
+		if err := s.DB.First(&head, *headUserID).Error; err != nil {
+			return errors.New("head user not found")
+		}
+		if !head.Active {
+			return ErrUserDeactivated
+		}
+	}
+
+	department.Name = name
+	department.ParentID = parentID
+	department.HeadUserID = headUserID
+	return nil
+}
+
+// sumCounts adds up task counts across statuses
+func sumCounts(counts map[models.TaskStatus]int64) int64 {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	return total
+}
+
+// statusSummary formats task counts by status for reports
+func statusSummary(counts map[models.TaskStatus]int64) map[string]interface{} {
+	return map[string]interface{}{
+		"total":       sumCounts(counts),
+		"pending":     counts[models.TaskStatusPending],
+		"in_progress": counts[models.TaskStatusInProgress],
+		"completed":   counts[models.TaskStatusCompleted],
+		"cancelled":   counts[models.TaskStatusCancelled],
+	}
+}
+
+// legacyDepartmentTables are tables that stored department names before departments
+// became their own table
+var legacyDepartmentTables = []string{"users", "invitations", "department_access_grants"}
+
+// MigrateLegacyDepartments turns the old free-text department columns into department_id
+// references, creating one department per distinct name, and then drops the old columns.
+// It does nothing once the old columns are gone.
+func MigrateLegacyDepartments(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range legacyDepartmentTables {
+			if !tx.Migrator().HasColumn(table, "department") {
+				continue
+			}
+
+			var names []string
+			if err := tx.Table(table).Distinct("department").Pluck("department", &names).Error; err != nil {
+				return err
+			}
+
+			for _, name := range names {
+				// "*" was the old grant value for every department, which is now a nil department_id
+				trimmed := strings.TrimSpace(name)
+				if trimmed == "" || trimmed == "*" {
+					continue
+				}
+
+				var department models.Department
+				if err := tx.Where("LOWER(name) = LOWER(?)", trimmed).
+					Attrs(models.Department{Name: trimmed}).
+					FirstOrCreate(&department).Error; err != nil {
+					return err
+				}
+
+				if err := tx.Table(table).Where("department = ?", name).Update("department_id", department.ID).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Migrator().DropColumn(table, "department"); err != nil {
+				return err
+			}
+			log.Printf("Migrated %d department name(s) from %s", len(names), table)
+		}
+		return nil
+	})
+}
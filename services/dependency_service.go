@@ -0,0 +1,504 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned for task dependency changes
+var (
+	ErrDependencyNotFound = errors.New("dependency not found")
+	ErrDependencyExists   = errors.New("the tasks are already linked")
+	ErrSelfDependency     = errors.New("a task cannot block itself")
+	ErrDependencyCycle    = errors.New("the dependency would create a cycle")
+	ErrTaskBlocked        = errors.New("task is blocked by open tasks")
+)
+
+// dependencyLockClass is the first key of the transaction-scoped advisory lock that serializes
+// dependency changes; the second is the workspace
+const dependencyLockClass = 1
+
+// TaskRef points at a task or collaborative task
+type TaskRef struct {
+	Kind string `json:"kind"` // See the models.TaskKind constants
+	ID   uint   `json:"id"`
+}
+
+// String formats the reference as "kind:id", e.g. "collaborative_task:12"
+func (r TaskRef) String() string {
+	return r.Kind + ":" + strconv.FormatUint(uint64(r.ID), 10)
+}
+
+// DependencyCycleError is returned for a link that would close a cycle. Cycle starts at the
+// requested blocker and follows the existing links back to it.
+type DependencyCycleError struct {
+	Cycle []TaskRef
+}
+
+func (e *DependencyCycleError) Error() string {
+	steps := make([]string, len(e.Cycle))
+	for i, ref := range e.Cycle {
+		steps[i] = ref.String()
+	}
+	return ErrDependencyCycle.Error() + ": " + strings.Join(steps, " blocks ")
+}
+
+func (e *DependencyCycleError) Unwrap() error {
+	return ErrDependencyCycle
+}
+
+// BlockedTaskError is returned when a task would start while some of its blockers are open
+type BlockedTaskError struct {
+	Task     TaskRef
+	Blockers []TaskRef
+}
+
+func (e *BlockedTaskError) Error() string {
+	blockers := make([]string, len(e.Blockers))
+	for i, ref := range e.Blockers {
+		blockers[i] = ref.String()
+	}
+	return fmt.Sprintf("%s: %s waits for %s", ErrTaskBlocked, e.Task, strings.Join(blockers, ", "))
+}
+
+func (e *BlockedTaskError) Unwrap() error {
+	return ErrTaskBlocked
+}
+
+type DependencyService struct {
+	DB *gorm.DB
+}
+
+func NewDependencyService(db *gorm.DB) *DependencyService {
+	return &DependencyService{DB: db}
+}
+
+// DependencyLink is the task at the other end of one of a task's dependencies. Title is left
+// empty when the actor may not view that task.
+type DependencyLink struct {
+	DependencyID uint
+	Task         TaskRef
+	Title        string
+	Status       models.TaskStatus
+	ProjectID    *uint
+	CreatedBy    uint
+	CreatedAt    time.Time
+}
+
+// TaskDependencies lists the tasks blocking a task and the tasks it blocks
+type TaskDependencies struct {
+	BlockedBy []DependencyLink
+	Blocks    []DependencyLink
+	Blocked   bool // Some blocker is still open
+}
+
+// GetDependencies returns the dependencies of a task or collaborative task
+func (s *DependencyService) GetDependencies(actor *Actor, kind string, taskID uint) (*TaskDependencies, error) {
+	task, err := loadAnyTask(s.DB, kind, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := task.authorize(actor, TaskActionView); err != nil {
+		return nil, err
+	}
+
+	var links []models.TaskDependency
+	err = s.DB.Where("(blocker_kind = ? AND blocker_id = ?) OR (blocked_kind = ? AND blocked_id = ?)", kind, taskID, kind, taskID).
+		Order("id").
+		Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := &TaskDependencies{BlockedBy: []DependencyLink{}, Blocks: []DependencyLink{}}
+	for _, link := range links {
+		blocker := TaskRef{Kind: link.BlockerKind, ID: link.BlockerID}
+		other, blocks := blocker, false
+		if blocker == (TaskRef{Kind: kind, ID: taskID}) {
+			other, blocks = TaskRef{Kind: link.BlockedKind, ID: link.BlockedID}, true
+		}
+		otherTask, err := loadAnyTask(s.DB, other.Kind, other.ID)
+		if errors.Is(err, ErrTaskNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry := DependencyLink{
+			DependencyID: link.ID,
+			Task:         other,
+			Status:       otherTask.status(),
+			ProjectID:    otherTask.projectID(),
+			CreatedBy:    link.CreatedBy,
+			CreatedAt:    link.CreatedAt,
+		}
+		if otherTask.authorize(actor, TaskActionView) == nil {
+			entry.Title = otherTask.title()
+		}
+		if blocks {
+			dependencies.Blocks = append(dependencies.Blocks, entry)
+			continue
+		}
+		dependencies.BlockedBy = append(dependencies.BlockedBy, entry)
+		if !GetTaskWorkflow(other.Kind).isClosed(entry.Status) {
+			dependencies.Blocked = true
+		}
+	}
+	return dependencies, nil
+}
+
+// AddDependency records that blocker blocks the blocked task. The link needs the edit access to
+// the blocked task and the view access to the blocker, and is rejected when the blocked task
+// already blocks the blocker, directly or through other tasks.
+func (s *DependencyService) AddDependency(actor *Actor, blocked, blocker TaskRef) (*models.TaskDependency, error) {
+	if blocked == blocker {
+		return nil, ErrSelfDependency
+	}
+	blockedTask, err := loadAnyTask(s.DB, blocked.Kind, blocked.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := blockedTask.authorize(actor, TaskActionEdit); err != nil {
+		return nil, err
+	}
+	blockerTask, err := loadAnyTask(s.DB, blocker.Kind, blocker.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := blockerTask.authorize(actor, TaskActionView); err != nil {
+		return nil, err
+	}
+
+	link := &models.TaskDependency{
+		BlockerKind: blocker.Kind,
+		BlockerID:   blocker.ID,
+		BlockedKind: blocked.Kind,
+		BlockedID:   blocked.ID,
+		CreatedBy:   actor.UserID,
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Two links added at once could each pass the cycle check and close a cycle together,
+		// so links in a workspace are added one at a time
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?::int, ?::int)", dependencyLockClass, blockedTask.workspaceID()).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		err := tx.Model(&models.TaskDependency{}).
+			Where("blocker_kind = ? AND blocker_id = ? AND blocked_kind = ? AND blocked_id = ?", blocker.Kind, blocker.ID, blocked.Kind, blocked.ID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDependencyExists
+		}
+
+		path, err := findPath(blocked, blocker, func(ref TaskRef) ([]TaskRef, error) {
+			return blockedTasks(tx, ref)
+		})
+		if err != nil {
+			return err
+		}
+		if path != nil {
+			return &DependencyCycleError{Cycle: append([]TaskRef{blocker}, path...)}
+		}
+		return tx.Create(link).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// RemoveDependency deletes one of a task's dependencies, from either end of the link. It needs the
+// edit access to the blocked task.
+func (s *DependencyService) RemoveDependency(actor *Actor, kind string, taskID, dependencyID uint) error {
+	var link models.TaskDependency
+	err := s.DB.Where("(blocker_kind = ? AND blocker_id = ?) OR (blocked_kind = ? AND blocked_id = ?)", kind, taskID, kind, taskID).
+		First(&link, dependencyID).Error
+	if err != nil {
+		return ErrDependencyNotFound
+	}
+
+	blockedTask, err := loadAnyTask(s.DB, link.BlockedKind, link.BlockedID)
+	if err != nil {
+		return err
+	}
+	if err := blockedTask.authorize(actor, TaskActionEdit); err != nil {
+		return err
+	}
+	return s.DB.Delete(&link).Error
+}
+
+// DependencyNode is a task or collaborative task in a dependency graph. External nodes belong to
+// another project; they show only their links to the project and no title.
+type DependencyNode struct {
+	Task      TaskRef
+	Title     string
+	Status    models.TaskStatus
+	Priority  models.Priority
+	ProjectID *uint
+	Blocked   bool // An open task in the graph blocks it
+	External  bool
+}
+
+// DependencyEdge is a link in a dependency graph: From blocks To
+type DependencyEdge struct {
+	DependencyID uint
+	From         TaskRef
+	To           TaskRef
+}
+
+// DependencyGraph holds the tasks of a project and the links between them
+type DependencyGraph struct {
+	Nodes []DependencyNode
+	Edges []DependencyEdge
+}
+
+// GetDependencyGraph returns the tasks and collaborative tasks of a project with their
+// dependencies, including the tasks of other projects they are linked to
+func (s *DependencyService) GetDependencyGraph(projectID uint) (*DependencyGraph, error) {
+	if err := s.DB.First(&models.Project{}, projectID).Error; err != nil {
+		return nil, ErrProjectNotFound
+	}
+
+	var nodes []DependencyNode
+	ids := map[string][]uint{}
+	for _, kind := range []string{models.TaskKindTask, models.TaskKindCollaborative} {
+		kindNodes, err := dependencyNodes(s.DB, kind, "project_id = ?", projectID)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range kindNodes {
+			ids[kind] = append(ids[kind], node.Task.ID)
+		}
+		nodes = append(nodes, kindNodes...)
+	}
+
+	var links []models.TaskDependency
+	tasks, collaborative := ids[models.TaskKindTask], ids[models.TaskKindCollaborative]
+	err := s.DB.Where("(blocker_kind = ? AND blocker_id IN ?) OR (blocked_kind = ? AND blocked_id IN ?) OR (blocker_kind = ? AND blocker_id IN ?) OR (blocked_kind = ? AND blocked_id IN ?)",
+		models.TaskKindTask, tasks, models.TaskKindTask, tasks, models.TaskKindCollaborative, collaborative, models.TaskKindCollaborative, collaborative).
+		Order("id").
+		Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the tasks of other projects at the far end of the links
+	inProject := make(map[TaskRef]bool, len(nodes))
+	for _, node := range nodes {
+		inProject[node.Task] = true
+	}
+	external := map[string][]uint{}
+	for _, link := range links {
+		for _, ref := range []TaskRef{{Kind: link.BlockerKind, ID: link.BlockerID}, {Kind: link.BlockedKind, ID: link.BlockedID}} {
+			if !inProject[ref] {
+				inProject[ref] = true
+				external[ref.Kind] = append(external[ref.Kind], ref.ID)
+			}
+		}
+	}
+	for _, kind := range []string{models.TaskKindTask, models.TaskKindCollaborative} {
+		if len(external[kind]) == 0 {
+			continue
+		}
+		kindNodes, err := dependencyNodes(s.DB, kind, "id IN ?", external[kind])
+		if err != nil {
+			return nil, err
+		}
+		for i := range kindNodes {
+			kindNodes[i].Title = ""
+			kindNodes[i].External = true
+		}
+		nodes = append(nodes, kindNodes...)
+	}
+
+	return buildDependencyGraph(nodes, links), nil
+}
+
+// dependencyNodes loads the graph nodes of the tasks of one kind matching a condition
+func dependencyNodes(db *gorm.DB, kind string, query string, args ...interface{}) ([]DependencyNode, error) {
+	var rows []struct {
+		ID        uint
+		Title     string
+		Status    models.TaskStatus
+		Priority  models.Priority
+		ProjectID *uint
+	}
+	err := db.Model(taskModel(kind)).
+		Select("id, title, status, priority, project_id").
+		Where(query, args...).
+		Order("id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]DependencyNode, 0, len(rows))
+	for _, row := range rows {
+		nodes = append(nodes, DependencyNode{
+			Task:      TaskRef{Kind: kind, ID: row.ID},
+			Title:     row.Title,
+			Status:    row.Status,
+			Priority:  row.Priority,
+			ProjectID: row.ProjectID,
+		})
+	}
+	return nodes, nil
+}
+
+// buildDependencyGraph turns the links between the nodes into edges and marks the nodes an open
+// node blocks. Links to tasks missing from the nodes are left out.
+func buildDependencyGraph(nodes []DependencyNode, links []models.TaskDependency) *DependencyGraph {
+	index := make(map[TaskRef]int, len(nodes))
+	for i, node := range nodes {
+		index[node.Task] = i
+	}
+
+	edges := make([]DependencyEdge, 0, len(links))
+	for _, link := range links {
+		from, to := TaskRef{Kind: link.BlockerKind, ID: link.BlockerID}, TaskRef{Kind: link.BlockedKind, ID: link.BlockedID}
+		blocker, ok := index[from]
+		if !ok {
+			continue
+		}
+		blocked, ok := index[to]
+		if !ok {
+			continue
+		}
+		edges = append(edges, DependencyEdge{DependencyID: link.ID, From: from, To: to})
+		if !GetTaskWorkflow(from.Kind).isClosed(nodes[blocker].Status) {
+			nodes[blocked].Blocked = true
+		}
+	}
+	if nodes == nil {
+		nodes = []DependencyNode{}
+	}
+	return &DependencyGraph{Nodes: nodes, Edges: edges}
+}
+
+// findPath searches the graph given by next breadth-first for a path from one task to another.
+// The path includes both ends; it is nil when to cannot be reached.
+func findPath(from, to TaskRef, next func(TaskRef) ([]TaskRef, error)) ([]TaskRef, error) {
+	previous := map[TaskRef]TaskRef{}
+	visited := map[TaskRef]bool{from: true}
+	queue := []TaskRef{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			path := []TaskRef{current}
+			for current != from {
+				current = previous[current]
+				path = append(path, current)
+			}
+			for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+				path[i], path[j] = path[j], path[i]
+			}
+			return path, nil
+		}
+
+		neighbours, err := next(current)
+		if err != nil {
+			return nil, err
+		}
+		for _, neighbour := range neighbours {
+			if !visited[neighbour] {
+				visited[neighbour] = true
+				previous[neighbour] = current
+				queue = append(queue, neighbour)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// blockedTasks returns the tasks a task blocks directly
+func blockedTasks(db *gorm.DB, task TaskRef) ([]TaskRef, error) {
+	var links []models.TaskDependency
+	if err := db.Where("blocker_kind = ? AND blocker_id = ?", task.Kind, task.ID).Order("id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	refs := make([]TaskRef, len(links))
+	for i, link := range links {
+		refs[i] = TaskRef{Kind: link.BlockedKind, ID: link.BlockedID}
+	}
+	return refs, nil
+}
+
+// openBlockers returns the blockers of a task that are not closed in the workflow of their kind
+func openBlockers(db *gorm.DB, task TaskRef) ([]TaskRef, error) {
+	var links []models.TaskDependency
+	if err := db.Where("blocked_kind = ? AND blocked_id = ?", task.Kind, task.ID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	var open []TaskRef
+	for _, kind := range []string{models.TaskKindTask, models.TaskKindCollaborative} {
+		var ids []uint
+		for _, link := range links {
+			if link.BlockerKind == kind {
+				ids = append(ids, link.BlockerID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		query := db.Model(taskModel(kind)).Where("id IN ?", ids)
+		if closed := GetTaskWorkflow(kind).Closed; len(closed) > 0 {
+			query = query.Where("status NOT IN ?", closed)
+		}
+		var openIDs []uint
+		if err := query.Order("id").Pluck("id", &openIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range openIDs {
+			open = append(open, TaskRef{Kind: kind, ID: id})
+		}
+	}
+	return open, nil
+}
+
+// checkBlockers rejects changes that start a task while some of its blockers are open
+func checkBlockers(db *gorm.DB, task TaskRef, changes taskChanges) error {
+	from, to, ok := changes.status()
+	if !ok || !startsWork(from, to) {
+		return nil
+	}
+	blockers, err := openBlockers(db, task)
+	if err != nil {
+		return err
+	}
+	if len(blockers) > 0 {
+		return &BlockedTaskError{Task: task, Blockers: blockers}
+	}
+	return nil
+}
+
+// startsWork reports whether a status change takes a task from waiting to being worked on or done
+func startsWork(from, to models.TaskStatus) bool {
+	return !isStarted(from) && isStarted(to)
+}
+
+func isStarted(status models.TaskStatus) bool {
+	return status == models.TaskStatusInProgress || status == models.TaskStatusCompleted
+}
+
+// deleteDependencies removes the links of tasks of one kind that are being deleted
+func deleteDependencies(db *gorm.DB, kind string, taskIDs []uint) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	return db.Where("(blocker_kind = ? AND blocker_id IN ?) OR (blocked_kind = ? AND blocked_id IN ?)", kind, taskIDs, kind, taskIDs).
+		Delete(&models.TaskDependency{}).Error
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"reflect"
+	"testing"
+)
+
+func TestFindPath(t *testing.T) {
+	a := TaskRef{Kind: models.TaskKindTask, ID: 1}
+	b := TaskRef{Kind: models.TaskKindCollaborative, ID: 1}
+	c := TaskRef{Kind: models.TaskKindTask, ID: 2}
+	d := TaskRef{Kind: models.TaskKindTask, ID: 3}
+
+	// a blocks b and d, b blocks c, c blocks a
+	blocks := map[TaskRef][]TaskRef{a: {b, d}, b: {c}, c: {a}}
+	next := func(ref TaskRef) ([]TaskRef, error) { return blocks[ref], nil }
+
+	path, err := findPath(a, c, next)
+	if err != nil || !reflect.DeepEqual(path, []TaskRef{a, b, c}) {
+		t.Errorf("a to c: got %v, %v, want [a b c]", path, err)
+	}
+	if path, err := findPath(d, a, next); err != nil || path != nil {
+		t.Errorf("d blocks nothing: got %v, %v, want no path", path, err)
+	}
+
+	// Making c a blocker of a closes the cycle c -> a -> b -> c found above
+	cycle := &DependencyCycleError{Cycle: append([]TaskRef{c}, path...)}
+	if !errors.Is(cycle, ErrDependencyCycle) || cycle.Error() != ErrDependencyCycle.Error()+": task:2 blocks task:1 blocks collaborative_task:1 blocks task:2" {
+		t.Errorf("cycle error: got %q", cycle.Error())
+	}
+
+	failure := errors.New("lookup failed")
+	if _, err := findPath(a, c, func(TaskRef) ([]TaskRef, error) { return nil, failure }); !errors.Is(err, failure) {
+		t.Errorf("lookup error: got %v, want %v", err, failure)
+	}
+}
+
+func TestBuildDependencyGraph(t *testing.T) {
+	done := TaskRef{Kind: models.TaskKindTask, ID: 1}
+	open := TaskRef{Kind: models.TaskKindTask, ID: 2}
+	waiting := TaskRef{Kind: models.TaskKindCollaborative, ID: 3}
+	nodes := []DependencyNode{
+		{Task: done, Status: models.TaskStatusCompleted},
+		{Task: open, Status: models.TaskStatusInProgress},
+		{Task: waiting, Status: models.TaskStatusPending},
+	}
+	links := []models.TaskDependency{
+		{ID: 10, BlockerKind: done.Kind, BlockerID: done.ID, BlockedKind: open.Kind, BlockedID: open.ID},
+		{ID: 11, BlockerKind: open.Kind, BlockerID: open.ID, BlockedKind: waiting.Kind, BlockedID: waiting.ID},
+		{ID: 12, BlockerKind: models.TaskKindTask, BlockerID: 99, BlockedKind: waiting.Kind, BlockedID: waiting.ID},
+	}
+
+	graph := buildDependencyGraph(nodes, links)
+	wantEdges := []DependencyEdge{{DependencyID: 10, From: done, To: open}, {DependencyID: 11, From: open, To: waiting}}
+	if !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Errorf("edges: got %v, want %v", graph.Edges, wantEdges)
+	}
+	if graph.Nodes[1].Blocked {
+		t.Error("a task blocked only by completed work should not be blocked")
+	}
+	if !graph.Nodes[2].Blocked {
+		t.Error("a task blocked by an open task should be blocked")
+	}
+}
+
+func TestStartsWork(t *testing.T) {
+	cases := []struct {
+		from, to models.TaskStatus
+		want     bool
+	}{
+		{models.TaskStatusPending, models.TaskStatusInProgress, true},
+		{models.TaskStatusInProgress, models.TaskStatusCompleted, false},
+		{models.TaskStatusCompleted, models.TaskStatusInProgress, false},
+		{models.TaskStatusPending, models.TaskStatusCancelled, false},
+		{models.TaskStatusCancelled, models.TaskStatusPending, false},
+	}
+	for _, tc := range cases {
+		if got := startsWork(tc.from, tc.to); got != tc.want {
+			t.Errorf("startsWork(%s, %s) = %v, want %v", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"project-x/config"
+	"project-x/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImpersonationService struct {
+	DB *gorm.DB
+}
+
+func NewImpersonationService(db *gorm.DB) *ImpersonationService {
+	return &ImpersonationService{DB: db}
+}
+
+// ImpersonationToken is a short-lived access token for acting as another user.
+// There is no refresh token; the admin starts a new impersonation when it expires.
+type ImpersonationToken struct {
+	AccessToken        string    `json:"token"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	ImpersonatorID     uint      `json:"impersonator_id"`
+	ImpersonatedUserID uint      `json:"impersonated_user_id"`
+}
+
+// StartImpersonation issues a token that lets an admin act as the target user.
+// The session belongs to the admin, so their logout-all also ends it.
+func (s *ImpersonationService) StartImpersonation(adminID, targetUserID uint, userAgent, ipAddress string) (*ImpersonationToken, error) {
+	if adminID == targetUserID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	var target models.User
+	if err := s.DB.First(&target, targetUserID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !target.Active {
+		return nil, ErrUserDeactivated
+	}
+
+	// Users who could impersonate or change roles themselves are off limits
+	permissionService := NewPermissionService(s.DB)
+	if permissionService.RoleHasPermission(target.Role, models.PermissionUserImpersonate) ||
+		permissionService.RoleHasPermission(target.Role, models.PermissionRoleManage) {
+		return nil, errors.New("cannot impersonate another admin")
+	}
+
+	// The refresh token is never handed out, but the column must be unique and non-empty
+	unusedToken, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(config.Get().ImpersonationTTL)
+	session := &models.Session{
+		UserID:             adminID,
+		RefreshTokenHash:   hashToken(unusedToken),
+		ExpiresAt:          expiresAt,
+		LastUsedAt:         now,
+		UserAgent:          userAgent,
+		IPAddress:          ipAddress,
+		ImpersonatedUserID: &targetUserID,
+	}
+	if err := s.DB.Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	token, err := generateToken(&target, session.ID, expiresAt, &adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🎭 Admin %d started impersonating user %d (session %d)", adminID, targetUserID, session.ID)
+
+	return &ImpersonationToken{
+		AccessToken:        token,
+		ExpiresAt:          expiresAt,
+		ImpersonatorID:     adminID,
+		ImpersonatedUserID: targetUserID,
+	}, nil
+}
+
+// RecordRequest writes an audit entry for a request made while impersonating
+func (s *ImpersonationService) RecordRequest(sessionID, impersonatorID, impersonatedUserID uint, method, path string, statusCode int, ipAddress string) error {
+	log.Printf("🎭 Admin %d as user %d: %s %s -> %d", impersonatorID, impersonatedUserID, method, path, statusCode)
+
+	return s.DB.Create(&models.ImpersonationLog{
+		SessionID:          sessionID,
+		ImpersonatorID:     impersonatorID,
+		ImpersonatedUserID: impersonatedUserID,
+		Method:             method,
+		Path:               path,
+		StatusCode:         statusCode,
+		IPAddress:          ipAddress,
+	}).Error
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"project-x/config"
+	"project-x/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InvitationService struct {
+	DB       *gorm.DB
+	Notifier Notifier
+}
+
+func NewInvitationService(db *gorm.DB) *InvitationService {
+	return &InvitationService{DB: db, Notifier: GetNotifier()}
+}
+
+// CreateInvitation creates an invite and sends its token to the invitee's email address. The token
+// is never shown to the inviter. The actor must hold every permission of the role.
+func (s *InvitationService) CreateInvitation(actor *Actor, email, role string, departmentID uint) (*models.Invitation, error) {
+	address, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, errors.New("email is required")
+	}
+	if !NewUserService(s.DB).isValidRole(role) {
+		return nil, errors.New("invalid role")
+	}
+	if err := NewPermissionService(s.DB).AuthorizeRoleGrant(actor, models.Role(role)); err != nil {
+		return nil, err
+	}
+	if !NewDepartmentService(s.DB).Exists(departmentID) {
+		return nil, ErrDepartmentNotFound
+	}
+
+	token, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.Invitation{
+		Email:        *address,
+		TokenHash:    hashToken(token),
+		Role:         models.Role(role),
+		DepartmentID: &departmentID,
+		InvitedBy:    actor.UserID,
+		ExpiresAt:    time.Now().Add(config.Get().InvitationTTL),
+	}
+
+	// The invitation only exists once its token has been sent
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invitation).Error; err != nil {
+			return err
+		}
+		return s.sendInvitation(invitation, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// GetPendingInvitations returns invitations that have not been accepted, revoked or expired
+func (s *InvitationService) GetPendingInvitations() ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := s.DB.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Preload("Inviter").
+		Preload("Department").
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// ResendInvitation sends a new token for an open invitation to the invitee and restarts its expiry.
+// The previous token stops working. The actor must be able to grant the invitation's role.
+func (s *InvitationService) ResendInvitation(actor *Actor, invitationID uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := s.DB.First(&invitation, invitationID).Error; err != nil {
+		return nil, errors.New("invitation not found")
+	}
+	if err := NewPermissionService(s.DB).AuthorizeRoleGrant(actor, invitation.Role); err != nil {
+		return nil, err
+	}
+
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return nil, errors.New("invitation is no longer open")
+	}
+	if invitation.Email == "" {
+		return nil, errors.New("invitation has no email address to send to")
+	}
+
+	token, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.TokenHash = hashToken(token)
+	invitation.ExpiresAt = time.Now().Add(config.Get().InvitationTTL)
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&invitation).Error; err != nil {
+			return err
+		}
+		return s.sendInvitation(&invitation, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// sendInvitation sends an invitation token to the invitee
+func (s *InvitationService) sendInvitation(invitation *models.Invitation, token string) error {
+	cfg := config.Get()
+	body := "You have been invited to join. Use this token to create your account: " + token
+	if cfg.InvitationURL != "" {
+		body = "You have been invited to join. Create your account here: " + cfg.InvitationURL + token
+	}
+	body += "\nThe invitation expires on " + invitation.ExpiresAt.Format(time.RFC1123) + "."
+
+	return s.Notifier.Send(Notification{
+		Recipient: invitation.Email,
+		Subject:   "Invitation",
+		Body:      body,
+	})
+}
+
+// RevokeInvitation revokes an open invitation
+func (s *InvitationService) RevokeInvitation(invitationID uint) error {
+	result := s.DB.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", invitationID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("invitation not found or no longer open")
+	}
+
+	return nil
+}
+
+// AcceptInvitation creates the invited user with their chosen credentials and signs them in
+func (s *InvitationService) AcceptInvitation(token, username, password, userAgent, ipAddress string) (*models.User, *TokenPair, error) {
+	var user *models.User
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var invitation models.Invitation
+		if err := tx.Where("token_hash = ?", hashToken(token)).First(&invitation).Error; err != nil {
+			return errors.New("invalid invitation token")
+		}
+
+		if invitation.Status() != "pending" {
+			return errors.New("invitation is " + invitation.Status())
+		}
+
+		if invitation.DepartmentID == nil {
+			return errors.New("invitation has no department")
+		}
+
+		// The invitee joins the inviter's workspace, with a role the inviter can still grant
+		workspaceTx := InWorkspace(tx, invitation.WorkspaceID)
+		inviter, err := LoadActor(workspaceTx, invitation.InvitedBy)
+		if err != nil {
+			return errors.New("invitation is no longer valid")
+		}
+		user, err = NewUserService(workspaceTx).CreateUser(inviter, username, password, string(invitation.Role), *invitation.DepartmentID)
+		if err != nil {
+			return err
+		}
+
+		// Mark the invite used; the condition guards against two concurrent accepts
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{
+				"accepted_at":      time.Now(),
+				"accepted_user_id": user.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invitation has already been used")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := NewAuthService(s.DB).startSession(user, userAgent, ipAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"project-x/models"
+	"strings"
+	"testing"
+)
+
+// recordingNotifier keeps every notification it is asked to send
+type recordingNotifier struct {
+	sent []Notification
+}
+
+func (n *recordingNotifier) Send(notification Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func TestInvitationTokenGoesToInvitee(t *testing.T) {
+	db := testDB(t)
+	notifier := &recordingNotifier{}
+	service := &InvitationService{DB: db, Notifier: notifier}
+
+	department := models.Department{Name: "Invitation test department"}
+	if err := db.Create(&department).Error; err != nil {
+		t.Fatalf("create department: %v", err)
+	}
+
+	if _, err := service.CreateInvitation(testActors["admin"], "", string(models.RoleEmployee), department.ID); err == nil {
+		t.Error("an invitation without an email address should be refused")
+	}
+
+	invitation, err := service.CreateInvitation(testActors["admin"], "New.Hire@example.com", string(models.RoleEmployee), department.ID)
+	if err != nil {
+		t.Fatalf("create invitation: %v", err)
+	}
+	resent, err := service.ResendInvitation(testActors["admin"], invitation.ID)
+	if err != nil {
+		t.Fatalf("resend invitation: %v", err)
+	}
+
+	if len(notifier.sent) != 2 {
+		t.Fatalf("notifications: got %d, want one per send", len(notifier.sent))
+	}
+	for i, want := range []string{invitation.TokenHash, resent.TokenHash} {
+		notification := notifier.sent[i]
+		if notification.Recipient != "new.hire@example.com" || !containsToken(notification.Body, want) {
+			t.Errorf("notification %d: got %+v, want the current token sent to the invitee", i, notification)
+		}
+	}
+}
+
+// containsToken reports whether any word of a message is the token with the given hash
+func containsToken(body, tokenHash string) bool {
+	for _, word := range strings.Fields(body) {
+		if hashToken(word) == tokenHash {
+			return true
+		}
+	}
+	return false
+}
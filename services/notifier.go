@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"project-x/config"
+	"sync"
+	"time"
+)
+
+// Notification is a message addressed to a user
+type Notification struct {
+	Recipient string // Username or address of the recipient
+	Subject   string
+	Body      string
+}
+
+// Notifier delivers notifications to users. Implementations can send email,
+// chat messages, etc.; the log and file notifiers are meant for local testing.
+type Notifier interface {
+	Send(notification Notification) error
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct{}
+
+func (n *LogNotifier) Send(notification Notification) error {
+	log.Printf("📨 Notification to %s: %s\n%s", notification.Recipient, notification.Subject, notification.Body)
+	return nil
+}
+
+// FileNotifier appends notifications to a file
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *FileNotifier) Send(notification Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "[%s] To: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), notification.Recipient, notification.Subject, notification.Body)
+	return err
+}
+
+var (
+	notifier     Notifier
+	notifierOnce sync.Once
+)
+
+// GetNotifier returns the notifier selected in config
+func GetNotifier() Notifier {
+	notifierOnce.Do(func() {
+		cfg := config.Get()
+		switch cfg.Notifier {
+		case "file":
+			notifier = &FileNotifier{Path: cfg.NotifierFilePath}
+		default:
+			notifier = &LogNotifier{}
+		}
+	})
+	return notifier
+}
+
+// SetNotifier replaces the notifier, e.g. with a real delivery channel or a test double
+func SetNotifier(n Notifier) {
+	notifierOnce.Do(func() {})
+	notifier = n
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSuccessorsRequired is returned when a user still has open work in a project nobody was picked for
+var ErrSuccessorsRequired = errors.New("pick a successor for every project with open work")
+
+// OffboardingGroup is a user's open work in one project; ProjectID is nil for work outside projects
+type OffboardingGroup struct {
+	ProjectID          *uint
+	ProjectTitle       string
+	Tasks              []models.Task
+	CollaborativeTasks []models.CollaborativeTask
+}
+
+// Successor takes over a leaving user's open work in one project
+type Successor struct {
+	ProjectID *uint `json:"project_id"` // null for work outside projects
+	UserID    uint  `json:"user_id" binding:"required"`
+}
+
+type OffboardingService struct {
+	DB *gorm.DB
+}
+
+func NewOffboardingService(db *gorm.DB) *OffboardingService {
+	return &OffboardingService{DB: db}
+}
+
+// GetOffboardingPlan returns a user's open tasks and the collaborative tasks they lead, grouped
+// by project. Each group needs a successor before the user can be deactivated.
+func (s *OffboardingService) GetOffboardingPlan(userID uint) (*models.User, []OffboardingGroup, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+
+	var tasks []models.Task
+	if err := s.DB.Where("user_id = ? AND status IN ?", userID, openTaskStatuses).
+		Preload("Project").
+		Order("project_id, created_at").
+		Find(&tasks).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var collaborativeTasks []models.CollaborativeTask
+	if err := s.DB.Where("lead_user_id = ? AND status IN ?", userID, openTaskStatuses).
+		Preload("Project").
+		Order("project_id, created_at").
+		Find(&collaborativeTasks).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var groups []OffboardingGroup
+	groupFor := func(projectID *uint, project *models.Project) *OffboardingGroup {
+		for i := range groups {
+			if sameProject(groups[i].ProjectID, projectID) {
+				return &groups[i]
+			}
+		}
+		group := OffboardingGroup{ProjectID: projectID}
+		if project != nil {
+			group.ProjectTitle = project.Title
+		}
+		groups = append(groups, group)
+		return &groups[len(groups)-1]
+	}
+
+	for _, task := range tasks {
+		group := groupFor(task.ProjectID, task.Project)
+		group.Tasks = append(group.Tasks, task)
+	}
+	for _, task := range collaborativeTasks {
+		group := groupFor(task.ProjectID, task.Project)
+		group.CollaborativeTasks = append(group.CollaborativeTasks, task)
+	}
+
+	return &user, groups, nil
+}
+
+// DeactivateUser hands the user's open work to the successor picked for each project, then
+// deactivates the account. Finished work keeps the user as its historical owner. Their reports
+// move up to their manager, departments they head lose their head and every session and
+// access token is revoked. Users whose role outranks the actor's are refused.
+func (s *OffboardingService) DeactivateUser(actor *Actor, userID uint, successors []Successor) (*models.User, error) {
+	if userID == actor.UserID {
+		return nil, errors.New("cannot deactivate yourself")
+	}
+
+	user, groups, err := s.GetOffboardingPlan(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, user); err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrUserDeactivated
+	}
+
+	// Every group with open work needs a valid successor before anything changes
+	successorFor := make(map[int]uint, len(groups))
+	for i, group := range groups {
+		successor, found := findSuccessor(successors, group.ProjectID)
+		if !found {
+			return nil, ErrSuccessorsRequired
+		}
+		if err := s.validateSuccessor(userID, successor, group.ProjectID); err != nil {
+			return nil, err
+		}
+		successorFor[i] = successor
+	}
+
+	now := time.Now()
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		for i, group := range groups {
+			if err := handOverGroup(tx, userID, successorFor[i], group, now); err != nil {
+				return err
+			}
+		}
+
+		// Leave the remaining collaborative tasks they take part in
+		if err := tx.Model(&models.CollaborativeTaskParticipant{}).
+			Where("user_id = ? AND status = ?", userID, "active").
+			Update("status", "inactive").Error; err != nil {
+			return err
+		}
+
+		// Their reports move up to their manager
+		if err := tx.Model(&models.User{}).Where("manager_id = ?", userID).Update("manager_id", user.ManagerID).Error; err != nil {
+			return err
+		}
+
+		// Departments they head are left without a head
+		if err := tx.Model(&models.Department{}).Where("head_user_id = ?", userID).Update("head_user_id", nil).Error; err != nil {
+			return err
+		}
+
+		// Revoke all sessions and access tokens so they stop working immediately
+		if err := NewSessionService(tx).RevokeAllUserSessions(userID); err != nil {
+			return err
+		}
+		if err := NewPersonalAccessTokenService(tx).RevokeAllUserTokens(userID); err != nil {
+			return err
+		}
+
+		return tx.Model(user).Updates(map[string]interface{}{
+			"active":         false,
+			"deactivated_at": now,
+			"deactivated_by": actor.UserID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewUserService(s.DB).GetUserByID(userID)
+}
+
+// ReactivateUser lets a deactivated user sign in again. Work handed over during offboarding stays with the successors.
+// Users whose role outranks the actor's are refused.
+func (s *OffboardingService) ReactivateUser(actor *Actor, userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, &user); err != nil {
+		return nil, err
+	}
+	if user.Active {
+		return nil, errors.New("user is already active")
+	}
+
+	if err := s.DB.Model(&user).Updates(map[string]interface{}{
+		"active":                true,
+		"deactivated_at":        nil,
+		"deactivated_by":        nil,
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return NewUserService(s.DB).GetUserByID(userID)
+}
+
+// validateSuccessor checks that a successor can take over work in a project
+func (s *OffboardingService) validateSuccessor(userID, successorID uint, projectID *uint) error {
+	if successorID == userID {
+		return errors.New("a user cannot be their own successor")
+	}
+
+	var successor models.User
+	if err := s.DB.First(&successor, successorID).Error; err != nil {
+		return fmt.Errorf("successor %d not found", successorID)
+	}
+	if !successor.Active {
+		return fmt.Errorf("successor %s: %w", successor.Username, ErrUserDeactivated)
+	}
+
+	if projectID != nil {
+		var userProject models.UserProject
+		if err := s.DB.Where("user_id = ? AND project_id = ?", successorID, *projectID).First(&userProject).Error; err != nil {
+			return fmt.Errorf("successor %s is not a member of project %d", successor.Username, *projectID)
+		}
+	}
+	return nil
+}
+
+// handOverGroup moves one project's open tasks and collaborative task leads to the successor
+func handOverGroup(tx *gorm.DB, userID, successorID uint, group OffboardingGroup, now time.Time) error {
+	if len(group.Tasks) > 0 {
+		taskIDs := make([]uint, 0, len(group.Tasks))
+		for _, task := range group.Tasks {
+			taskIDs = append(taskIDs, task.ID)
+		}
+		if err := tx.Model(&models.Task{}).Where("id IN ?", taskIDs).
+			Updates(map[string]interface{}{"user_id": successorID, "assigned_at": now}).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, task := range group.CollaborativeTasks {
+		if err := tx.Model(&task).Update("lead_user_id", successorID).Error; err != nil {
+			return err
+		}
+
+		// The successor becomes the lead participant, joining the task if needed
+		var participant models.CollaborativeTaskParticipant
+		err := tx.Where("collaborative_task_id = ? AND user_id = ?", task.ID, successorID).First(&participant).Error
+		switch {
+		case err == nil:
+			if err := tx.Model(&participant).Updates(map[string]interface{}{"role": "lead", "status": "active"}).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&models.CollaborativeTaskParticipant{
+				CollaborativeTaskID: task.ID,
+				UserID:              successorID,
+				Role:                "lead",
+				Status:              "active",
+				AssignedAt:          now,
+			}).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// findSuccessor returns the successor picked for a project
+func findSuccessor(successors []Successor, projectID *uint) (uint, bool) {
+	for _, successor := range successors {
+		if sameProject(successor.ProjectID, projectID) {
+			return successor.UserID, true
+		}
+	}
+	return 0, false
+}
+
+// sameProject compares optional project IDs; nil matches nil
+func sameProject(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
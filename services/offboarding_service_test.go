@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"testing"
+	"time"
+)
+
+func TestFindSuccessor(t *testing.T) {
+	successors := []Successor{{ProjectID: nil, UserID: 7}, {ProjectID: uintPtr(1), UserID: 8}}
+
+	cases := []struct {
+		name      string
+		projectID *uint
+		want      uint
+		found     bool
+	}{
+		{"work outside projects", nil, 7, true},
+		{"project with a successor", uintPtr(1), 8, true},
+		{"project without a successor", uintPtr(2), 0, false},
+	}
+	for _, tc := range cases {
+		if got, found := findSuccessor(successors, tc.projectID); got != tc.want || found != tc.found {
+			t.Errorf("%s: got %d, %v, want %d, %v", tc.name, got, found, tc.want, tc.found)
+		}
+	}
+}
+
+func TestDeactivateUserRevokesAccess(t *testing.T) {
+	db := testDB(t)
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	manager := models.User{WorkspaceID: workspaceID, Username: "offboarding-manager", Password: "x", Role: models.RoleManager}
+	if err := db.Create(&manager).Error; err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	leaver := models.User{WorkspaceID: workspaceID, Username: "offboarding-leaver", Password: "x", Role: models.RoleEmployee, ManagerID: &manager.ID}
+	if err := db.Create(&leaver).Error; err != nil {
+		t.Fatalf("create leaver: %v", err)
+	}
+	report := models.User{WorkspaceID: workspaceID, Username: "offboarding-report", Password: "x", Role: models.RoleEmployee, ManagerID: &leaver.ID}
+	if err := db.Create(&report).Error; err != nil {
+		t.Fatalf("create report: %v", err)
+	}
+
+	session, _, err := NewSessionService(db).CreateSession(leaver.ID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	token := models.PersonalAccessToken{UserID: leaver.ID, Name: "ci", TokenHash: hashToken("offboarding-token"), TokenPrefix: "pxp_offboa", Scopes: "*:read"}
+	if err := db.Create(&token).Error; err != nil {
+		t.Fatalf("create access token: %v", err)
+	}
+	task := models.Task{WorkspaceID: workspaceID, Title: "Open work", Description: "-", UserID: leaver.ID, AssignedAt: time.Now()}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	offboardingService := NewOffboardingService(db)
+	actor := testActors["admin"]
+	if _, err := offboardingService.DeactivateUser(actor, leaver.ID, nil); !errors.Is(err, ErrSuccessorsRequired) {
+		t.Fatalf("deactivate with open work and no successor: got %v, want ErrSuccessorsRequired", err)
+	}
+	deactivated, err := offboardingService.DeactivateUser(actor, leaver.ID, []Successor{{UserID: manager.ID}})
+	if err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if deactivated.Active || deactivated.DeactivatedAt == nil {
+		t.Errorf("deactivated user: got %+v", deactivated)
+	}
+
+	if _, err := NewSessionService(db).GetActiveSession(session.ID); err == nil {
+		t.Error("the leaver's session should be revoked")
+	}
+	if _, err := NewPersonalAccessTokenService(db).Authenticate("offboarding-token"); err == nil {
+		t.Error("the leaver's access token should be revoked")
+	}
+
+	var taskAfter models.Task
+	db.First(&taskAfter, task.ID)
+	var reportAfter models.User
+	db.First(&reportAfter, report.ID)
+	if taskAfter.UserID != manager.ID || reportAfter.ManagerID == nil || *reportAfter.ManagerID != manager.ID {
+		t.Errorf("handover: task owner %d, report's manager %v, want both to be %d", taskAfter.UserID, reportAfter.ManagerID, manager.ID)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcProviderMetadata is the subset of the discovery document we use
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcProvider caches the identity provider's metadata and signing keys
+type oidcProvider struct {
+	mu         sync.Mutex
+	issuerURL  string
+	httpClient *http.Client
+	metadata   *oidcProviderMetadata
+	keys       map[string]interface{}
+	keysAt     time.Time
+}
+
+var (
+	oidcProviders   = make(map[string]*oidcProvider)
+	oidcProvidersMu sync.Mutex
+)
+
+// getOIDCProvider returns the cached provider for an issuer URL
+func getOIDCProvider(issuerURL string, httpClient *http.Client) *oidcProvider {
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+
+	provider, exists := oidcProviders[issuerURL]
+	if !exists {
+		provider = &oidcProvider{issuerURL: issuerURL, httpClient: httpClient}
+		oidcProviders[issuerURL] = provider
+	}
+	return provider
+}
+
+// Metadata fetches and caches the provider's discovery document
+func (p *oidcProvider) Metadata() (*oidcProviderMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var metadata oidcProviderMetadata
+	discoveryURL := strings.TrimSuffix(p.issuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(discoveryURL, &metadata); err != nil {
+		return nil, fmt.Errorf("fetching OIDC discovery document: %w", err)
+	}
+
+	if metadata.Issuer != p.issuerURL {
+		return nil, errors.New("OIDC discovery issuer does not match the configured issuer")
+	}
+
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience and expiry and returns its claims
+func (p *oidcProvider) VerifyIDToken(rawToken, clientID string) (jwt.MapClaims, error) {
+	metadata, err := p.Metadata()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(kid)
+	},
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	return claims, nil
+}
+
+// signingKey returns the provider key with the given kid, refetching the key set
+// when the kid is unknown (the provider may have rotated keys)
+func (p *oidcProvider) signingKey(kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, exists := p.keys[kid]; exists {
+		return key, nil
+	}
+
+	// Avoid hammering the provider with unknown kids
+	if time.Since(p.keysAt) < 10*time.Second && p.keys != nil {
+		return nil, errors.New("unknown ID token signing key")
+	}
+
+	var keySet struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(p.metadata.JWKSURI, &keySet); err != nil {
+		return nil, fmt.Errorf("fetching OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	p.keys = keys
+	p.keysAt = time.Now()
+
+	key, exists := keys[kid]
+	if !exists {
+		return nil, errors.New("unknown ID token signing key")
+	}
+	return key, nil
+}
+
+// getJSON performs a GET request and decodes a JSON response
+func (p *oidcProvider) getJSON(url string, target interface{}) error {
+	resp, err := p.httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
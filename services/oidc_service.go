@@ -0,0 +1,372 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"project-x/config"
+	"project-x/models"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const oidcAuthRequestTTL = 10 * time.Minute
+
+// ErrOIDCAccountExists is returned when a first SSO login would take the username of a
+// local account. Local accounts are only linked by verified email, and only when enabled.
+var ErrOIDCAccountExists = errors.New("an account with this username already exists; ask an administrator to link it")
+
+type OIDCService struct {
+	DB         *gorm.DB
+	Config     *config.Config
+	HTTPClient *http.Client
+}
+
+func NewOIDCService(db *gorm.DB) *OIDCService {
+	return &OIDCService{
+		DB:         db,
+		Config:     config.Get(),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether single sign-on is configured
+func (s *OIDCService) Enabled() bool {
+	return s.Config.OIDCIssuerURL != "" && s.Config.OIDCClientID != ""
+}
+
+// BeginLogin stores the state, nonce and PKCE verifier for a new login
+// and returns the identity provider URL to redirect the user to
+func (s *OIDCService) BeginLogin() (string, error) {
+	if !s.Enabled() {
+		return "", errors.New("single sign-on is not configured")
+	}
+
+	metadata, err := getOIDCProvider(s.Config.OIDCIssuerURL, s.HTTPClient).Metadata()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := generateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	// Logins that were never completed are dropped here, so unfinished requests cannot pile up
+	if err := s.DB.Unscoped().Where("expires_at < ?", time.Now()).Delete(&models.OIDCAuthRequest{}).Error; err != nil {
+		return "", err
+	}
+
+	authRequest := &models.OIDCAuthRequest{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcAuthRequestTTL),
+	}
+	if err := s.DB.Create(authRequest).Error; err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", s.Config.OIDCClientID)
+	params.Set("redirect_uri", s.Config.OIDCRedirectURL)
+	params.Set("scope", s.Config.OIDCScopes)
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", pkceChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// CompleteLogin exchanges the authorization code, verifies the ID token and provisions or links
+// the user. The rest of the login is the same as after a password: locked and deactivated accounts
+// are refused, and users with 2FA get a challenge instead of a session.
+func (s *OIDCService) CompleteLogin(state, code, userAgent, ipAddress string) (*LoginResult, error) {
+	if !s.Enabled() {
+		return nil, errors.New("single sign-on is not configured")
+	}
+
+	// State is single-use: delete it whether or not the rest succeeds
+	var authRequest models.OIDCAuthRequest
+	if err := s.DB.Where("state = ?", state).First(&authRequest).Error; err != nil {
+		return nil, errors.New("invalid or expired login state")
+	}
+	s.DB.Unscoped().Delete(&authRequest)
+
+	claims, err := s.verifyCallback(&authRequest, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.provisionUser(claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsLocked() {
+		return nil, ErrAccountLocked
+	}
+
+	return NewAuthService(s.DB).completeFirstFactor(user, userAgent, ipAddress)
+}
+
+// verifyCallback redeems the code for the login started by authRequest and returns the
+// verified ID token claims
+func (s *OIDCService) verifyCallback(authRequest *models.OIDCAuthRequest, code string) (jwt.MapClaims, error) {
+	if time.Now().After(authRequest.ExpiresAt) {
+		return nil, errors.New("invalid or expired login state")
+	}
+
+	provider := getOIDCProvider(s.Config.OIDCIssuerURL, s.HTTPClient)
+	rawIDToken, err := s.exchangeCode(provider, code, authRequest.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := provider.VerifyIDToken(rawIDToken, s.Config.OIDCClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if nonce, _ := claims["nonce"].(string); nonce != authRequest.Nonce {
+		return nil, errors.New("ID token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+// exchangeCode redeems an authorization code at the token endpoint and returns the raw ID token
+func (s *OIDCService) exchangeCode(provider *oidcProvider, code, codeVerifier string) (string, error) {
+	metadata, err := provider.Metadata()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", s.Config.OIDCRedirectURL)
+	form.Set("client_id", s.Config.OIDCClientID)
+	form.Set("code_verifier", codeVerifier)
+	if s.Config.OIDCClientSecret != "" {
+		form.Set("client_secret", s.Config.OIDCClientSecret)
+	}
+
+	resp, err := s.HTTPClient.PostForm(metadata.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("exchanging authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("token response did not include an ID token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// provisionUser finds the user linked to the ID token subject, links an existing account
+// with the same verified email when OIDCLinkByEmail is set, or creates a new one. A new
+// account never takes over a local username. Role and department are
+// re-synced from the identity provider on every login; a department claim that matches
+// no department leaves the current one in place.
+func (s *OIDCService) provisionUser(claims jwt.MapClaims) (*models.User, error) {
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	issuer := s.Config.OIDCIssuerURL
+	role := s.mapRole(claims)
+	department := s.mapDepartment(claims)
+
+	var user models.User
+	err := s.DB.Where("oidc_issuer = ? AND oidc_subject = ?", issuer, subject).First(&user).Error
+	if err == nil {
+		user.Role = role
+		if department != nil {
+			user.DepartmentID = department
+		}
+		if err := s.DB.Save(&user).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	// Link an existing local account only by an email address verified on both sides: the identity
+	// provider vouches for the claim, and the local address did not come from a profile edit
+	if email := oidcLinkEmail(claims, s.Config.OIDCLinkByEmail); email != "" {
+		if err := s.DB.Where("email = ? AND email_verified = ? AND oidc_subject IS NULL", email, true).First(&user).Error; err == nil {
+			user.OIDCIssuer = issuer
+			user.OIDCSubject = &subject
+			user.Role = role
+			if department != nil {
+				user.DepartmentID = department
+			}
+			if err := s.DB.Save(&user).Error; err != nil {
+				return nil, err
+			}
+			return &user, nil
+		}
+	}
+
+	username := oidcUsername(claims, subject)
+	if NewUserService(s.DB).usernameTaken(username) {
+		return nil, ErrOIDCAccountExists
+	}
+
+	// SSO users get an unusable random password; they sign in through the identity provider
+	randomPassword, err := generateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// New SSO users join the default workspace
+	workspaceID, err := NewWorkspaceService(s.DB).DefaultWorkspaceID()
+	if err != nil {
+		return nil, err
+	}
+
+	user = models.User{
+		WorkspaceID:  workspaceID,
+		Username:     username,
+		Password:     string(hashedPassword),
+		Role:         role,
+		DepartmentID: department,
+		OIDCIssuer:   issuer,
+		OIDCSubject:  &subject,
+	}
+	if err := s.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// mapRole maps the configured role claim to a role; the highest matching role wins.
+// Custom roles rank above employee and below head.
+func (s *OIDCService) mapRole(claims jwt.MapClaims) models.Role {
+	rank := func(role models.Role) int {
+		switch role {
+		case models.RoleEmployee:
+			return 1
+		case models.RoleHead:
+			return 3
+		case models.RoleManager:
+			return 4
+		case models.RoleAdmin:
+			return 5
+		}
+		return 2
+	}
+
+	permissionService := NewPermissionService(s.DB)
+	role := models.Role(s.Config.OIDCDefaultRole)
+	for _, value := range claimStrings(claims[s.Config.OIDCRoleClaim]) {
+		mapped, exists := s.Config.OIDCRoleMapping[value]
+		if exists && rank(models.Role(mapped)) > rank(role) && permissionService.RoleExists(mapped) {
+			role = models.Role(mapped)
+		}
+	}
+
+	if !permissionService.RoleExists(string(role)) {
+		return models.RoleEmployee
+	}
+	return role
+}
+
+// mapDepartment matches the configured department claim against department names, falling
+// back to the default department. Unknown names do not create departments.
+func (s *OIDCService) mapDepartment(claims jwt.MapClaims) *uint {
+	departmentService := NewDepartmentService(s.DB)
+	if values := claimStrings(claims[s.Config.OIDCDepartmentClaim]); len(values) > 0 && values[0] != "" {
+		if department, err := departmentService.FindByName(values[0]); err == nil {
+			return &department.ID
+		}
+	}
+	if department, err := departmentService.FindByName(s.Config.OIDCDefaultDept); err == nil {
+		return &department.ID
+	}
+	return nil
+}
+
+// oidcLinkEmail returns the email address to link a local account by: the email claim,
+// lower-cased, when linking is enabled and the provider marks the address verified
+func oidcLinkEmail(claims jwt.MapClaims, linkByEmail bool) string {
+	email, _ := claims["email"].(string)
+	if !linkByEmail || email == "" {
+		return ""
+	}
+	if verified, _ := claims["email_verified"].(bool); !verified {
+		return ""
+	}
+	return strings.ToLower(email)
+}
+
+// pkceChallenge derives the S256 code challenge sent with the authorization request
+func pkceChallenge(codeVerifier string) string {
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(challenge[:])
+}
+
+// oidcUsername picks a username from the standard profile claims
+func oidcUsername(claims jwt.MapClaims, subject string) string {
+	for _, claim := range []string{"preferred_username", "email"} {
+		if value, _ := claims[claim].(string); value != "" {
+			return value
+		}
+	}
+	return subject
+}
+
+// claimStrings normalizes a claim that may be a string or a list of strings
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"project-x/config"
+	"project-x/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const mockOIDCClientID = "project-x"
+
+// mockOIDCProvider is an identity provider serving discovery, a key set and a token endpoint
+// that checks the PKCE verifier of each code it issued
+type mockOIDCProvider struct {
+	server *httptest.Server
+	issuer string // Advertised in discovery
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]mockOIDCGrant // Unredeemed authorization codes
+}
+
+// mockOIDCGrant is a login the user completed at the provider
+type mockOIDCGrant struct {
+	challenge string
+	claims    jwt.MapClaims // Added to, or overriding, the standard ID token claims
+}
+
+// newMockOIDCProvider starts a provider; issuer overrides the advertised issuer when set
+func newMockOIDCProvider(t *testing.T, issuer string) *mockOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate provider key: %v", err)
+	}
+
+	p := &mockOIDCProvider{key: key, grants: make(map[string]mockOIDCGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	p.issuer = p.server.URL
+	if issuer != "" {
+		p.issuer = issuer
+	}
+	return p
+}
+
+// authorize records a completed login and returns its authorization code
+func (p *mockOIDCProvider) authorize(challenge string, claims jwt.MapClaims) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(len(p.grants) + 1)).Bytes())
+	p.grants[code] = mockOIDCGrant{challenge: challenge, claims: claims}
+	return code
+}
+
+func (p *mockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(oidcProviderMetadata{
+		Issuer:                p.issuer,
+		AuthorizationEndpoint: p.server.URL + "/authorize",
+		TokenEndpoint:         p.server.URL + "/token",
+		JWKSURI:               p.server.URL + "/jwks",
+	})
+}
+
+func (p *mockOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "mock-key",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+	}}})
+}
+
+func (p *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("client_id") != mockOIDCClientID {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Codes are single-use
+	p.mu.Lock()
+	grant, exists := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !exists || pkceChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss": p.issuer,
+		"aud": mockOIDCClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "mock-key"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "unused", "token_type": "Bearer", "id_token": idToken})
+}
+
+// newTestOIDCService returns a service configured to sign in through the provider
+func newTestOIDCService(p *mockOIDCProvider, db *gorm.DB, linkByEmail bool) *OIDCService {
+	return &OIDCService{
+		DB: db,
+		Config: &config.Config{
+			OIDCIssuerURL:       p.server.URL,
+			OIDCClientID:        mockOIDCClientID,
+			OIDCRedirectURL:     "https://app.example.com/sso/callback",
+			OIDCScopes:          "openid profile email",
+			OIDCRoleClaim:       "groups",
+			OIDCDefaultRole:     string(models.RoleEmployee),
+			OIDCDepartmentClaim: "department",
+			OIDCDefaultDept:     "unassigned",
+			OIDCLinkByEmail:     linkByEmail,
+		},
+		HTTPClient: p.server.Client(),
+	}
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	p := newMockOIDCProvider(t, "")
+	metadata, err := getOIDCProvider(p.server.URL, p.server.Client()).Metadata()
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+	if metadata.TokenEndpoint != p.server.URL+"/token" || metadata.JWKSURI != p.server.URL+"/jwks" {
+		t.Errorf("discovery: got %+v", metadata)
+	}
+
+	impostor := newMockOIDCProvider(t, "https://idp.example.com")
+	if _, err := getOIDCProvider(impostor.server.URL, impostor.server.Client()).Metadata(); err == nil {
+		t.Error("a discovery document for another issuer should be rejected")
+	}
+}
+
+func TestOIDCBeginLogin(t *testing.T) {
+	p := newMockOIDCProvider(t, "")
+	authURL, err := newTestOIDCService(p, dryRunDB(t), false).BeginLogin()
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil || !strings.HasPrefix(authURL, p.server.URL+"/authorize?") {
+		t.Fatalf("authorization URL: got %q", authURL)
+	}
+	params := parsed.Query()
+	for name, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             mockOIDCClientID,
+		"redirect_uri":          "https://app.example.com/sso/callback",
+		"code_challenge_method": "S256",
+	} {
+		if got := params.Get(name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+	if params.Get("state") == "" || params.Get("nonce") == "" || params.Get("state") == params.Get("nonce") {
+		t.Errorf("state and nonce should be distinct random values, got %q and %q", params.Get("state"), params.Get("nonce"))
+	}
+	if challenge, _ := base64.RawURLEncoding.DecodeString(params.Get("code_challenge")); len(challenge) != 32 {
+		t.Errorf("code_challenge should be a SHA-256 digest, got %q", params.Get("code_challenge"))
+	}
+}
+
+func TestOIDCVerifyCallback(t *testing.T) {
+	p := newMockOIDCProvider(t, "")
+	service := newTestOIDCService(p, dryRunDB(t), false)
+	login := func(nonce string) *models.OIDCAuthRequest {
+		return &models.OIDCAuthRequest{Nonce: nonce, CodeVerifier: "verifier-" + nonce, ExpiresAt: time.Now().Add(time.Minute)}
+	}
+
+	authRequest := login("n1")
+	code := p.authorize(pkceChallenge(authRequest.CodeVerifier), jwt.MapClaims{"sub": "alice", "nonce": "n1"})
+	claims, err := service.verifyCallback(authRequest, code)
+	if err != nil || claims["sub"] != "alice" {
+		t.Fatalf("valid callback: got %v, %v", claims, err)
+	}
+	if _, err := service.verifyCallback(authRequest, code); err == nil {
+		t.Error("a redeemed code should not be accepted again")
+	}
+
+	cases := []struct {
+		name        string
+		authRequest *models.OIDCAuthRequest
+		challenge   string
+		claims      jwt.MapClaims
+	}{
+		{"wrong PKCE verifier", login("n2"), pkceChallenge("another-verifier"), jwt.MapClaims{"sub": "alice", "nonce": "n2"}},
+		{"nonce from another login", login("n3"), pkceChallenge("verifier-n3"), jwt.MapClaims{"sub": "alice", "nonce": "n1"}},
+		{"token for another client", login("n4"), pkceChallenge("verifier-n4"), jwt.MapClaims{"sub": "alice", "nonce": "n4", "aud": "other-app"}},
+		{"expired token", login("n5"), pkceChallenge("verifier-n5"), jwt.MapClaims{"sub": "alice", "nonce": "n5", "exp": time.Now().Add(-time.Minute).Unix()}},
+		{"expired login state", &models.OIDCAuthRequest{Nonce: "n6", CodeVerifier: "verifier-n6", ExpiresAt: time.Now().Add(-time.Second)}, pkceChallenge("verifier-n6"), jwt.MapClaims{"sub": "alice", "nonce": "n6"}},
+	}
+	for _, tc := range cases {
+		code := p.authorize(tc.challenge, tc.claims)
+		if claims, err := service.verifyCallback(tc.authRequest, code); err == nil {
+			t.Errorf("%s: accepted with claims %v", tc.name, claims)
+		}
+	}
+}
+
+func TestOIDCLinkEmail(t *testing.T) {
+	cases := []struct {
+		name        string
+		claims      jwt.MapClaims
+		linkByEmail bool
+		want        string
+	}{
+		{"verified, linking enabled", jwt.MapClaims{"email": "Admin@Example.com", "email_verified": true}, true, "admin@example.com"},
+		{"verified, linking disabled", jwt.MapClaims{"email": "admin@example.com", "email_verified": true}, false, ""},
+		{"unverified", jwt.MapClaims{"email": "admin@example.com", "email_verified": false}, true, ""},
+		{"verification not stated", jwt.MapClaims{"email": "admin@example.com"}, true, ""},
+		{"verified as a string", jwt.MapClaims{"email": "admin@example.com", "email_verified": "true"}, true, ""},
+		{"no email", jwt.MapClaims{"email_verified": true}, true, ""},
+	}
+	for _, tc := range cases {
+		if got := oidcLinkEmail(tc.claims, tc.linkByEmail); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOIDCProvisionUser(t *testing.T) {
+	db := testDB(t)
+	p := newMockOIDCProvider(t, "")
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	email := "root@example.com"
+	admin := models.User{WorkspaceID: workspaceID, Username: "root", Password: "x", Role: models.RoleAdmin, Email: &email}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+
+	// An identity provider user named after a local account does not take it over
+	impostor := jwt.MapClaims{"sub": "idp-1", "preferred_username": "root", "email": email, "email_verified": false}
+	for _, linkByEmail := range []bool{false, true} {
+		if _, err := newTestOIDCService(p, db, linkByEmail).provisionUser(impostor); !errors.Is(err, ErrOIDCAccountExists) {
+			t.Errorf("unverified login as root (link by email %v): got %v, want ErrOIDCAccountExists", linkByEmail, err)
+		}
+	}
+	verified := jwt.MapClaims{"sub": "idp-1", "preferred_username": "root", "email": email, "email_verified": true}
+	if _, err := newTestOIDCService(p, db, false).provisionUser(verified); !errors.Is(err, ErrOIDCAccountExists) {
+		t.Errorf("verified login as root with linking disabled: got %v, want ErrOIDCAccountExists", err)
+	}
+
+	// An address the user typed into their own profile is not enough to take over an SSO login
+	if _, err := NewProfileService(db).UpdateProfile(admin.ID, ProfileUpdate{Email: &email}); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if _, err := newTestOIDCService(p, db, true).provisionUser(verified); !errors.Is(err, ErrOIDCAccountExists) {
+		t.Errorf("verified login to an unverified local address: got %v, want ErrOIDCAccountExists", err)
+	}
+
+	// A verified email links the account when linking is enabled, and the role follows the provider
+	if err := db.Model(&admin).Update("email_verified", true).Error; err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	linked, err := newTestOIDCService(p, db, true).provisionUser(verified)
+	if err != nil || linked.ID != admin.ID || linked.OIDCSubject == nil || *linked.OIDCSubject != "idp-1" {
+		t.Fatalf("verified login with linking enabled: got %+v, %v", linked, err)
+	}
+	if linked.Role != models.RoleEmployee {
+		t.Errorf("linked role: got %s, want the provider's %s", linked.Role, models.RoleEmployee)
+	}
+
+	// New identities get a new account in the default workspace, found by subject afterwards
+	created, err := newTestOIDCService(p, db, false).provisionUser(jwt.MapClaims{"sub": "idp-2", "preferred_username": "newcomer"})
+	if err != nil || created.Username != "newcomer" || created.WorkspaceID != workspaceID {
+		t.Fatalf("new identity: got %+v, %v", created, err)
+	}
+	again, err := newTestOIDCService(p, db, false).provisionUser(jwt.MapClaims{"sub": "idp-2", "preferred_username": "renamed"})
+	if err != nil || again.ID != created.ID {
+		t.Errorf("returning identity: got %+v, %v, want user %d", again, err, created.ID)
+	}
+}
+
+func TestOIDCCompleteLogin(t *testing.T) {
+	db := testDB(t)
+	p := newMockOIDCProvider(t, "")
+	service := newTestOIDCService(p, db, false)
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	subject := "idp-2fa"
+	user := models.User{WorkspaceID: workspaceID, Username: "sso-2fa", Password: "x", Role: models.RoleEmployee,
+		OIDCIssuer: p.server.URL, OIDCSubject: &subject, TwoFactorEnabled: true, TwoFactorSecret: "JBSWY3DPEHPK3PXP"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Starting a login drops requests that were never completed
+	stale := models.OIDCAuthRequest{State: "stale", Nonce: "stale", CodeVerifier: "stale", ExpiresAt: time.Now().Add(-time.Minute)}
+	if err := db.Create(&stale).Error; err != nil {
+		t.Fatalf("create stale request: %v", err)
+	}
+	authURL, err := service.BeginLogin()
+	if err != nil {
+		t.Fatalf("begin login: %v", err)
+	}
+	if err := db.Unscoped().First(&models.OIDCAuthRequest{}, stale.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("expired request after a new login: got %v, want it deleted", err)
+	}
+
+	// A user with 2FA gets a challenge from the identity provider login, not a session
+	parsed, _ := url.Parse(authURL)
+	var authRequest models.OIDCAuthRequest
+	if err := db.Where("state = ?", parsed.Query().Get("state")).First(&authRequest).Error; err != nil {
+		t.Fatalf("stored request: %v", err)
+	}
+	code := p.authorize(pkceChallenge(authRequest.CodeVerifier), jwt.MapClaims{"sub": subject, "nonce": authRequest.Nonce})
+	result, err := service.CompleteLogin(authRequest.State, code, "test", "127.0.0.1")
+	if err != nil || !result.TwoFactorRequired || result.ChallengeToken == "" || result.TokenPair != nil {
+		t.Errorf("SSO login with 2FA enabled: got %+v, %v, want a challenge", result, err)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"project-x/config"
+	"project-x/models"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrWeakPassword is wrapped by every password policy violation
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+//go:embed common_passwords.txt
+var builtinCommonPasswords string
+
+var (
+	blockedPasswords     map[string]bool
+	blockedPasswordsOnce sync.Once
+)
+
+// ValidatePassword checks a password against the configured policy and the offline
+// list of common or breached passwords
+func ValidatePassword(password, username string) error {
+	cfg := config.Get()
+
+	if len([]rune(password)) < cfg.PasswordMinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, cfg.PasswordMinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if cfg.PasswordRequireUpper && !hasUpper {
+		return fmt.Errorf("%w: must contain an uppercase letter", ErrWeakPassword)
+	}
+	if cfg.PasswordRequireLower && !hasLower {
+		return fmt.Errorf("%w: must contain a lowercase letter", ErrWeakPassword)
+	}
+	if cfg.PasswordRequireDigit && !hasDigit {
+		return fmt.Errorf("%w: must contain a digit", ErrWeakPassword)
+	}
+	if cfg.PasswordRequireSymbol && !hasSymbol {
+		return fmt.Errorf("%w: must contain a symbol", ErrWeakPassword)
+	}
+
+	lowered := strings.ToLower(password)
+	if username != "" && strings.Contains(lowered, strings.ToLower(username)) {
+		return fmt.Errorf("%w: must not contain the username", ErrWeakPassword)
+	}
+
+	if isBlockedPassword(lowered) {
+		return fmt.Errorf("%w: this password is too common or has appeared in a data breach", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// checkPasswordHistory rejects a password matching one of the user's recent passwords
+func checkPasswordHistory(db *gorm.DB, userID uint, password string) error {
+	historySize := config.Get().PasswordHistorySize
+	if historySize <= 0 {
+		return nil
+	}
+
+	var history []models.PasswordHistory
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Limit(historySize).Find(&history).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range history {
+		if bcrypt.CompareHashAndPassword([]byte(entry.PasswordHash), []byte(password)) == nil {
+			return fmt.Errorf("%w: must not match any of your last %d passwords", ErrWeakPassword, historySize)
+		}
+	}
+
+	return nil
+}
+
+// recordPasswordHistory stores a password hash and prunes entries beyond the history size
+func recordPasswordHistory(db *gorm.DB, userID uint, passwordHash string) error {
+	if err := db.Create(&models.PasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+		return err
+	}
+
+	historySize := config.Get().PasswordHistorySize
+	if historySize <= 0 {
+		historySize = 1
+	}
+
+	var keepIDs []uint
+	if err := db.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(historySize).
+		Pluck("id", &keepIDs).Error; err != nil {
+		return err
+	}
+
+	return db.Unscoped().Where("user_id = ? AND id NOT IN ?", userID, keepIDs).Delete(&models.PasswordHistory{}).Error
+}
+
+// isBlockedPassword checks the built-in list plus the optional configured blocklist file
+func isBlockedPassword(lowered string) bool {
+	blockedPasswordsOnce.Do(func() {
+		blockedPasswords = make(map[string]bool)
+		addBlockedPasswords(strings.NewReader(builtinCommonPasswords))
+
+		if path := config.Get().PasswordBlocklistFile; path != "" {
+			file, err := os.Open(path)
+			if err != nil {
+				log.Println("Failed to open password blocklist:", err)
+				return
+			}
+			defer file.Close()
+			addBlockedPasswords(file)
+		}
+	})
+
+	return blockedPasswords[lowered]
+}
+
+func addBlockedPasswords(source io.Reader) {
+	scanner := bufio.NewScanner(source)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blockedPasswords[strings.ToLower(line)] = true
+	}
+}
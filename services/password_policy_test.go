@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The cases assume the default policy: at least 10 characters with an uppercase letter,
+// a lowercase letter and a digit
+func TestValidatePassword(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		username string
+		valid    bool
+	}{
+		{"meets the policy", "Correct-Horse-42", "alice", true},
+		{"symbols are optional", "CorrectHorse42", "alice", true},
+		{"too short", "Short-1a", "alice", false},
+		{"length counts characters, not bytes", "Äpfelbäume1", "alice", true},
+		{"no uppercase letter", "correct-horse-42", "alice", false},
+		{"no lowercase letter", "CORRECT-HORSE-42", "alice", false},
+		{"no digit", "Correct-Horse-Battery", "alice", false},
+		{"contains the username", "Alice-Horse-42", "alice", false},
+		{"username check ignores case", "xxALICExx-42a", "Alice", false},
+		{"common password", "Password123", "alice", false},
+		{"common password in another case", "pASSWORD123", "alice", false},
+		{"breached password", "Summer2024", "alice", false},
+	}
+	for _, tc := range cases {
+		err := ValidatePassword(tc.password, tc.username)
+		if tc.valid && err != nil {
+			t.Errorf("%s: got %v, want valid", tc.name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrWeakPassword) {
+			t.Errorf("%s: got %v, want ErrWeakPassword", tc.name, err)
+		}
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	db := testDB(t)
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	user := models.User{WorkspaceID: workspaceID, Username: "history-user", Password: "x", Role: models.RoleEmployee}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// Seven passwords with the default history of five: the oldest two may be used again
+	passwords := []string{"History-Pass-1", "History-Pass-2", "History-Pass-3", "History-Pass-4", "History-Pass-5", "History-Pass-6", "History-Pass-7"}
+	for _, password := range passwords {
+		hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err := recordPasswordHistory(db, user.ID, string(hash)); err != nil {
+			t.Fatalf("record password history: %v", err)
+		}
+	}
+
+	var kept int64
+	db.Model(&models.PasswordHistory{}).Where("user_id = ?", user.ID).Count(&kept)
+	if kept != 5 {
+		t.Errorf("history entries: got %d, want 5", kept)
+	}
+	for i, password := range passwords {
+		err := checkPasswordHistory(db, user.ID, password)
+		if reused := i >= 2; reused != errors.Is(err, ErrWeakPassword) {
+			t.Errorf("%s: got %v, reuse blocked should be %v", password, err, reused)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"project-x/config"
+	"project-x/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetService struct {
+	DB       *gorm.DB
+	Notifier Notifier
+}
+
+func NewPasswordResetService(db *gorm.DB) *PasswordResetService {
+	return &PasswordResetService{DB: db, Notifier: GetNotifier()}
+}
+
+// RequestReset creates a reset token for the user and sends it through the notifier.
+// Unknown and deactivated usernames are ignored, and failures are logged rather than returned,
+// so callers cannot tell which accounts exist. The work happens in the background: only existing
+// accounts get a token written and sent, and the time that takes must not show in the response.
+func (s *PasswordResetService) RequestReset(username string) {
+	go s.sendResetToken(username)
+}
+
+// sendResetToken stores a new reset token for an active user and sends it to them
+func (s *PasswordResetService) sendResetToken(username string) {
+	var user models.User
+	if err := s.DB.Where("username = ? AND active = ?", username, true).First(&user).Error; err != nil {
+		return
+	}
+
+	token, err := generateRandomToken()
+	if err != nil {
+		log.Println("Failed to generate password reset token:", err)
+		return
+	}
+
+	cfg := config.Get()
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the newest token is valid
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(cfg.PasswordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		log.Println("Failed to store password reset token:", err)
+		return
+	}
+
+	body := "Use this token to reset your password: " + token
+	if cfg.PasswordResetURL != "" {
+		body = "Reset your password here: " + cfg.PasswordResetURL + token
+	}
+	body += "\nIt expires in " + cfg.PasswordResetTTL.String() + ". If you did not ask for a reset, you can ignore this message."
+
+	if err := s.Notifier.Send(Notification{
+		Recipient: user.Username,
+		Subject:   "Password reset",
+		Body:      body,
+	}); err != nil {
+		log.Println("Failed to send password reset notification:", err)
+	}
+}
+
+// ConfirmReset sets a new password using a reset token. All sessions and personal access tokens
+// of the user are revoked.
+func (s *PasswordResetService) ConfirmReset(token, newPassword string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Where("token_hash = ?", hashToken(token)).First(&resetToken).Error; err != nil {
+			return errors.New("invalid or expired reset token")
+		}
+
+		if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+			return errors.New("invalid or expired reset token")
+		}
+
+		// Mark used first; the condition guards against two concurrent confirmations
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("invalid or expired reset token")
+		}
+
+		if err := NewUserService(tx).UpdateUserPassword(resetToken.UserID, newPassword); err != nil {
+			return err
+		}
+
+		// Tokens minted by whoever had the account before the reset stop working too
+		if err := NewPersonalAccessTokenService(tx).RevokeAllUserTokens(resetToken.UserID); err != nil {
+			return err
+		}
+
+		// Proving control of the account also lifts any login lockout
+		var user models.User
+		if err := tx.First(&user, resetToken.UserID).Error; err != nil {
+			return err
+		}
+		_, err := NewUserService(tx).clearLockout(&user)
+		return err
+	})
+}
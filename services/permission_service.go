@@ -0,0 +1,383 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrRoleNotFound is returned when a role name does not exist
+var ErrRoleNotFound = errors.New("role not found")
+
+// roleNamePattern keeps custom role names URL and claim friendly
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// builtInRoles are created on startup and cannot be deleted
+var builtInRoles = []models.RoleDefinition{
+	{Name: models.RoleAdmin, Description: "Full access to everything"},
+	{Name: models.RoleManager, Description: "Manages projects, assigns work and views reports"},
+	{Name: models.RoleHead, Description: "Creates and manages tasks for their team"},
+	{Name: models.RoleEmployee, Description: "Works on assigned tasks"},
+}
+
+// permissionCatalog lists every permission and the built-in roles that get it by default.
+// Admins always hold every permission.
+var permissionCatalog = []struct {
+	Name         string
+	Description  string
+	DefaultRoles []models.Role
+}{
+	{models.PermissionTaskCreate, "Create tasks and collaborative tasks", []models.Role{models.RoleManager, models.RoleHead}},
+	{models.PermissionTaskAssign, "Create tasks for other users", []models.Role{models.RoleManager}},
+	{models.PermissionTaskDelete, "Delete tasks and collaborative tasks", []models.Role{models.RoleManager, models.RoleHead}},
+	{models.PermissionTaskReassign, "Reassign or force delete any task", nil},
+	{models.PermissionTaskUpdateAny, "Change the status of other users' tasks", []models.Role{models.RoleManager, models.RoleHead}},
+	{models.PermissionTaskReopen, "Reopen completed or cancelled tasks", []models.Role{models.RoleManager, models.RoleHead}},
+	{models.PermissionTaskBulkUpdate, "Update the status of many tasks at once", []models.Role{models.RoleManager}},
+	{models.PermissionTaskViewAll, "List tasks across users", []models.Role{models.RoleManager}},
+	{models.PermissionTaskViewDepartment, "List tasks by department", []models.Role{models.RoleManager}},
+	{models.PermissionCollaborativeParticipants, "Add and remove collaborative task participants", []models.Role{models.RoleManager, models.RoleHead}},
+	{models.PermissionProjectCreate, "Create projects", []models.Role{models.RoleManager}},
+	{models.PermissionProjectView, "View any project and its tasks", []models.Role{models.RoleManager}},
+	{models.PermissionProjectManageMembers, "Add and remove project members", []models.Role{models.RoleManager}},
+	{models.PermissionProjectUpdateStatus, "Change a project's status", []models.Role{models.RoleManager}},
+	{models.PermissionProjectDelete, "Delete projects", nil},
+	{models.PermissionReportView, "View task statistics and reports", []models.Role{models.RoleManager}},
+	{models.PermissionDepartmentAccessAll, "Assign, view and report across every department", nil},
+	{models.PermissionDepartmentManage, "Create, edit and delete departments", nil},
+	{models.PermissionUserManage, "Create, update, invite and delete users", nil},
+	{models.PermissionUserImpersonate, "Act as another user", nil},
+	{models.PermissionRoleManage, "Create roles and change role permissions", nil},
+	{models.PermissionWorkspaceManage, "Create workspaces and work inside any of them", nil},
+	{models.PermissionSCIMManage, "Create and revoke SCIM provisioning tokens", nil},
+}
+
+// projectRolePermissions are granted inside a project by the caller's UserProject.Role.
+// Every project role can view its project.
+var projectRolePermissions = map[string][]string{
+	models.ProjectRoleManager: {
+		models.PermissionProjectManageMembers,
+		models.PermissionProjectUpdateStatus,
+		models.PermissionTaskAssign,
+		models.PermissionTaskReassign,
+	},
+	models.ProjectRoleLead: {
+		models.PermissionProjectManageMembers,
+		models.PermissionTaskAssign,
+		models.PermissionTaskReassign,
+	},
+}
+
+// projectRoleRank orders project roles so members cannot hand out more than they hold
+var projectRoleRank = map[string]int{
+	models.ProjectRoleMember:  1,
+	models.ProjectRoleLead:    2,
+	models.ProjectRoleManager: 3,
+}
+
+type PermissionService struct {
+	DB *gorm.DB
+}
+
+func NewPermissionService(db *gorm.DB) *PermissionService {
+	return &PermissionService{DB: db}
+}
+
+// SyncPermissions creates missing built-in roles and catalog permissions. A permission is
+// granted to its default roles only when it is first added, so later admin changes stick.
+func (s *PermissionService) SyncPermissions() error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, builtIn := range builtInRoles {
+			role := models.RoleDefinition{Name: builtIn.Name}
+			if err := tx.Where(models.RoleDefinition{Name: builtIn.Name}).
+				Attrs(models.RoleDefinition{Description: builtIn.Description, BuiltIn: true}).
+				FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, entry := range permissionCatalog {
+			var existing models.Permission
+			err := tx.Where("name = ?", entry.Name).First(&existing).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			if err := tx.Create(&models.Permission{Name: entry.Name, Description: entry.Description}).Error; err != nil {
+				return err
+			}
+
+			for _, role := range append([]models.Role{models.RoleAdmin}, entry.DefaultRoles...) {
+				grant := models.RolePermission{RoleName: role, Permission: entry.Name}
+				if err := tx.Where(grant).FirstOrCreate(&grant).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetRolePermissions returns the permissions granted to a role
+func (s *PermissionService) GetRolePermissions(role models.Role) (models.PermissionSet, error) {
+	permissions := make(models.PermissionSet)
+
+	if role == models.RoleAdmin {
+		for _, entry := range permissionCatalog {
+			permissions[entry.Name] = true
+		}
+		return permissions, nil
+	}
+
+	var grants []models.RolePermission
+	if err := s.DB.Where("role_name = ?", role).Find(&grants).Error; err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		permissions[grant.Permission] = true
+	}
+
+	return permissions, nil
+}
+
+// RoleHasPermission reports whether a role holds a permission
+func (s *PermissionService) RoleHasPermission(role models.Role, permission string) bool {
+	permissions, err := s.GetRolePermissions(role)
+	if err != nil {
+		return false
+	}
+	return permissions.Has(permission)
+}
+
+// AuthorizeRoleGrant checks handing out a role, directly, through an invitation or through SCIM:
+// the actor must hold every permission of the role, so nobody can grant more than they have
+func (s *PermissionService) AuthorizeRoleGrant(actor *Actor, role models.Role) error {
+	permissions, err := s.GetRolePermissions(role)
+	if err != nil {
+		return err
+	}
+	if missing := missingPermissions(actor.Permissions, permissions); len(missing) > 0 {
+		return fmt.Errorf("%w: the %s role holds %s, which you do not have", ErrAccessDenied, role, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// AuthorizeUserAdministration checks changing another user's role or password. A user whose role
+// holds a permission the actor lacks outranks the actor and is off limits.
+func (s *PermissionService) AuthorizeUserAdministration(actor *Actor, target *models.User) error {
+	permissions, err := s.GetRolePermissions(target.Role)
+	if err != nil {
+		return err
+	}
+	if len(missingPermissions(actor.Permissions, permissions)) > 0 {
+		return fmt.Errorf("%w: %s has permissions you do not have", ErrAccessDenied, target.Username)
+	}
+	return nil
+}
+
+// authorizePermissionGrant rejects putting permissions the actor does not hold into a role
+func authorizePermissionGrant(actor *Actor, permissions []string) error {
+	requested := make(models.PermissionSet, len(permissions))
+	for _, permission := range permissions {
+		requested[permission] = true
+	}
+	if missing := missingPermissions(actor.Permissions, requested); len(missing) > 0 {
+		return fmt.Errorf("%w: you cannot grant %s, which you do not have", ErrAccessDenied, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingPermissions returns the permissions of a role that the holder lacks, sorted
+func missingPermissions(holder, role models.PermissionSet) []string {
+	var missing []string
+	for permission := range role {
+		if !holder.Has(permission) {
+			missing = append(missing, permission)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// ProjectRoleHasPermission reports whether a project role grants a permission inside its project
+func ProjectRoleHasPermission(projectRole, permission string) bool {
+	if permission == models.PermissionProjectView {
+		return true
+	}
+	for _, granted := range projectRolePermissions[projectRole] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectRoleOutranks reports whether one project role ranks at least as high as another.
+// Unknown roles from older data rank as plain members.
+func ProjectRoleOutranks(projectRole, other string) bool {
+	rank := func(role string) int {
+		if value, exists := projectRoleRank[role]; exists {
+			return value
+		}
+		return projectRoleRank[models.ProjectRoleMember]
+	}
+	return rank(projectRole) >= rank(other)
+}
+
+// ListPermissions returns the permission catalog
+func (s *PermissionService) ListPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := s.DB.Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ListRoles returns every role with its permissions
+func (s *PermissionService) ListRoles() ([]models.RoleDefinition, error) {
+	var roles []models.RoleDefinition
+	if err := s.DB.Preload("Permissions").Order("built_in DESC, name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetRole returns a role with its permissions
+func (s *PermissionService) GetRole(name string) (*models.RoleDefinition, error) {
+	var role models.RoleDefinition
+	if err := s.DB.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	return &role, nil
+}
+
+// RoleExists reports whether a role with the given name exists
+func (s *PermissionService) RoleExists(name string) bool {
+	var count int64
+	s.DB.Model(&models.RoleDefinition{}).Where("name = ?", name).Count(&count)
+	return count > 0
+}
+
+// CreateRole creates a custom role with the given permissions, all of which the actor must hold
+func (s *PermissionService) CreateRole(actor *Actor, name, description string, permissions []string) (*models.RoleDefinition, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New("role name must be 2-32 lowercase letters, digits, '-' or '_' and start with a letter")
+	}
+	if s.RoleExists(name) {
+		return nil, errors.New("role already exists")
+	}
+	if err := s.validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+	if err := authorizePermissionGrant(actor, permissions); err != nil {
+		return nil, err
+	}
+
+	role := &models.RoleDefinition{Name: models.Role(name), Description: description}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		return replaceRolePermissions(tx, role.Name, permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRole(name)
+}
+
+// SetRolePermissions replaces a role's permissions. The admin role cannot be changed, and the actor
+// must hold every permission the role has now and every one it is given.
+func (s *PermissionService) SetRolePermissions(actor *Actor, name string, permissions []string) (*models.RoleDefinition, error) {
+	role, err := s.GetRole(name)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == models.RoleAdmin {
+		return nil, errors.New("the admin role always has every permission")
+	}
+	if err := s.validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+	if err := s.AuthorizeRoleGrant(actor, role.Name); err != nil {
+		return nil, err
+	}
+	if err := authorizePermissionGrant(actor, permissions); err != nil {
+		return nil, err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRolePermissions(tx, role.Name, permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetRole(name)
+}
+
+// DeleteRole deletes a custom role that no user has
+func (s *PermissionService) DeleteRole(name string) error {
+	role, err := s.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return errors.New("built-in roles cannot be deleted")
+	}
+
+	var userCount int64
+	s.DB.Model(&models.User{}).Where("role = ?", role.Name).Count(&userCount)
+	if userCount > 0 {
+		return fmt.Errorf("role is assigned to %d user(s)", userCount)
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_name = ?", role.Name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+}
+
+// validatePermissions makes sure every permission is in the catalog
+func (s *PermissionService) validatePermissions(permissions []string) error {
+	for _, permission := range permissions {
+		var count int64
+		s.DB.Model(&models.Permission{}).Where("name = ?", permission).Count(&count)
+		if count == 0 {
+			return fmt.Errorf("unknown permission %q", permission)
+		}
+	}
+	return nil
+}
+
+// replaceRolePermissions swaps a role's grants for the given set
+func replaceRolePermissions(tx *gorm.DB, role models.Role, permissions []string) error {
+	if err := tx.Where("role_name = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, permission := range permissions {
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+
+		if err := tx.Create(&models.RolePermission{RoleName: role, Permission: permission}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PersonalAccessTokenPrefix marks bearer tokens that are personal access tokens rather than JWTs
+const PersonalAccessTokenPrefix = "pxp_"
+
+// Route groups a token can be scoped to; "*" covers all of them
+var tokenScopeGroups = []string{"*", "tasks", "collaborative-tasks", "projects", "users", "departments", "workspaces"}
+
+type PersonalAccessTokenService struct {
+	DB *gorm.DB
+}
+
+func NewPersonalAccessTokenService(db *gorm.DB) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{DB: db}
+}
+
+// CreateToken creates a token for the actor and returns it with its plaintext value, which is shown
+// only once. Tokens act with their owner's full authority, so nobody can create one for someone else.
+func (s *PersonalAccessTokenService) CreateToken(actor *Actor, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	var user models.User
+	if err := s.DB.First(&user, actor.UserID).Error; err != nil {
+		return nil, "", errors.New("user not found")
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !isValidTokenScope(scope) {
+			return nil, "", errors.New("invalid scope: " + scope)
+		}
+	}
+
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, "", errors.New("expiry must be in the future")
+	}
+
+	random, err := generateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := PersonalAccessTokenPrefix + random
+
+	token := &models.PersonalAccessToken{
+		UserID:      user.ID,
+		Name:        name,
+		TokenHash:   hashToken(plaintext),
+		TokenPrefix: plaintext[:len(PersonalAccessTokenPrefix)+6],
+		Scopes:      strings.Join(scopes, ","),
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := s.DB.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+
+	return token, plaintext, nil
+}
+
+// GetUserTokens returns all tokens of a user, newest first
+func (s *PersonalAccessTokenService) GetUserTokens(userID uint) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := s.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeToken revokes one of a user's tokens
+func (s *PersonalAccessTokenService) RevokeToken(userID, tokenID uint) error {
+	result := s.DB.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("token not found")
+	}
+
+	return nil
+}
+
+// RevokeAllUserTokens revokes every token of a user
+func (s *PersonalAccessTokenService) RevokeAllUserTokens(userID uint) error {
+	return s.DB.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Authenticate looks up an active token by its plaintext value and records its use
+func (s *PersonalAccessTokenService) Authenticate(plaintext string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	if err := s.DB.Where("token_hash = ?", hashToken(plaintext)).First(&token).Error; err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	if !token.IsActive() {
+		return nil, errors.New("token expired or revoked")
+	}
+
+	s.DB.Model(&token).Update("last_used_at", time.Now())
+
+	return &token, nil
+}
+
+// TokenAllows reports whether a token's scopes permit a request.
+// Scopes have the form "<group>:<access>" where access is "read" or "write" (write implies read).
+func TokenAllows(scopes []string, routeGroup string, readOnly bool) bool {
+	for _, scope := range scopes {
+		group, access, _ := strings.Cut(scope, ":")
+		if group != "*" && group != routeGroup {
+			continue
+		}
+		if access == "write" || (access == "read" && readOnly) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidTokenScope checks a scope against the known route groups and access levels
+func isValidTokenScope(scope string) bool {
+	group, access, found := strings.Cut(scope, ":")
+	if !found || (access != "read" && access != "write") {
+		return false
+	}
+
+	for _, validGroup := range tokenScopeGroups {
+		if group == validGroup {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"project-x/config"
+	"project-x/models"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxDisplayNameLength keeps names readable in lists and reports
+const maxDisplayNameLength = 100
+
+// avatarTypes are the accepted avatar image types and the extension each is stored with
+var avatarTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// localePattern accepts simple BCP 47 tags such as "en", "de-DE" or "pt-BR"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+var (
+	ErrAvatarTooLarge    = errors.New("avatar image is too large")
+	ErrAvatarUnsupported = errors.New("avatar must be a PNG, JPEG, GIF or WebP image")
+	ErrEmailTaken        = errors.New("email address is already in use")
+)
+
+// ProfileUpdate holds the profile fields to change; nil fields are left as they are
+type ProfileUpdate struct {
+	Email       *string `json:"email"` // "" removes the address
+	DisplayName *string `json:"display_name"`
+	Timezone    *string `json:"timezone"`
+	Locale      *string `json:"locale"`
+}
+
+type ProfileService struct {
+	DB      *gorm.DB
+	Storage Storage
+}
+
+func NewProfileService(db *gorm.DB) *ProfileService {
+	return &ProfileService{DB: db, Storage: GetAvatarStorage()}
+}
+
+// UpdateProfile validates and saves a user's own profile changes. A changed email address is
+// unverified until the identity provider supplies it.
+func (s *ProfileService) UpdateProfile(userID uint, update ProfileUpdate) (*models.User, error) {
+	return s.updateProfile(userID, update, false)
+}
+
+// updateProfile validates and saves profile changes; emailVerified records whether a changed
+// address came from the identity provider
+func (s *ProfileService) updateProfile(userID uint, update ProfileUpdate, emailVerified bool) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	changes := make(map[string]interface{})
+
+	if update.Email != nil {
+		email, err := normalizeEmail(*update.Email)
+		if err != nil {
+			return nil, err
+		}
+		if email != nil {
+			// Addresses are unique across workspaces
+			var count int64
+			AcrossWorkspaces(s.DB).Model(&models.User{}).Where("LOWER(email) = ? AND id <> ?", *email, userID).Count(&count)
+			if count > 0 {
+				return nil, ErrEmailTaken
+			}
+		}
+		if email == nil || user.Email == nil || *email != *user.Email {
+			changes["email"] = email
+			changes["email_verified"] = email != nil && emailVerified
+		} else if emailVerified && !user.EmailVerified {
+			changes["email_verified"] = true
+		}
+	}
+
+	if update.DisplayName != nil {
+		displayName := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			return nil, fmt.Errorf("display name must be at most %d characters", maxDisplayNameLength)
+		}
+		changes["display_name"] = displayName
+	}
+
+	if update.Timezone != nil {
+		if _, err := time.LoadLocation(*update.Timezone); err != nil || *update.Timezone == "" || *update.Timezone == "Local" {
+			return nil, errors.New("timezone must be an IANA name such as Europe/Berlin")
+		}
+		changes["timezone"] = *update.Timezone
+	}
+
+	if update.Locale != nil {
+		if !localePattern.MatchString(*update.Locale) {
+			return nil, errors.New("locale must be a language tag such as en or de-DE")
+		}
+		changes["locale"] = *update.Locale
+	}
+
+	if len(changes) > 0 {
+		if err := s.DB.Model(&user).Updates(changes).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return NewUserService(s.DB).GetUserByID(userID)
+}
+
+// SetAvatar stores a new avatar image and replaces the previous one. The type is detected
+// from the content, not from the file name or the client's content type.
+func (s *ProfileService) SetAvatar(userID uint, content io.Reader) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Read one byte past the limit to tell a file at the limit from a larger one
+	maxBytes := config.Get().AvatarMaxBytes
+	data, err := io.ReadAll(io.LimitReader(content, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	extension, ok := avatarTypes[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrAvatarUnsupported
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%d-%s%s", userID, hex.EncodeToString(suffix), extension)
+
+	if err := s.Storage.Save(key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&user).Update("avatar_key", key).Error; err != nil {
+		s.Storage.Delete(key)
+		return nil, err
+	}
+	s.deleteStoredAvatar(user.AvatarKey)
+
+	return NewUserService(s.DB).GetUserByID(userID)
+}
+
+// RemoveAvatar deletes the user's avatar
+func (s *ProfileService) RemoveAvatar(userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.AvatarKey != "" {
+		if err := s.DB.Model(&user).Update("avatar_key", "").Error; err != nil {
+			return nil, err
+		}
+		s.deleteStoredAvatar(user.AvatarKey)
+	}
+
+	return NewUserService(s.DB).GetUserByID(userID)
+}
+
+// deleteStoredAvatar removes a replaced avatar; a leftover file is only logged
+func (s *ProfileService) deleteStoredAvatar(key string) {
+	if key == "" {
+		return
+	}
+	if err := s.Storage.Delete(key); err != nil {
+		log.Printf("Failed to delete avatar %s: %v", key, err)
+	}
+}
+
+// normalizeEmail validates an address and lower-cases it; "" clears the address
+func normalizeEmail(email string) (*string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, nil
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return nil, errors.New("invalid email address")
+	}
+	normalized := strings.ToLower(address.Address)
+	return &normalized, nil
+}
@@ -8,6 +8,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrProjectNotFound is returned for a project that does not exist in the workspace
+var ErrProjectNotFound = errors.New("project not found")
+
 type ProjectService struct {
 	DB *gorm.DB
 }
@@ -35,7 +38,7 @@ func (s *ProjectService) CreateProject(title, description string, createdBy uint
 	userProject := &models.UserProject{
 		ProjectID: project.ID,
 		UserID:    createdBy,
-		Role:      "manager",
+		Role:      models.ProjectRoleManager,
 		JoinedAt:  time.Now(),
 	}
 
@@ -76,12 +79,31 @@ func (s *ProjectService) GetProjectMembers(projectID uint) ([]models.User, error
 	return users, err
 }
 
+// GetProjectMemberships returns the membership rows of a project with their users
+func (s *ProjectService) GetProjectMemberships(projectID uint) ([]models.UserProject, error) {
+	var memberships []models.UserProject
+	err := s.DB.Where("project_id = ?", projectID).
+		Preload("User.Department").
+		Order("joined_at").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+// GetProjectRole returns a user's role in a project
+func (s *ProjectService) GetProjectRole(userID, projectID uint) (string, error) {
+	var membership models.UserProject
+	if err := s.DB.Where("user_id = ? AND project_id = ?", userID, projectID).First(&membership).Error; err != nil {
+		return "", errors.New("user is not a member of this project")
+	}
+	return membership.Role, nil
+}
+
 // AddUserToProject adds a user to a project
-func (s *ProjectService) AddUserToProject(userID, projectID uint, role string) error {
+func (s *ProjectService) AddUserToProject(actor *Actor, userID, projectID uint, role string) error {
 	// Check if project exists
 	var project models.Project
 	if err := s.DB.First(&project, projectID).Error; err != nil {
-		return errors.New("project not found")
+		return ErrProjectNotFound
 	}
 
 	// Check if user exists
@@ -89,6 +111,9 @@ func (s *ProjectService) AddUserToProject(userID, projectID uint, role string) e
 	if err := s.DB.First(&user, userID).Error; err != nil {
 		return errors.New("user not found")
 	}
+	if !user.Active {
+		return ErrUserDeactivated
+	}
 
 	// Check if user is already in the project
 	var existingUserProject models.UserProject
@@ -97,7 +122,7 @@ func (s *ProjectService) AddUserToProject(userID, projectID uint, role string) e
 	}
 
 	// Validate role
-	validRoles := []string{"manager", "head", "employee"}
+	validRoles := []string{models.ProjectRoleManager, models.ProjectRoleLead, models.ProjectRoleMember}
 	validRole := false
 	for _, r := range validRoles {
 		if r == role {
@@ -109,6 +134,10 @@ func (s *ProjectService) AddUserToProject(userID, projectID uint, role string) e
 		return errors.New("invalid role")
 	}
 
+	if err := AuthorizeProjectMemberChange(actor, projectID, role); err != nil {
+		return err
+	}
+
 	userProject := &models.UserProject{
 		ProjectID: projectID,
 		UserID:    userID,
@@ -120,17 +149,25 @@ func (s *ProjectService) AddUserToProject(userID, projectID uint, role string) e
 }
 
 // RemoveUserFromProject removes a user from a project
-func (s *ProjectService) RemoveUserFromProject(userID, projectID uint) error {
+func (s *ProjectService) RemoveUserFromProject(actor *Actor, userID, projectID uint) error {
 	// Check if user is the project creator
 	var project models.Project
 	if err := s.DB.First(&project, projectID).Error; err != nil {
-		return errors.New("project not found")
+		return ErrProjectNotFound
 	}
 
 	if project.CreatedBy == userID {
 		return errors.New("cannot remove project creator")
 	}
 
+	memberRole, err := s.GetProjectRole(userID, projectID)
+	if err != nil {
+		return err
+	}
+	if err := AuthorizeProjectMemberChange(actor, projectID, memberRole); err != nil {
+		return err
+	}
+
 	// Remove user from project
 	result := s.DB.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&models.UserProject{})
 	if result.Error != nil {
@@ -145,12 +182,20 @@ func (s *ProjectService) RemoveUserFromProject(userID, projectID uint) error {
 }
 
 // UpdateProjectStatus updates the status of a project
-func (s *ProjectService) UpdateProjectStatus(projectID uint, status models.ProjectStatus) error {
+func (s *ProjectService) UpdateProjectStatus(actor *Actor, projectID uint, status models.ProjectStatus) error {
+	if err := AuthorizeProject(actor, projectID, models.PermissionProjectUpdateStatus); err != nil {
+		return err
+	}
+
 	return s.DB.Model(&models.Project{}).Where("id = ?", projectID).Update("status", status).Error
 }
 
 // DeleteProject deletes a project and all related data
-func (s *ProjectService) DeleteProject(projectID uint) error {
+func (s *ProjectService) DeleteProject(actor *Actor, projectID uint) error {
+	if err := AuthorizeProject(actor, projectID, models.PermissionProjectDelete); err != nil {
+		return err
+	}
+
 	// Start a transaction
 	tx := s.DB.Begin()
 
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scimFilter is a parsed SCIM filter (RFC 7644 section 3.4.2.2), e.g.
+// `userName eq "alice" and active eq true`. Value paths such as emails[type eq "work"] are not supported.
+type scimFilter struct {
+	op          string      // "and", "or", "not" or a comparison operator such as "eq" or "pr"
+	left, right *scimFilter // Operands of "and" and "or"; "not" uses left
+	attr        string      // Lower-cased attribute path of a comparison
+	value       interface{} // string, bool, float64 or nil
+}
+
+// scimAttrKind decides how a filtered attribute is compared
+type scimAttrKind int
+
+const (
+	scimString          scimAttrKind = iota // Compared ignoring case
+	scimCaseExactString                     // Compared as is
+	scimBoolean
+	scimNumericID // SCIM ids are strings but stored as numeric primary keys
+	scimDateTime
+)
+
+// scimColumn maps a filterable SCIM attribute to a database column
+type scimColumn struct {
+	name string
+	kind scimAttrKind
+}
+
+var scimComparisonOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// parseSCIMFilter parses a filter; "and" binds tighter than "or"
+func parseSCIMFilter(filter string) (*scimFilter, error) {
+	tokens, err := scanSCIMFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty filter", ErrSCIMInvalidFilter)
+	}
+
+	p := &scimFilterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSCIMInvalidFilter, p.tokens[p.pos].text)
+	}
+	return expr, nil
+}
+
+// scimToken is a word, a quoted string or a parenthesis of a filter
+type scimToken struct {
+	text   string
+	quoted bool
+}
+
+func scanSCIMFilter(filter string) ([]scimToken, error) {
+	var tokens []scimToken
+	for i := 0; i < len(filter); {
+		switch ch := filter[i]; {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, scimToken{text: string(ch)})
+			i++
+		case ch == '"':
+			// Strings use JSON escaping
+			end := i + 1
+			for end < len(filter) && filter[end] != '"' {
+				if filter[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(filter) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrSCIMInvalidFilter)
+			}
+			var text string
+			if err := json.Unmarshal([]byte(filter[i:end+1]), &text); err != nil {
+				return nil, fmt.Errorf("%w: invalid string %s", ErrSCIMInvalidFilter, filter[i:end+1])
+			}
+			tokens = append(tokens, scimToken{text: text, quoted: true})
+			i = end + 1
+		default:
+			end := i
+			for end < len(filter) && !strings.ContainsRune(" \t()\"", rune(filter[end])) {
+				end++
+			}
+			tokens = append(tokens, scimToken{text: filter[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type scimFilterParser struct {
+	tokens []scimToken
+	pos    int
+}
+
+// keyword consumes the next token if it is the given unquoted keyword
+func (p *scimFilterParser) keyword(word string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *scimFilterParser) next() (scimToken, error) {
+	if p.pos >= len(p.tokens) {
+		return scimToken{}, fmt.Errorf("%w: unexpected end of filter", ErrSCIMInvalidFilter)
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token, nil
+}
+
+func (p *scimFilterParser) parseOr() (*scimFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimFilter{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseAnd() (*scimFilter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimFilter{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseUnary() (*scimFilter, error) {
+	negate := p.keyword("not")
+	if p.keyword("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("%w: missing )", ErrSCIMInvalidFilter)
+		}
+		if negate {
+			return &scimFilter{op: "not", left: expr}, nil
+		}
+		return expr, nil
+	}
+	if negate {
+		return nil, fmt.Errorf("%w: not must be followed by (", ErrSCIMInvalidFilter)
+	}
+	return p.parseComparison()
+}
+
+func (p *scimFilterParser) parseComparison() (*scimFilter, error) {
+	attr, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if attr.quoted || attr.text == "(" || attr.text == ")" {
+		return nil, fmt.Errorf("%w: expected an attribute, got %q", ErrSCIMInvalidFilter, attr.text)
+	}
+
+	opToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(opToken.text)
+	if opToken.quoted || (op != "pr" && !scimComparisonOps[op]) {
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrSCIMInvalidFilter, opToken.text)
+	}
+
+	filter := &scimFilter{op: op, attr: normalizeSCIMPath(attr.text)}
+	if op == "pr" {
+		return filter, nil
+	}
+
+	valueToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case valueToken.quoted:
+		filter.value = valueToken.text
+	case strings.EqualFold(valueToken.text, "true"):
+		filter.value = true
+	case strings.EqualFold(valueToken.text, "false"):
+		filter.value = false
+	case strings.EqualFold(valueToken.text, "null"):
+		filter.value = nil
+	default:
+		number, err := strconv.ParseFloat(valueToken.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value %q", ErrSCIMInvalidFilter, valueToken.text)
+		}
+		filter.value = number
+	}
+	return filter, nil
+}
+
+// sql translates the filter into a WHERE condition over the given columns
+func (f *scimFilter) sql(columns map[string]scimColumn) (string, []interface{}, error) {
+	switch f.op {
+	case "and", "or":
+		left, leftArgs, err := f.left.sql(columns)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := f.right.sql(columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + left + " " + strings.ToUpper(f.op) + " " + right + ")", append(leftArgs, rightArgs...), nil
+	case "not":
+		inner, args, err := f.left.sql(columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + inner + ")", args, nil
+	}
+
+	column, ok := columns[f.attr]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: cannot filter on %s", ErrSCIMInvalidFilter, f.attr)
+	}
+
+	if f.op == "pr" {
+		if column.kind == scimString || column.kind == scimCaseExactString {
+			return "(" + column.name + " IS NOT NULL AND " + column.name + " <> '')", nil, nil
+		}
+		return column.name + " IS NOT NULL", nil, nil
+	}
+
+	switch column.kind {
+	case scimString, scimCaseExactString:
+		value, ok := f.value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: %s needs a string", ErrSCIMInvalidFilter, f.attr)
+		}
+		name := column.name
+		if column.kind == scimString {
+			name = "LOWER(" + name + ")"
+			value = strings.ToLower(value)
+		}
+		switch f.op {
+		case "co", "sw", "ew":
+			pattern := escapeLike(value)
+			if f.op != "sw" {
+				pattern = "%" + pattern
+			}
+			if f.op != "ew" {
+				pattern += "%"
+			}
+			return name + ` LIKE ? ESCAPE '\'`, []interface{}{pattern}, nil
+		case "ne":
+			return "(" + column.name + " IS NULL OR " + name + " <> ?)", []interface{}{value}, nil
+		default:
+			return name + " " + sqlComparison[f.op] + " ?", []interface{}{value}, nil
+		}
+
+	case scimBoolean:
+		value, ok := f.value.(bool)
+		if !ok || (f.op != "eq" && f.op != "ne") {
+			return "", nil, fmt.Errorf("%w: %s only supports eq and ne with true or false", ErrSCIMInvalidFilter, f.attr)
+		}
+		return column.name + " " + sqlComparison[f.op] + " ?", []interface{}{value}, nil
+
+	case scimNumericID:
+		if f.op != "eq" && f.op != "ne" {
+			return "", nil, fmt.Errorf("%w: %s only supports eq and ne", ErrSCIMInvalidFilter, f.attr)
+		}
+		id, ok := parseSCIMID(f.value)
+		if !ok {
+			// An id that cannot exist matches nothing, or everything when negated
+			if f.op == "eq" {
+				return "1 = 0", nil, nil
+			}
+			return "1 = 1", nil, nil
+		}
+		return column.name + " " + sqlComparison[f.op] + " ?", []interface{}{id}, nil
+
+	case scimDateTime:
+		text, _ := f.value.(string)
+		value, err := time.Parse(time.RFC3339, text)
+		if err != nil || f.op == "co" || f.op == "sw" || f.op == "ew" {
+			return "", nil, fmt.Errorf("%w: %s needs an RFC 3339 date and a comparison operator", ErrSCIMInvalidFilter, f.attr)
+		}
+		return column.name + " " + sqlComparison[f.op] + " ?", []interface{}{value}, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: cannot filter on %s", ErrSCIMInvalidFilter, f.attr)
+}
+
+// matches evaluates the filter in memory. values returns an attribute's values and whether it
+// is compared case-sensitively; ok is false for unknown attributes. Only string values are supported.
+func (f *scimFilter) matches(values func(attr string) (vals []string, caseExact bool, ok bool)) (bool, error) {
+	switch f.op {
+	case "and", "or":
+		left, err := f.left.matches(values)
+		if err != nil {
+			return false, err
+		}
+		right, err := f.right.matches(values)
+		if err != nil {
+			return false, err
+		}
+		if f.op == "and" {
+			return left && right, nil
+		}
+		return left || right, nil
+	case "not":
+		inner, err := f.left.matches(values)
+		return !inner, err
+	}
+
+	vals, caseExact, ok := values(f.attr)
+	if !ok {
+		return false, fmt.Errorf("%w: cannot filter on %s", ErrSCIMInvalidFilter, f.attr)
+	}
+	if f.op == "pr" {
+		for _, val := range vals {
+			if val != "" {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	expected, ok := f.value.(string)
+	if !ok {
+		return false, fmt.Errorf("%w: %s needs a string", ErrSCIMInvalidFilter, f.attr)
+	}
+
+	// A multi-valued attribute matches when any of its values does; ne needs all of them to differ
+	if f.op == "ne" {
+		for _, val := range vals {
+			if compareSCIMString("eq", val, expected, caseExact) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	for _, val := range vals {
+		if compareSCIMString(f.op, val, expected, caseExact) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+var sqlComparison = map[string]string{
+	"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+}
+
+func compareSCIMString(op, actual, expected string, caseExact bool) bool {
+	if !caseExact {
+		actual, expected = strings.ToLower(actual), strings.ToLower(expected)
+	}
+	switch op {
+	case "eq":
+		return actual == expected
+	case "co":
+		return strings.Contains(actual, expected)
+	case "sw":
+		return strings.HasPrefix(actual, expected)
+	case "ew":
+		return strings.HasSuffix(actual, expected)
+	case "gt":
+		return actual > expected
+	case "ge":
+		return actual >= expected
+	case "lt":
+		return actual < expected
+	case "le":
+		return actual <= expected
+	}
+	return false
+}
+
+// parseSCIMID reads a numeric resource id from a filter value, which clients send as a string
+func parseSCIMID(value interface{}) (uint, bool) {
+	switch v := value.(type) {
+	case string:
+		id, err := strconv.ParseUint(v, 10, 32)
+		return uint(id), err == nil && id > 0
+	case float64:
+		return uint(v), v > 0 && v == float64(uint32(v))
+	}
+	return 0, false
+}
+
+// escapeLike escapes the LIKE wildcards in a value matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
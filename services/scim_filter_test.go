@@ -0,0 +1,165 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSCIMFilterSQL(t *testing.T) {
+	tests := []struct {
+		filter string
+		sql    string
+		args   []interface{}
+	}{
+		{`userName eq "Alice"`, "LOWER(username) = ?", []interface{}{"alice"}},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, "LOWER(username) = ?", []interface{}{"alice"}},
+		{`externalId eq "00u1AbC"`, "external_id = ?", []interface{}{"00u1AbC"}},
+		{`emails.value co "50%_off"`, `LOWER(email) LIKE ? ESCAPE '\'`, []interface{}{`%50\%\_off%`}},
+		{`displayName sw "Al"`, `LOWER(display_name) LIKE ? ESCAPE '\'`, []interface{}{"al%"}},
+		{`active eq false`, "active = ?", []interface{}{false}},
+		{`id eq "42"`, "id = ?", []interface{}{uint(42)}},
+		{`id eq "not-a-user"`, "1 = 0", nil},
+		{`emails pr`, "(email IS NOT NULL AND email <> '')", nil},
+		{
+			`userName eq "a" or userName eq "b" and active eq true`,
+			"(LOWER(username) = ? OR (LOWER(username) = ? AND active = ?))",
+			[]interface{}{"a", "b", true},
+		},
+		{
+			`not (active eq true) and (externalId pr)`,
+			"(NOT (active = ?) AND (external_id IS NOT NULL AND external_id <> ''))",
+			[]interface{}{true},
+		},
+	}
+
+	for _, tt := range tests {
+		parsed, err := parseSCIMFilter(tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		sql, args, err := parsed.sql(scimUserColumns)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		if sql != tt.sql || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: got %s %v, want %s %v", tt.filter, sql, args, tt.sql, tt.args)
+		}
+	}
+}
+
+func TestSCIMFilterRejectsInvalidFilters(t *testing.T) {
+	filters := []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName like "a"`,
+		`userName eq "unterminated`,
+		`(userName eq "a"`,
+		`userName eq "a" extra`,
+		`password eq "secret"`,
+		`active co true`,
+		`meta.created gt "yesterday"`,
+	}
+
+	for _, filter := range filters {
+		parsed, err := parseSCIMFilter(filter)
+		if err == nil {
+			_, _, err = parsed.sql(scimUserColumns)
+		}
+		if !errors.Is(err, ErrSCIMInvalidFilter) {
+			t.Errorf("%q: got %v, want an invalid filter error", filter, err)
+		}
+	}
+}
+
+func TestSCIMFilterMatchesGroups(t *testing.T) {
+	group := scimGroup{id: "department-3", displayName: "Engineering"}
+	values := group.filterValues([]scimMember{{ID: 7}, {ID: 9}})
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`displayName eq "engineering"`, true},
+		{`id eq "Department-3"`, false},
+		{`members.value eq "9"`, true},
+		{`members eq "8"`, false},
+		{`displayName sw "Eng" and not (members eq "7")`, false},
+		{`externalId pr or members pr`, true},
+	}
+
+	for _, tt := range tests {
+		parsed, err := parseSCIMFilter(tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		got, err := parsed.matches(values)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestSCIMUserPatch(t *testing.T) {
+	active := SCIMBool(true)
+	resource := SCIMUserResource{
+		UserName:   "alice",
+		Emails:     []SCIMMultiValue{{Value: "alice@example.com", Primary: true}},
+		Active:     &active,
+		Enterprise: &SCIMEnterpriseUser{Department: "Engineering"},
+	}
+
+	// Operations as sent by common identity providers: capitalized ops, string booleans,
+	// value filters on emails, bare manager ids and path-less values
+	var operations []SCIMPatchOperation
+	body := `[
+		{"op": "Replace", "path": "active", "value": "False"},
+		{"op": "Add", "path": "emails[type eq \"work\"].value", "value": "alice@corp.example"},
+		{"op": "Replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager", "value": "12"},
+		{"op": "replace", "value": {"displayName": "Alice Smith", "name": {"givenName": "Alice"}, "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "Sales"}}},
+		{"op": "remove", "path": "phoneNumbers[type eq \"work\"]"}
+	]`
+	if err := json.Unmarshal([]byte(body), &operations); err != nil {
+		t.Fatalf("decode operations: %v", err)
+	}
+	for _, operation := range operations {
+		if err := resource.applyPatch(operation); err != nil {
+			t.Fatalf("apply %s %s: %v", operation.Op, operation.Path, err)
+		}
+	}
+
+	if resource.Active == nil || bool(*resource.Active) {
+		t.Error("user is still active")
+	}
+	if email := resource.primaryEmail(); email != "alice@corp.example" {
+		t.Errorf("email is %q", email)
+	}
+	if resource.Enterprise.Manager == nil || resource.Enterprise.Manager.Value != "12" {
+		t.Errorf("manager is %+v", resource.Enterprise.Manager)
+	}
+	if resource.displayName() != "Alice Smith" || resource.Enterprise.Department != "Sales" {
+		t.Errorf("display name %q, department %q", resource.displayName(), resource.Enterprise.Department)
+	}
+
+	// Required and read-only attributes cannot be removed or set
+	for _, operation := range []SCIMPatchOperation{
+		{Op: "remove", Path: "userName"},
+		{Op: "replace", Path: "id", Value: json.RawMessage(`"7"`)},
+	} {
+		if err := resource.applyPatch(operation); !errors.Is(err, ErrSCIMMutability) {
+			t.Errorf("%s %s: got %v, want a mutability error", operation.Op, operation.Path, err)
+		}
+	}
+	if err := resource.applyPatch(SCIMPatchOperation{Op: "move", Path: "active"}); !errors.Is(err, ErrSCIMInvalidSyntax) {
+		t.Errorf("unknown op: got %v", err)
+	}
+}
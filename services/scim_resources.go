@@ -0,0 +1,407 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"project-x/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SCIM schema and message URNs (RFC 7643, RFC 7644)
+const (
+	SCIMUserSchema           = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMEnterpriseUserSchema = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SCIMGroupSchema          = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMListResponseSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMPatchOpSchema        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMErrorSchema          = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// SCIMBasePath is where the SCIM endpoints are served; resource locations are built from it
+const SCIMBasePath = "/scim/v2"
+
+// SCIMMeta describes a resource
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// SCIMMultiValue is an entry of a multi-valued attribute such as emails, groups or members
+type SCIMMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// SCIMName is a user's name. Only the formatted name is stored, as the display name.
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEnterpriseUser is the enterprise extension; only the department and manager are stored
+type SCIMEnterpriseUser struct {
+	Department string       `json:"department,omitempty"` // Department name
+	Manager    *SCIMManager `json:"manager,omitempty"`
+}
+
+// SCIMManager references the user's manager by id
+type SCIMManager struct {
+	Value       string `json:"value"`
+	Ref         string `json:"$ref,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// UnmarshalJSON also accepts a bare id, which some identity providers send in PATCH requests
+func (m *SCIMManager) UnmarshalJSON(data []byte) error {
+	var id string
+	if err := json.Unmarshal(data, &id); err == nil {
+		*m = SCIMManager{Value: id}
+		return nil
+	}
+	type plain SCIMManager
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// SCIMBool accepts JSON booleans and the "True" and "False" strings some identity providers send
+type SCIMBool bool
+
+func (b *SCIMBool) UnmarshalJSON(data []byte) error {
+	var value bool
+	if err := json.Unmarshal(data, &value); err == nil {
+		*b = SCIMBool(value)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	value, err := strconv.ParseBool(strings.ToLower(text))
+	if err != nil {
+		return err
+	}
+	*b = SCIMBool(value)
+	return nil
+}
+
+// SCIMUserResource is a SCIM user as sent by clients and returned by the API
+type SCIMUserResource struct {
+	Schemas     []string            `json:"schemas"`
+	ID          string              `json:"id,omitempty"`
+	ExternalID  string              `json:"externalId,omitempty"`
+	UserName    string              `json:"userName"`
+	Name        *SCIMName           `json:"name,omitempty"`
+	DisplayName string              `json:"displayName,omitempty"`
+	Emails      []SCIMMultiValue    `json:"emails,omitempty"`
+	Active      *SCIMBool           `json:"active,omitempty"` // Defaults to true when creating
+	Timezone    string              `json:"timezone,omitempty"`
+	Locale      string              `json:"locale,omitempty"`
+	Password    string              `json:"password,omitempty"` // Write-only, never returned
+	Groups      []SCIMMultiValue    `json:"groups,omitempty"`   // Read-only: the user's role and department
+	Enterprise  *SCIMEnterpriseUser `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta        *SCIMMeta           `json:"meta,omitempty"`
+}
+
+// SCIMGroupResource is a role or department as a SCIM group
+type SCIMGroupResource struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id"`
+	DisplayName string           `json:"displayName"`
+	Members     []SCIMMultiValue `json:"members,omitempty"`
+	Meta        *SCIMMeta        `json:"meta,omitempty"`
+}
+
+// SCIMPatchOperation is one operation of a PATCH request
+type SCIMPatchOperation struct {
+	Op    string          `json:"op" binding:"required"` // add, replace or remove
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// NewSCIMUserResource formats a user, with their department preloaded, as a SCIM user
+func NewSCIMUserResource(user *models.User) SCIMUserResource {
+	active := SCIMBool(user.Active)
+	resource := SCIMUserResource{
+		Schemas:     []string{SCIMUserSchema, SCIMEnterpriseUserSchema},
+		ID:          strconv.FormatUint(uint64(user.ID), 10),
+		ExternalID:  user.ExternalID,
+		UserName:    user.Username,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+		Enterprise:  &SCIMEnterpriseUser{Department: user.DepartmentName()},
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     scimUserLocation(user.ID),
+		},
+	}
+	if user.DisplayName != "" {
+		resource.Name = &SCIMName{Formatted: user.DisplayName}
+	}
+	if user.Email != nil {
+		resource.Emails = []SCIMMultiValue{{Value: *user.Email, Type: "work", Primary: true}}
+	}
+	if user.ManagerID != nil {
+		resource.Enterprise.Manager = &SCIMManager{
+			Value: strconv.FormatUint(uint64(*user.ManagerID), 10),
+			Ref:   scimUserLocation(*user.ManagerID),
+		}
+	}
+
+	roleGroup := scimRoleGroupID(user.Role)
+	resource.Groups = append(resource.Groups, SCIMMultiValue{Value: roleGroup, Display: string(user.Role), Ref: scimGroupLocation(roleGroup)})
+	if user.DepartmentID != nil {
+		departmentGroup := scimDepartmentGroupID(*user.DepartmentID)
+		resource.Groups = append(resource.Groups, SCIMMultiValue{Value: departmentGroup, Display: user.DepartmentName(), Ref: scimGroupLocation(departmentGroup)})
+	}
+
+	return resource
+}
+
+// primaryEmail returns the primary email, or the first one when none is marked primary
+func (r *SCIMUserResource) primaryEmail() string {
+	for _, email := range r.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(r.Emails) > 0 {
+		return r.Emails[0].Value
+	}
+	return ""
+}
+
+// displayName returns the display name, falling back to the name sent in parts
+func (r *SCIMUserResource) displayName() string {
+	if r.DisplayName != "" || r.Name == nil {
+		return r.DisplayName
+	}
+	if r.Name.Formatted != "" {
+		return r.Name.Formatted
+	}
+	return strings.TrimSpace(r.Name.GivenName + " " + r.Name.FamilyName)
+}
+
+// applyPatch applies one PATCH operation (RFC 7644 section 3.5.2). Attributes that are not
+// stored, such as phone numbers or the parts of a name, are ignored as they are in a PUT.
+func (r *SCIMUserResource) applyPatch(operation SCIMPatchOperation) error {
+	op, err := scimPatchOp(operation)
+	if err != nil {
+		return err
+	}
+	if operation.Path != "" {
+		return r.setAttribute(op, operation.Path, operation.Value)
+	}
+	if op == "remove" {
+		return fmt.Errorf("%w: remove needs a path", ErrSCIMNoTarget)
+	}
+
+	// Without a path the value holds the attributes to set; name and the extension are nested
+	values, err := scimObject(operation.Value)
+	if err != nil {
+		return err
+	}
+	for _, path := range sortedKeys(values) {
+		switch strings.ToLower(path) {
+		case "name", strings.ToLower(SCIMEnterpriseUserSchema):
+			nested, err := scimObject(values[path])
+			if err != nil {
+				return err
+			}
+			separator := ":"
+			if strings.EqualFold(path, "name") {
+				separator = "."
+			}
+			for _, subPath := range sortedKeys(nested) {
+				if err := r.setAttribute(op, path+separator+subPath, nested[subPath]); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := r.setAttribute(op, path, values[path]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setAttribute adds, replaces or removes a single attribute
+func (r *SCIMUserResource) setAttribute(op, path string, value json.RawMessage) error {
+	attr := normalizeSCIMPath(path)
+	remove := op == "remove"
+	enterprise := strings.ToLower(SCIMEnterpriseUserSchema) + ":"
+
+	switch {
+	case attr == "username":
+		if remove {
+			return fmt.Errorf("%w: userName is required", ErrSCIMMutability)
+		}
+		return decodeSCIMValue(value, &r.UserName)
+
+	case attr == "externalid":
+		if remove {
+			r.ExternalID = ""
+			return nil
+		}
+		return decodeSCIMValue(value, &r.ExternalID)
+
+	case attr == "displayname", attr == "name.formatted":
+		var displayName string
+		if !remove {
+			if err := decodeSCIMValue(value, &displayName); err != nil {
+				return err
+			}
+		}
+		r.DisplayName, r.Name = displayName, nil
+		return nil
+
+	case attr == "emails":
+		if remove {
+			r.Emails = nil
+			return nil
+		}
+		// Only one address is stored, so adding replaces it
+		return decodeSCIMValue(value, &r.Emails)
+
+	case strings.HasPrefix(attr, "emails["):
+		// e.g. emails[type eq "work"].value; the filter always selects the one stored address
+		if remove {
+			r.Emails = nil
+			return nil
+		}
+		email := SCIMMultiValue{Type: "work", Primary: true}
+		if strings.HasSuffix(attr, "].value") {
+			if err := decodeSCIMValue(value, &email.Value); err != nil {
+				return err
+			}
+		} else if err := decodeSCIMValue(value, &email); err != nil {
+			return err
+		}
+		r.Emails = []SCIMMultiValue{email}
+		return nil
+
+	case attr == "active":
+		if remove {
+			return fmt.Errorf("%w: active cannot be removed", ErrSCIMMutability)
+		}
+		var active SCIMBool
+		if err := decodeSCIMValue(value, &active); err != nil {
+			return err
+		}
+		r.Active = &active
+		return nil
+
+	case attr == "timezone":
+		if remove {
+			r.Timezone = "UTC"
+			return nil
+		}
+		return decodeSCIMValue(value, &r.Timezone)
+
+	case attr == "locale":
+		if remove {
+			r.Locale = "en"
+			return nil
+		}
+		return decodeSCIMValue(value, &r.Locale)
+
+	case attr == "password":
+		if remove {
+			return fmt.Errorf("%w: password cannot be removed", ErrSCIMMutability)
+		}
+		return decodeSCIMValue(value, &r.Password)
+
+	case attr == enterprise+"department":
+		if r.Enterprise == nil {
+			r.Enterprise = &SCIMEnterpriseUser{}
+		}
+		if remove {
+			r.Enterprise.Department = ""
+			return nil
+		}
+		return decodeSCIMValue(value, &r.Enterprise.Department)
+
+	case attr == enterprise+"manager", attr == enterprise+"manager.value":
+		if r.Enterprise == nil {
+			r.Enterprise = &SCIMEnterpriseUser{}
+		}
+		if remove {
+			r.Enterprise.Manager = nil
+			return nil
+		}
+		var manager SCIMManager
+		if err := decodeSCIMValue(value, &manager); err != nil {
+			return err
+		}
+		r.Enterprise.Manager = &manager
+		return nil
+
+	case attr == "id", attr == "groups", attr == "schemas", strings.HasPrefix(attr, "meta"):
+		return fmt.Errorf("%w: %s is read-only", ErrSCIMMutability, path)
+	}
+
+	return nil
+}
+
+// scimPatchOp validates and lower-cases an operation's op, which some clients capitalize
+func scimPatchOp(operation SCIMPatchOperation) (string, error) {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return "", fmt.Errorf("%w: unknown op %q", ErrSCIMInvalidSyntax, operation.Op)
+	}
+	return op, nil
+}
+
+// normalizeSCIMPath lower-cases an attribute path and drops the core schema URN it may be qualified with
+func normalizeSCIMPath(path string) string {
+	attr := strings.ToLower(strings.TrimSpace(path))
+	for _, schema := range []string{SCIMUserSchema, SCIMGroupSchema} {
+		attr = strings.TrimPrefix(attr, strings.ToLower(schema)+":")
+	}
+	return attr
+}
+
+func decodeSCIMValue(value json.RawMessage, target interface{}) error {
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("%w: %v", ErrSCIMInvalidValue, err)
+	}
+	return nil
+}
+
+func scimObject(value json.RawMessage) (map[string]json.RawMessage, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(value, &object); err != nil {
+		return nil, fmt.Errorf("%w: expected an object", ErrSCIMInvalidValue)
+	}
+	return object, nil
+}
+
+func sortedKeys(values map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func scimUserLocation(userID uint) string {
+	return fmt.Sprintf("%s/Users/%d", SCIMBasePath, userID)
+}
+
+func scimGroupLocation(groupID string) string {
+	return SCIMBasePath + "/Groups/" + groupID
+}
@@ -0,0 +1,907 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SCIMTokenPrefix marks bearer tokens of SCIM provisioning clients
+const SCIMTokenPrefix = "pxs_"
+
+// SCIMMaxResults caps the page size of list requests
+const SCIMMaxResults = 200
+
+// Group ids name the kind of group: "role-admin" or "department-12"
+const (
+	scimRoleGroupPrefix       = "role-"
+	scimDepartmentGroupPrefix = "department-"
+)
+
+var (
+	ErrSCIMNotFound      = errors.New("resource not found")
+	ErrSCIMInvalidFilter = errors.New("invalid filter")
+	ErrSCIMInvalidSyntax = errors.New("invalid request")
+	ErrSCIMInvalidValue  = errors.New("invalid value")
+	ErrSCIMInvalidPath   = errors.New("invalid path")
+	ErrSCIMNoTarget      = errors.New("operation has no target")
+	ErrSCIMMutability    = errors.New("attribute cannot be changed")
+	ErrSCIMGroupExists   = errors.New("group already exists")
+	ErrSCIMGroupsFixed   = errors.New("groups are the workspace's roles and departments and cannot be created or deleted over SCIM")
+
+	// ErrSCIMDeactivationBlocked is returned when a user's open work cannot be handed to their manager
+	ErrSCIMDeactivationBlocked = errors.New("user cannot be deactivated")
+)
+
+// scimUserColumns are the user attributes that can be filtered on
+var scimUserColumns = map[string]scimColumn{
+	"id":                {"id", scimNumericID},
+	"username":          {"username", scimString},
+	"externalid":        {"external_id", scimCaseExactString},
+	"displayname":       {"display_name", scimString},
+	"emails":            {"email", scimString},
+	"emails.value":      {"email", scimString},
+	"active":            {"active", scimBoolean},
+	"meta.created":      {"created_at", scimDateTime},
+	"meta.lastmodified": {"updated_at", scimDateTime},
+}
+
+// SCIMService provisions users for an identity provider. Users are created and changed through
+// UserService; roles and departments are exposed as groups whose members hold that role or
+// belong to that department.
+type SCIMService struct {
+	DB *gorm.DB
+}
+
+func NewSCIMService(db *gorm.DB) *SCIMService {
+	return &SCIMService{DB: db}
+}
+
+// CreateToken creates a provisioning token for the session's workspace and returns it with its
+// plaintext value, which is shown only once
+func (s *SCIMService) CreateToken(name string, createdBy uint) (*models.SCIMToken, string, error) {
+	random, err := generateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := SCIMTokenPrefix + random
+
+	token := &models.SCIMToken{
+		Name:        name,
+		TokenHash:   hashToken(plaintext),
+		TokenPrefix: plaintext[:len(SCIMTokenPrefix)+6],
+		CreatedBy:   createdBy,
+	}
+	if err := s.DB.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+
+	return token, plaintext, nil
+}
+
+// ListTokens returns the provisioning tokens, newest first
+func (s *SCIMService) ListTokens() ([]models.SCIMToken, error) {
+	var tokens []models.SCIMToken
+	err := s.DB.Preload("Creator").Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeToken revokes a provisioning token
+func (s *SCIMService) RevokeToken(tokenID uint) error {
+	result := s.DB.Model(&models.SCIMToken{}).
+		Where("id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("token not found")
+	}
+
+	return nil
+}
+
+// Authenticate looks up an active token by its plaintext value and records its use
+func (s *SCIMService) Authenticate(plaintext string) (*models.SCIMToken, error) {
+	var token models.SCIMToken
+	if err := s.DB.Where("token_hash = ?", hashToken(plaintext)).First(&token).Error; err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	if !token.IsActive() {
+		return nil, errors.New("token revoked")
+	}
+
+	s.DB.Model(&token).Update("last_used_at", time.Now())
+
+	return &token, nil
+}
+
+// ListUsers returns a page of users matching a SCIM filter and the total number of matches.
+// startIndex is 1-based.
+func (s *SCIMService) ListUsers(filter string, startIndex, count int) ([]models.User, int64, error) {
+	var condition string
+	var args []interface{}
+	if filter != "" {
+		parsed, err := parseSCIMFilter(filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		if condition, args, err = parsed.sql(scimUserColumns); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	query := func() *gorm.DB {
+		db := s.DB.Model(&models.User{})
+		if condition != "" {
+			db = db.Where(condition, args...)
+		}
+		return db
+	}
+
+	var total int64
+	if err := query().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	if count > 0 {
+		if err := query().Preload("Department").Order("id").Offset(startIndex - 1).Limit(count).Find(&users).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return users, total, nil
+}
+
+// GetUser returns a user by SCIM id
+func (s *SCIMService) GetUser(id string) (*models.User, error) {
+	userID, ok := parseSCIMID(id)
+	if !ok {
+		return nil, ErrSCIMNotFound
+	}
+
+	user, err := NewUserService(s.DB).GetUserByID(userID)
+	if err != nil {
+		return nil, ErrSCIMNotFound
+	}
+	return user, nil
+}
+
+// CreateUser provisions a user with the employee role. Users created without a password get an
+// unusable random one and sign in through single sign-on or a password reset.
+func (s *SCIMService) CreateUser(resource *SCIMUserResource, actorID uint) (*models.User, error) {
+	username := strings.TrimSpace(resource.UserName)
+	if username == "" {
+		return nil, fmt.Errorf("%w: userName is required", ErrSCIMInvalidValue)
+	}
+	actor, err := LoadActor(s.DB, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := NewPermissionService(s.DB).AuthorizeRoleGrant(actor, models.RoleEmployee); err != nil {
+		return nil, err
+	}
+	if NewUserService(s.DB).usernameTaken(username) {
+		return nil, ErrUsernameTaken
+	}
+
+	password := resource.Password
+	if password == "" {
+		random, err := generateRandomToken()
+		if err != nil {
+			return nil, err
+		}
+		password = random
+	} else if err := ValidatePassword(password, username); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSCIMInvalidValue, err)
+	}
+
+	var user *models.User
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		created, err := NewUserService(tx).insertUser(&models.User{
+			Username:   username,
+			Role:       models.RoleEmployee,
+			ExternalID: resource.ExternalID,
+			Active:     true,
+		}, password)
+		if err != nil {
+			return err
+		}
+
+		// The password is already set; the rest of the resource is applied like an update
+		resource.Password = ""
+		user, err = NewSCIMService(tx).applyUser(created, resource, actorID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ReplaceUser replaces a user's attributes (PUT). Department and manager are only changed when
+// the enterprise extension is sent, so group-based department assignment is not undone.
+func (s *SCIMService) ReplaceUser(id string, resource *SCIMUserResource, actorID uint) (*models.User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.User
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		updated, err = NewSCIMService(tx).applyUser(user, resource, actorID)
+		return err
+	})
+	return updated, err
+}
+
+// PatchUser applies PATCH operations to a user
+func (s *SCIMService) PatchUser(id string, operations []SCIMPatchOperation, actorID uint) (*models.User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := NewSCIMUserResource(user)
+	for _, operation := range operations {
+		if err := resource.applyPatch(operation); err != nil {
+			return nil, err
+		}
+	}
+
+	var updated *models.User
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		updated, err = NewSCIMService(tx).applyUser(user, &resource, actorID)
+		return err
+	})
+	return updated, err
+}
+
+// DeleteUser deactivates a user. Users are never deleted so their finished work keeps its owner.
+func (s *SCIMService) DeleteUser(id string, actorID uint) error {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return err
+	}
+	if !user.Active {
+		return nil
+	}
+
+	actor, err := LoadActor(s.DB, actorID)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		return NewSCIMService(tx).deactivateUser(user, actor)
+	})
+}
+
+// applyUser brings a user in line with a SCIM resource
+func (s *SCIMService) applyUser(user *models.User, resource *SCIMUserResource, actorID uint) (*models.User, error) {
+	userService := NewUserService(s.DB)
+	actor, err := LoadActor(s.DB, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	username := strings.TrimSpace(resource.UserName)
+	if username == "" {
+		return nil, fmt.Errorf("%w: userName is required", ErrSCIMInvalidValue)
+	}
+	if username != user.Username {
+		if userService.usernameTaken(username) {
+			return nil, ErrUsernameTaken
+		}
+		if err := s.DB.Model(user).Update("username", username).Error; err != nil {
+			return nil, err
+		}
+	}
+	if resource.ExternalID != user.ExternalID {
+		if err := s.DB.Model(user).Update("external_id", resource.ExternalID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Profile fields are validated like the user's own profile changes
+	email, displayName := resource.primaryEmail(), resource.displayName()
+	profile := ProfileUpdate{Email: &email, DisplayName: &displayName}
+	if resource.Timezone != "" {
+		profile.Timezone = &resource.Timezone
+	}
+	if resource.Locale != "" {
+		profile.Locale = &resource.Locale
+	}
+	// Addresses from the identity provider count as verified
+	if _, err := NewProfileService(s.DB).updateProfile(user.ID, profile, true); err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSCIMInvalidValue, err)
+	}
+
+	if resource.Enterprise != nil {
+		if err := s.applyDepartment(actor, user, resource.Enterprise.Department); err != nil {
+			return nil, err
+		}
+		if err := s.applyManager(actor, user, resource.Enterprise.Manager); err != nil {
+			return nil, err
+		}
+	}
+
+	if resource.Password != "" {
+		if err := userService.ResetUserPassword(actor, user.ID, resource.Password); err != nil {
+			if errors.Is(err, ErrAccessDenied) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", ErrSCIMInvalidValue, err)
+		}
+	}
+
+	// Deactivation comes last so it sees the manager set by the same request
+	if resource.Active != nil {
+		active := bool(*resource.Active)
+		switch {
+		case user.Active && !active:
+			if err := s.deactivateUser(user, actor); err != nil {
+				return nil, err
+			}
+		case !user.Active && active:
+			if _, err := NewOffboardingService(s.DB).ReactivateUser(actor, user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return userService.GetUserByID(user.ID)
+}
+
+// applyDepartment moves a user to the department with the given name; "" removes them from their department
+func (s *SCIMService) applyDepartment(actor *Actor, user *models.User, name string) error {
+	if strings.TrimSpace(name) == "" {
+		if user.DepartmentID == nil {
+			return nil
+		}
+		_, err := NewUserService(s.DB).UpdateUserDepartment(actor, user.ID, nil)
+		return err
+	}
+
+	department, err := NewDepartmentService(s.DB).FindByName(name)
+	if err != nil {
+		return fmt.Errorf("%w: department %q not found", ErrSCIMInvalidValue, name)
+	}
+	if user.DepartmentID != nil && *user.DepartmentID == department.ID {
+		return nil
+	}
+
+	_, err = NewUserService(s.DB).UpdateUserDepartment(actor, user.ID, &department.ID)
+	return err
+}
+
+// applyManager sets who a user reports to; a nil or empty reference removes their manager
+func (s *SCIMService) applyManager(actor *Actor, user *models.User, manager *SCIMManager) error {
+	var managerID *uint
+	if manager != nil && manager.Value != "" {
+		id, ok := parseSCIMID(manager.Value)
+		if !ok {
+			return fmt.Errorf("%w: invalid manager id %q", ErrSCIMInvalidValue, manager.Value)
+		}
+		managerID = &id
+	}
+
+	if (managerID == nil && user.ManagerID == nil) ||
+		(managerID != nil && user.ManagerID != nil && *managerID == *user.ManagerID) {
+		return nil
+	}
+
+	updated, err := NewTeamService(s.DB).SetManager(actor, user.ID, managerID)
+	if errors.Is(err, ErrAccessDenied) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSCIMInvalidValue, err)
+	}
+	user.ManagerID = updated.ManagerID
+	return nil
+}
+
+// deactivateUser offboards a user, handing their open work in every project to their manager
+func (s *SCIMService) deactivateUser(user *models.User, actor *Actor) error {
+	offboardingService := NewOffboardingService(s.DB)
+	_, groups, err := offboardingService.GetOffboardingPlan(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(groups) > 0 && user.ManagerID == nil {
+		return fmt.Errorf("%w: they have open work and no manager to take it over; hand it over with GET /users/%d/offboarding",
+			ErrSCIMDeactivationBlocked, user.ID)
+	}
+
+	successors := make([]Successor, 0, len(groups))
+	for _, group := range groups {
+		successors = append(successors, Successor{ProjectID: group.ProjectID, UserID: *user.ManagerID})
+	}
+
+	if _, err := offboardingService.DeactivateUser(actor, user.ID, successors); err != nil {
+		if errors.Is(err, ErrAccessDenied) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrSCIMDeactivationBlocked, err)
+	}
+	return nil
+}
+
+// scimGroup is a role or a department seen as a SCIM group
+type scimGroup struct {
+	id           string
+	displayName  string
+	role         models.Role // Set for role groups
+	departmentID uint        // Set for department groups
+	createdAt    time.Time
+	updatedAt    time.Time
+}
+
+// scimMember is the part of a user needed to list group members
+type scimMember struct {
+	ID           uint
+	Username     string
+	DisplayName  string
+	Role         models.Role
+	DepartmentID *uint
+}
+
+// ListGroups returns a page of groups matching a SCIM filter and the total number of matches.
+// Members are left out when withMembers is false, which large groups make worthwhile.
+func (s *SCIMService) ListGroups(filter string, startIndex, count int, withMembers bool) ([]SCIMGroupResource, int, error) {
+	var parsed *scimFilter
+	if filter != "" {
+		var err error
+		if parsed, err = parseSCIMFilter(filter); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	groups, err := s.allGroups()
+	if err != nil {
+		return nil, 0, err
+	}
+	members, err := s.groupMembers()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var matched []scimGroup
+	for _, group := range groups {
+		if parsed != nil {
+			ok, err := parsed.matches(group.filterValues(members[group.id]))
+			if err != nil {
+				return nil, 0, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		matched = append(matched, group)
+	}
+
+	total := len(matched)
+	start := startIndex - 1
+	if start > total {
+		start = total
+	}
+	end := start + count
+	if end > total {
+		end = total
+	}
+
+	resources := make([]SCIMGroupResource, 0, end-start)
+	for _, group := range matched[start:end] {
+		resources = append(resources, group.resource(members[group.id], withMembers))
+	}
+	return resources, total, nil
+}
+
+// GetGroup returns a group by SCIM id
+func (s *SCIMService) GetGroup(id string, withMembers bool) (*SCIMGroupResource, error) {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groupMembers()
+	if err != nil {
+		return nil, err
+	}
+
+	resource := group.resource(members[group.id], withMembers)
+	return &resource, nil
+}
+
+// CreateGroup links a group pushed by the identity provider to an existing role or department.
+// New groups cannot be created since roles define permissions and departments are shared by all workspaces.
+func (s *SCIMService) CreateGroup(resource *SCIMGroupResource) error {
+	groups, err := s.allGroups()
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if strings.EqualFold(group.displayName, strings.TrimSpace(resource.DisplayName)) {
+			return fmt.Errorf("%w: %s is group %s", ErrSCIMGroupExists, group.displayName, group.id)
+		}
+	}
+	return ErrSCIMGroupsFixed
+}
+
+// DeleteGroup is refused for existing groups; see CreateGroup
+func (s *SCIMService) DeleteGroup(id string) error {
+	if _, err := s.findGroup(id); err != nil {
+		return err
+	}
+	return ErrSCIMGroupsFixed
+}
+
+// ReplaceGroup sets a group's members (PUT). The display name cannot be changed.
+func (s *SCIMService) ReplaceGroup(id string, resource *SCIMGroupResource, actorID uint) (*SCIMGroupResource, error) {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := group.checkDisplayName(resource.DisplayName); err != nil {
+		return nil, err
+	}
+
+	desired := make(map[uint]bool)
+	for _, member := range resource.Members {
+		userID, ok := parseSCIMID(member.Value)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid member %q", ErrSCIMInvalidValue, member.Value)
+		}
+		desired[userID] = true
+	}
+
+	return s.setGroupMembers(group, desired, actorID)
+}
+
+// PatchGroup applies PATCH operations to a group's members
+func (s *SCIMService) PatchGroup(id string, operations []SCIMPatchOperation, actorID uint) (*SCIMGroupResource, error) {
+	group, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groupMembers()
+	if err != nil {
+		return nil, err
+	}
+
+	desired := make(map[uint]bool)
+	for _, member := range members[group.id] {
+		desired[member.ID] = true
+	}
+
+	for _, operation := range operations {
+		op, err := scimPatchOp(operation)
+		if err != nil {
+			return nil, err
+		}
+
+		if operation.Path != "" {
+			if err := group.patchAttribute(desired, op, operation.Path, operation.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if op == "remove" {
+			return nil, fmt.Errorf("%w: remove needs a path", ErrSCIMNoTarget)
+		}
+
+		values, err := scimObject(operation.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range sortedKeys(values) {
+			if err := group.patchAttribute(desired, op, path, values[path]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.setGroupMembers(group, desired, actorID)
+}
+
+// patchAttribute applies one operation to the desired member set
+func (g *scimGroup) patchAttribute(desired map[uint]bool, op, path string, value []byte) error {
+	attr := normalizeSCIMPath(path)
+	switch {
+	case attr == "displayname":
+		var displayName string
+		if op == "remove" {
+			return fmt.Errorf("%w: displayName is required", ErrSCIMMutability)
+		}
+		if err := decodeSCIMValue(value, &displayName); err != nil {
+			return err
+		}
+		return g.checkDisplayName(displayName)
+
+	case attr == "externalid":
+		// External ids of groups are not stored
+		return nil
+
+	case attr == "members":
+		var members []SCIMMultiValue
+		if len(value) > 0 && string(value) != "null" {
+			if err := decodeSCIMValue(value, &members); err != nil {
+				return err
+			}
+		}
+
+		// Removing without a value removes every member, replacing starts from an empty group
+		if op == "replace" || (op == "remove" && len(members) == 0) {
+			for userID := range desired {
+				delete(desired, userID)
+			}
+		}
+		for _, member := range members {
+			userID, ok := parseSCIMID(member.Value)
+			if !ok {
+				return fmt.Errorf("%w: invalid member %q", ErrSCIMInvalidValue, member.Value)
+			}
+			desired[userID] = op != "remove"
+		}
+		return nil
+
+	case strings.HasPrefix(attr, "members[") && strings.HasSuffix(attr, "]"):
+		// e.g. members[value eq "42"]
+		if op != "remove" {
+			return fmt.Errorf("%w: members can only be removed with a filter", ErrSCIMInvalidPath)
+		}
+		filter, err := parseSCIMFilter(path[strings.Index(path, "[")+1 : len(path)-1])
+		if err != nil {
+			return err
+		}
+		for userID, member := range desired {
+			if !member {
+				continue
+			}
+			id := strconv.FormatUint(uint64(userID), 10)
+			matched, err := filter.matches(func(attr string) ([]string, bool, bool) {
+				return []string{id}, true, attr == "value"
+			})
+			if err != nil {
+				return err
+			}
+			if matched {
+				desired[userID] = false
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrSCIMInvalidPath, path)
+}
+
+// setGroupMembers gives the group's role or department to the desired members and takes it from
+// everyone else. Users leaving a role become employees; users leaving a department have none.
+// Role changes follow the role rules of the admin behind the token.
+func (s *SCIMService) setGroupMembers(group *scimGroup, desired map[uint]bool, actorID uint) (*SCIMGroupResource, error) {
+	actor, err := LoadActor(s.DB, actorID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groupMembers()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[uint]bool)
+	for _, member := range members[group.id] {
+		current[member.ID] = true
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		userService := NewUserService(tx)
+		for userID, member := range desired {
+			if !member || current[userID] {
+				continue
+			}
+
+			var err error
+			if group.role != "" {
+				_, err = userService.UpdateUserRole(actor, userID, string(group.role))
+			} else {
+				_, err = userService.UpdateUserDepartment(actor, userID, &group.departmentID)
+			}
+			if errors.Is(err, ErrAccessDenied) {
+				return fmt.Errorf("member %d: %w", userID, err)
+			}
+			if err != nil {
+				return fmt.Errorf("%w: member %d: %v", ErrSCIMInvalidValue, userID, err)
+			}
+		}
+
+		for userID := range current {
+			if desired[userID] {
+				continue
+			}
+
+			var err error
+			if group.role != "" {
+				_, err = userService.UpdateUserRole(actor, userID, string(models.RoleEmployee))
+			} else {
+				_, err = userService.UpdateUserDepartment(actor, userID, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(group.id, true)
+}
+
+// allGroups returns every role and department as a group, roles first
+func (s *SCIMService) allGroups() ([]scimGroup, error) {
+	roles, err := NewPermissionService(s.DB).ListRoles()
+	if err != nil {
+		return nil, err
+	}
+	departments, err := NewDepartmentService(s.DB).ListDepartments()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]scimGroup, 0, len(roles)+len(departments))
+	for _, role := range roles {
+		groups = append(groups, newRoleGroup(&role))
+	}
+	for _, department := range departments {
+		groups = append(groups, newDepartmentGroup(&department))
+	}
+	return groups, nil
+}
+
+// findGroup looks up a group by SCIM id
+func (s *SCIMService) findGroup(id string) (*scimGroup, error) {
+	if name, ok := strings.CutPrefix(id, scimRoleGroupPrefix); ok {
+		role, err := NewPermissionService(s.DB).GetRole(name)
+		if err != nil {
+			return nil, ErrSCIMNotFound
+		}
+		group := newRoleGroup(role)
+		return &group, nil
+	}
+
+	if rawID, ok := strings.CutPrefix(id, scimDepartmentGroupPrefix); ok {
+		departmentID, ok := parseSCIMID(rawID)
+		if !ok {
+			return nil, ErrSCIMNotFound
+		}
+		department, err := NewDepartmentService(s.DB).GetDepartment(departmentID)
+		if err != nil {
+			return nil, ErrSCIMNotFound
+		}
+		group := newDepartmentGroup(department)
+		return &group, nil
+	}
+
+	return nil, ErrSCIMNotFound
+}
+
+// groupMembers returns the members of every group in the workspace, keyed by group id
+func (s *SCIMService) groupMembers() (map[string][]scimMember, error) {
+	var users []scimMember
+	if err := s.DB.Model(&models.User{}).
+		Select("id, username, display_name, role, department_id").
+		Order("id").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	members := make(map[string][]scimMember)
+	for _, user := range users {
+		roleGroup := scimRoleGroupID(user.Role)
+		members[roleGroup] = append(members[roleGroup], user)
+		if user.DepartmentID != nil {
+			departmentGroup := scimDepartmentGroupID(*user.DepartmentID)
+			members[departmentGroup] = append(members[departmentGroup], user)
+		}
+	}
+	return members, nil
+}
+
+func newRoleGroup(role *models.RoleDefinition) scimGroup {
+	return scimGroup{
+		id:          scimRoleGroupID(role.Name),
+		displayName: string(role.Name),
+		role:        role.Name,
+		createdAt:   role.CreatedAt,
+		updatedAt:   role.UpdatedAt,
+	}
+}
+
+func newDepartmentGroup(department *models.Department) scimGroup {
+	return scimGroup{
+		id:           scimDepartmentGroupID(department.ID),
+		displayName:  department.Name,
+		departmentID: department.ID,
+		createdAt:    department.CreatedAt,
+		updatedAt:    department.UpdatedAt,
+	}
+}
+
+// checkDisplayName allows a client to resend a group's display name but not to change it
+func (g *scimGroup) checkDisplayName(displayName string) error {
+	if displayName != "" && !strings.EqualFold(strings.TrimSpace(displayName), g.displayName) {
+		return fmt.Errorf("%w: the display name of %s is managed in project-x", ErrSCIMMutability, g.id)
+	}
+	return nil
+}
+
+// filterValues exposes a group's attributes to in-memory filtering
+func (g *scimGroup) filterValues(members []scimMember) func(attr string) ([]string, bool, bool) {
+	return func(attr string) ([]string, bool, bool) {
+		switch attr {
+		case "id":
+			return []string{g.id}, true, true
+		case "displayname":
+			return []string{g.displayName}, false, true
+		case "externalid":
+			return nil, true, true
+		case "members", "members.value":
+			ids := make([]string, 0, len(members))
+			for _, member := range members {
+				ids = append(ids, strconv.FormatUint(uint64(member.ID), 10))
+			}
+			return ids, true, true
+		}
+		return nil, false, false
+	}
+}
+
+// resource formats the group as a SCIM group
+func (g *scimGroup) resource(members []scimMember, withMembers bool) SCIMGroupResource {
+	resource := SCIMGroupResource{
+		Schemas:     []string{SCIMGroupSchema},
+		ID:          g.id,
+		DisplayName: g.displayName,
+		Meta: &SCIMMeta{
+			ResourceType: "Group",
+			Created:      g.createdAt,
+			LastModified: g.updatedAt,
+			Location:     scimGroupLocation(g.id),
+		},
+	}
+	if withMembers {
+		for _, member := range members {
+			display := member.DisplayName
+			if display == "" {
+				display = member.Username
+			}
+			resource.Members = append(resource.Members, SCIMMultiValue{
+				Value:   strconv.FormatUint(uint64(member.ID), 10),
+				Display: display,
+				Ref:     scimUserLocation(member.ID),
+			})
+		}
+	}
+	return resource
+}
+
+func scimRoleGroupID(role models.Role) string {
+	return scimRoleGroupPrefix + string(role)
+}
+
+func scimDepartmentGroupID(departmentID uint) string {
+	return scimDepartmentGroupPrefix + strconv.FormatUint(uint64(departmentID), 10)
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"project-x/config"
+	"project-x/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionService struct {
+	DB *gorm.DB
+}
+
+func NewSessionService(db *gorm.DB) *SessionService {
+	return &SessionService{DB: db}
+}
+
+// CreateSession starts a new session for a user and returns its plaintext refresh token
+func (s *SessionService) CreateSession(userID uint, userAgent, ipAddress string) (*models.Session, string, error) {
+	refreshToken, err := generateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		UserID:           userID,
+		RefreshTokenHash: hashToken(refreshToken),
+		ExpiresAt:        now.Add(config.Get().RefreshTokenTTL),
+		LastUsedAt:       now,
+		UserAgent:        userAgent,
+		IPAddress:        ipAddress,
+	}
+
+	if err := s.DB.Create(session).Error; err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one on the same session.
+// Presenting an already rotated token revokes the session, since it may have been stolen.
+func (s *SessionService) RotateRefreshToken(refreshToken string) (*models.Session, string, error) {
+	tokenHash := hashToken(refreshToken)
+
+	var session models.Session
+	if err := s.DB.Where("refresh_token_hash = ?", tokenHash).First(&session).Error; err != nil {
+		var reused models.Session
+		if err := s.DB.Where("previous_token_hash = ?", tokenHash).First(&reused).Error; err == nil {
+			s.RevokeSession(reused.ID)
+		}
+		return nil, "", errors.New("invalid refresh token")
+	}
+
+	if !session.IsActive() {
+		return nil, "", errors.New("session expired or revoked")
+	}
+
+	newToken, err := generateRandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Only one of two requests racing with the same token may rotate it; the loser is
+	// treated as a reuse of the token
+	now := time.Now()
+	result := s.DB.Model(&session).
+		Where("refresh_token_hash = ?", tokenHash).
+		Updates(map[string]interface{}{
+			"previous_token_hash": tokenHash,
+			"refresh_token_hash":  hashToken(newToken),
+			"last_used_at":        now,
+		})
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected != 1 {
+		s.RevokeSession(session.ID)
+		return nil, "", errors.New("invalid refresh token")
+	}
+
+	session.PreviousTokenHash = tokenHash
+	session.RefreshTokenHash = hashToken(newToken)
+	session.LastUsedAt = now
+
+	return &session, newToken, nil
+}
+
+// GetActiveSession returns a session if it has not been revoked or expired
+func (s *SessionService) GetActiveSession(sessionID uint) (*models.Session, error) {
+	var session models.Session
+	if err := s.DB.First(&session, sessionID).Error; err != nil {
+		return nil, errors.New("session not found")
+	}
+
+	if !session.IsActive() {
+		return nil, errors.New("session expired or revoked")
+	}
+
+	return &session, nil
+}
+
+// RevokeSession revokes a single session
+func (s *SessionService) RevokeSession(sessionID uint) error {
+	return s.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllUserSessions revokes every active session belonging to a user
+func (s *SessionService) RevokeAllUserSessions(userID uint) error {
+	return s.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// generateRandomToken returns a URL-safe random token
+func generateRandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the SHA-256 hex digest used to store tokens at rest
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
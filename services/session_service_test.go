@@ -0,0 +1,49 @@
+package services
+
+import (
+	"project-x/models"
+	"testing"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	db := testDB(t)
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	user := models.User{WorkspaceID: workspaceID, Username: "rotation-user", Password: "x", Role: models.RoleEmployee}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	sessionService := NewSessionService(db)
+	session, first, err := sessionService.CreateSession(user.ID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	rotated, second, err := sessionService.RotateRefreshToken(first)
+	if err != nil || rotated.ID != session.ID || second == first {
+		t.Fatalf("rotate: got session %+v, same token %v, %v", rotated, second == first, err)
+	}
+	_, third, err := sessionService.RotateRefreshToken(second)
+	if err != nil {
+		t.Fatalf("rotate the new token: %v", err)
+	}
+
+	// Presenting a rotated token again means it leaked: the whole session is revoked
+	if _, _, err := sessionService.RotateRefreshToken(second); err == nil {
+		t.Error("a rotated token should not be accepted again")
+	}
+	if _, err := sessionService.GetActiveSession(session.ID); err == nil {
+		t.Error("reusing a rotated token should revoke the session")
+	}
+	if _, _, err := sessionService.RotateRefreshToken(third); err == nil {
+		t.Error("the latest token of a revoked session should stop working")
+	}
+
+	if _, _, err := sessionService.RotateRefreshToken("never-issued"); err == nil {
+		t.Error("an unknown token should be rejected")
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"project-x/config"
+	"project-x/models"
+	"strings"
+	"sync"
+)
+
+// Storage keeps uploaded files such as avatars. Implementations can write to object
+// storage or a CDN; the local storage is the default and is served by the API itself.
+type Storage interface {
+	Save(key string, content io.Reader) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// LocalStorage writes files to a directory on disk
+type LocalStorage struct {
+	Dir     string
+	BaseURL string // Path the directory is served at
+}
+
+func (s *LocalStorage) Save(key string, content io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial upload
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return strings.TrimSuffix(s.BaseURL, "/") + "/" + key
+}
+
+// path maps a key to a file inside Dir, refusing keys that would escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || strings.ContainsAny(key, `/\`) {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.Dir, key), nil
+}
+
+var (
+	avatarStorage     Storage
+	avatarStorageOnce sync.Once
+)
+
+// GetAvatarStorage returns the avatar storage. Local disk is the only built-in storage;
+// others are installed with SetAvatarStorage.
+func GetAvatarStorage() Storage {
+	avatarStorageOnce.Do(func() {
+		cfg := config.Get()
+		avatarStorage = &LocalStorage{Dir: cfg.AvatarDir, BaseURL: cfg.AvatarBaseURL}
+	})
+	return avatarStorage
+}
+
+// SetAvatarStorage replaces the avatar storage, e.g. with object storage or a test double
+func SetAvatarStorage(s Storage) {
+	avatarStorageOnce.Do(func() {})
+	avatarStorage = s
+}
+
+// AvatarURL returns where a user's avatar can be fetched, or "" when they have none
+func AvatarURL(user *models.User) string {
+	if user.AvatarKey == "" {
+		return ""
+	}
+	return GetAvatarStorage().URL(user.AvatarKey)
+}
@@ -0,0 +1,678 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned for subtask and checklist changes
+var (
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	ErrNestedSubtask         = errors.New("subtasks cannot have subtasks of their own")
+	ErrNotSubtask            = errors.New("task is not a subtask")
+	ErrInvalidOrder          = errors.New("the order must list each item exactly once")
+	ErrProgressRolledUp      = errors.New("progress is rolled up from the subtasks and checklist items")
+)
+
+type SubtaskService struct {
+	DB *gorm.DB
+}
+
+func NewSubtaskService(db *gorm.DB) *SubtaskService {
+	return &SubtaskService{DB: db}
+}
+
+// anyTask is a task or collaborative task, e.g. the parent of subtasks or one end of a dependency
+type anyTask struct {
+	kind          string // See the models.TaskKind constants
+	task          *models.Task
+	collaborative *models.CollaborativeTask
+}
+
+// loadAnyTask loads a task or collaborative task with what the access policy needs
+func loadAnyTask(db *gorm.DB, kind string, taskID uint) (*anyTask, error) {
+	switch kind {
+	case models.TaskKindTask:
+		task, err := NewTaskService(db).loadTask(taskID)
+		if err != nil {
+			return nil, err
+		}
+		return &anyTask{kind: kind, task: task}, nil
+	case models.TaskKindCollaborative:
+		task, err := loadCollaborativeTask(db, taskID)
+		if err != nil {
+			return nil, err
+		}
+		return &anyTask{kind: kind, collaborative: task}, nil
+	}
+	return nil, ErrTaskNotFound
+}
+
+func (p *anyTask) id() uint {
+	if p.task != nil {
+		return p.task.ID
+	}
+	return p.collaborative.ID
+}
+
+func (p *anyTask) workspaceID() uint {
+	if p.task != nil {
+		return p.task.WorkspaceID
+	}
+	return p.collaborative.WorkspaceID
+}
+
+// ownerID is the owner of a task or the lead of a collaborative task
+func (p *anyTask) ownerID() uint {
+	if p.task != nil {
+		return p.task.UserID
+	}
+	return p.collaborative.LeadUserID
+}
+
+func (p *anyTask) projectID() *uint {
+	if p.task != nil {
+		return p.task.ProjectID
+	}
+	return p.collaborative.ProjectID
+}
+
+func (p *anyTask) title() string {
+	if p.task != nil {
+		return p.task.Title
+	}
+	return p.collaborative.Title
+}
+
+func (p *anyTask) status() models.TaskStatus {
+	if p.task != nil {
+		return p.task.Status
+	}
+	return p.collaborative.Status
+}
+
+func (p *anyTask) priority() models.Priority {
+	if p.task != nil {
+		return p.task.Priority
+	}
+	return p.collaborative.Priority
+}
+
+// model is the empty model applyTaskChanges updates
+func (p *anyTask) model() interface{} {
+	return taskModel(p.kind)
+}
+
+// taskModel returns an empty model of a kind of task to query or update
+func taskModel(kind string) interface{} {
+	if kind == models.TaskKindCollaborative {
+		return &models.CollaborativeTask{}
+	}
+	return &models.Task{}
+}
+
+// authorize checks an action on the parent with the policy of its kind
+func (p *anyTask) authorize(actor *Actor, action TaskAction) error {
+	if p.task != nil {
+		return AuthorizeTask(actor, p.task, action)
+	}
+	return AuthorizeCollaborativeTask(actor, p.collaborative, action)
+}
+
+// authorizeCheckOff checks ticking checklist items: whoever may change a task's status, or
+// report progress on a collaborative task
+func (p *anyTask) authorizeCheckOff(actor *Actor) error {
+	if p.task != nil {
+		return AuthorizeTask(actor, p.task, TaskActionUpdateStatus)
+	}
+	return AuthorizeCollaborativeTask(actor, p.collaborative, TaskActionUpdateProgress)
+}
+
+// isParticipant reports whether a user works on the parent: its owner, or the lead or a participant of a collaborative task
+func (p *anyTask) isParticipant(userID uint) bool {
+	if userID == p.ownerID() {
+		return true
+	}
+	if p.collaborative != nil {
+		for _, participant := range p.collaborative.Participants {
+			if participant.UserID == userID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SubtaskInput holds the fields of a new subtask. UserID defaults to the parent's owner or lead
+// and Priority to the parent's priority.
+type SubtaskInput struct {
+	Title       string
+	Description string
+	UserID      *uint
+	DueDate     *time.Time
+	Priority    models.Priority
+}
+
+// GetSubtasks returns the subtasks of a task or collaborative task in their order
+func (s *SubtaskService) GetSubtasks(actor *Actor, kind string, parentID uint) ([]models.Task, error) {
+	parent, err := loadAnyTask(s.DB, kind, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.authorize(actor, TaskActionView); err != nil {
+		return nil, err
+	}
+	return subtasksOf(s.DB.Preload("User"), kind, parentID)
+}
+
+// CreateSubtask adds a subtask under a task or collaborative task. Subtasks belong to the parent's
+// project; assigning one to someone who does not work on the parent follows the assignment rules.
+func (s *SubtaskService) CreateSubtask(actor *Actor, kind string, parentID uint, input SubtaskInput) (*models.Task, error) {
+	parent, err := loadAnyTask(s.DB, kind, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return nil, err
+	}
+	if parent.task != nil && parent.task.ParentID != nil {
+		return nil, ErrNestedSubtask
+	}
+
+	title, err := checklistTitle(input.Title)
+	if err != nil {
+		return nil, err
+	}
+	userID := parent.ownerID()
+	if input.UserID != nil {
+		userID = *input.UserID
+	}
+	if err := s.checkSubtaskAssignee(actor, parent, userID); err != nil {
+		return nil, err
+	}
+	priority := input.Priority
+	if priority == "" {
+		priority = parent.priority()
+	}
+
+	subtask := &models.Task{
+		Title:       title,
+		Description: strings.TrimSpace(input.Description),
+		Status:      models.TaskStatusPending,
+		Priority:    priority,
+		UserID:      userID,
+		ProjectID:   parent.projectID(),
+		AssignedAt:  time.Now(),
+		DueDate:     input.DueDate,
+		ParentKind:  kind,
+		ParentID:    &parentID,
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx.Model(&models.Task{}).Where("parent_kind = ? AND parent_id = ?", kind, parentID))
+		if err != nil {
+			return err
+		}
+		subtask.Position = position
+		if err := tx.Create(subtask).Error; err != nil {
+			return err
+		}
+		return rollUp(tx, kind, parentID, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewTaskService(s.DB).loadTask(subtask.ID)
+}
+
+// checkSubtaskAssignee allows subtasks for the people working on the parent; anyone else must be
+// someone the actor may assign work to, and a member of the parent's project
+func (s *SubtaskService) checkSubtaskAssignee(actor *Actor, parent *anyTask, userID uint) error {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("target user not found")
+	}
+	if !user.Active {
+		return ErrUserDeactivated
+	}
+	if parent.isParticipant(userID) {
+		return nil
+	}
+	if err := AuthorizeAssignment(actor, &user); err != nil {
+		return err
+	}
+	if projectID := parent.projectID(); projectID != nil {
+		var count int64
+		if err := s.DB.Model(&models.UserProject{}).Where("user_id = ? AND project_id = ?", userID, *projectID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("target user is not a member of this project")
+		}
+	}
+	return nil
+}
+
+// ReorderSubtasks sets the order of a parent's subtasks; ids must list each subtask once
+func (s *SubtaskService) ReorderSubtasks(actor *Actor, kind string, parentID uint, ids []uint) error {
+	parent, err := loadAnyTask(s.DB, kind, parentID)
+	if err != nil {
+		return err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return err
+	}
+	return reorder(s.DB, &models.Task{}, s.DB.Model(&models.Task{}).Where("parent_kind = ? AND parent_id = ?", kind, parentID), ids)
+}
+
+// PromoteSubtask turns a subtask into a top-level task. It keeps its owner, project and checklist.
+func (s *SubtaskService) PromoteSubtask(actor *Actor, subtaskID uint) (*models.Task, error) {
+	taskService := NewTaskService(s.DB)
+	subtask, err := taskService.loadTask(subtaskID)
+	if err != nil {
+		return nil, err
+	}
+	if subtask.ParentID == nil {
+		return nil, ErrNotSubtask
+	}
+	parent, err := loadAnyTask(s.DB, subtask.ParentKind, *subtask.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return nil, err
+	}
+
+	var changes taskChanges
+	changes.add("parent_kind", "parent_kind", subtask.ParentKind, "")
+	changes.add("parent_id", "parent_id", subtask.ParentID, (*uint)(nil))
+	changes.add("position", "position", subtask.Position, 0)
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyTaskChanges(tx, &models.Task{}, models.TaskKindTask, subtask.ID, actor.UserID, changes); err != nil {
+			return err
+		}
+		return rollUp(tx, parent.kind, parent.id(), actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return taskService.loadTask(subtask.ID)
+}
+
+// GetChecklist returns the checklist of a task or collaborative task in its order
+func (s *SubtaskService) GetChecklist(actor *Actor, kind string, taskID uint) ([]models.ChecklistItem, error) {
+	parent, err := loadAnyTask(s.DB, kind, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.authorize(actor, TaskActionView); err != nil {
+		return nil, err
+	}
+
+	var items []models.ChecklistItem
+	err = s.DB.Where("task_kind = ? AND task_id = ?", kind, taskID).Order("position, id").Find(&items).Error
+	return items, err
+}
+
+// AddChecklistItem appends an item to the checklist of a task or collaborative task
+func (s *SubtaskService) AddChecklistItem(actor *Actor, kind string, taskID uint, title string) (*models.ChecklistItem, error) {
+	parent, err := loadAnyTask(s.DB, kind, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return nil, err
+	}
+	title, err = checklistTitle(title)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &models.ChecklistItem{TaskKind: kind, TaskID: taskID, Title: title, CreatedBy: actor.UserID}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx.Model(&models.ChecklistItem{}).Where("task_kind = ? AND task_id = ?", kind, taskID))
+		if err != nil {
+			return err
+		}
+		item.Position = position
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return rollUp(tx, kind, taskID, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateChecklistItem renames a checklist item or checks it off. Renaming needs the edit rule;
+// checking off is also open to whoever may move the task along.
+func (s *SubtaskService) UpdateChecklistItem(actor *Actor, kind string, taskID, itemID uint, title *string, done *bool) (*models.ChecklistItem, error) {
+	parent, item, err := s.loadChecklistItem(kind, taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if title != nil {
+		if err := parent.authorize(actor, TaskActionEdit); err != nil {
+			return nil, err
+		}
+		text, err := checklistTitle(*title)
+		if err != nil {
+			return nil, err
+		}
+		updates["title"] = text
+	}
+	if done != nil && *done != item.Done {
+		if err := parent.authorizeCheckOff(actor); err != nil {
+			return nil, err
+		}
+		updates["done"] = *done
+		if *done {
+			updates["done_by"] = actor.UserID
+			updates["done_at"] = time.Now()
+		} else {
+			updates["done_by"] = nil
+			updates["done_at"] = nil
+		}
+	}
+	if len(updates) == 0 {
+		return item, nil
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(item).Updates(updates).Error; err != nil {
+			return err
+		}
+		return rollUp(tx, kind, taskID, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, item, err = s.loadChecklistItem(kind, taskID, itemID)
+	return item, err
+}
+
+// DeleteChecklistItem removes an item from a checklist
+func (s *SubtaskService) DeleteChecklistItem(actor *Actor, kind string, taskID, itemID uint) error {
+	parent, item, err := s.loadChecklistItem(kind, taskID, itemID)
+	if err != nil {
+		return err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(item).Error; err != nil {
+			return err
+		}
+		return rollUp(tx, kind, taskID, actor)
+	})
+}
+
+// ReorderChecklist sets the order of a checklist; ids must list each item once
+func (s *SubtaskService) ReorderChecklist(actor *Actor, kind string, taskID uint, ids []uint) error {
+	parent, err := loadAnyTask(s.DB, kind, taskID)
+	if err != nil {
+		return err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return err
+	}
+	return reorder(s.DB, &models.ChecklistItem{}, s.DB.Model(&models.ChecklistItem{}).Where("task_kind = ? AND task_id = ?", kind, taskID), ids)
+}
+
+// ConvertChecklistItem turns a checklist item into a subtask owned by the parent's owner or lead.
+// A checked-off item becomes a completed subtask.
+func (s *SubtaskService) ConvertChecklistItem(actor *Actor, kind string, taskID, itemID uint) (*models.Task, error) {
+	parent, item, err := s.loadChecklistItem(kind, taskID, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.authorize(actor, TaskActionEdit); err != nil {
+		return nil, err
+	}
+	if parent.task != nil && parent.task.ParentID != nil {
+		return nil, ErrNestedSubtask
+	}
+
+	now := time.Now()
+	subtask := &models.Task{
+		Title:      item.Title,
+		Status:     models.TaskStatusPending,
+		Priority:   parent.priority(),
+		UserID:     parent.ownerID(),
+		ProjectID:  parent.projectID(),
+		AssignedAt: now,
+		ParentKind: kind,
+		ParentID:   &taskID,
+	}
+	if item.Done {
+		subtask.Status = models.TaskStatusCompleted
+		subtask.StartedAt = item.DoneAt
+		subtask.CompletedAt = item.DoneAt
+		subtask.Progress = 100
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		position, err := nextPosition(tx.Model(&models.Task{}).Where("parent_kind = ? AND parent_id = ?", kind, taskID))
+		if err != nil {
+			return err
+		}
+		subtask.Position = position
+		if err := tx.Create(subtask).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(item).Error; err != nil {
+			return err
+		}
+		return rollUp(tx, kind, taskID, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewTaskService(s.DB).loadTask(subtask.ID)
+}
+
+// loadChecklistItem loads a checklist item with the task it belongs to
+func (s *SubtaskService) loadChecklistItem(kind string, taskID, itemID uint) (*anyTask, *models.ChecklistItem, error) {
+	parent, err := loadAnyTask(s.DB, kind, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var item models.ChecklistItem
+	if err := s.DB.Where("task_kind = ? AND task_id = ?", kind, taskID).First(&item, itemID).Error; err != nil {
+		return nil, nil, ErrChecklistItemNotFound
+	}
+	return parent, &item, nil
+}
+
+// checklistTitle trims and checks the title of a checklist item or subtask
+func checklistTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	switch {
+	case title == "":
+		return "", errors.New("title is required")
+	case utf8.RuneCountInString(title) > TaskTitleMaxLength:
+		return "", fmt.Errorf("title must be at most %d characters", TaskTitleMaxLength)
+	}
+	return title, nil
+}
+
+// subtasksOf loads the subtasks of a task or collaborative task in their order
+func subtasksOf(db *gorm.DB, kind string, parentID uint) ([]models.Task, error) {
+	var subtasks []models.Task
+	err := db.Where("parent_kind = ? AND parent_id = ?", kind, parentID).Order("position, id").Find(&subtasks).Error
+	return subtasks, err
+}
+
+// nextPosition returns the position after the last row of an ordered list
+func nextPosition(query *gorm.DB) (int, error) {
+	var position int
+	err := query.Select("COALESCE(MAX(position) + 1, 0)").Row().Scan(&position)
+	return position, err
+}
+
+// reorder sets the positions of the rows of an ordered list to their index in ids
+func reorder(db *gorm.DB, model interface{}, list *gorm.DB, ids []uint) error {
+	var existing []uint
+	if err := list.Pluck("id", &existing).Error; err != nil {
+		return err
+	}
+	if !sameIDs(existing, ids) {
+		return ErrInvalidOrder
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range ids {
+			if err := tx.Model(model).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sameIDs reports whether ids lists each of the existing ids exactly once
+func sameIDs(existing, ids []uint) bool {
+	if len(existing) != len(ids) {
+		return false
+	}
+	remaining := make(map[uint]bool, len(existing))
+	for _, id := range existing {
+		remaining[id] = true
+	}
+	for _, id := range ids {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
+// hasChildren reports whether a task or collaborative task has subtasks or checklist items
+func hasChildren(db *gorm.DB, kind string, taskID uint) (bool, error) {
+	var subtasks, items int64
+	if err := db.Model(&models.Task{}).Where("parent_kind = ? AND parent_id = ?", kind, taskID).Count(&subtasks).Error; err != nil {
+		return false, err
+	}
+	if err := db.Model(&models.ChecklistItem{}).Where("task_kind = ? AND task_id = ?", kind, taskID).Count(&items).Error; err != nil {
+		return false, err
+	}
+	return subtasks+items > 0, nil
+}
+
+// deleteChildren removes the subtasks, with their dependencies and checklists, and the checklist
+// of a task or collaborative task being deleted
+func deleteChildren(db *gorm.DB, kind string, taskID uint) error {
+	var subtaskIDs []uint
+	if err := db.Model(&models.Task{}).Where("parent_kind = ? AND parent_id = ?", kind, taskID).Pluck("id", &subtaskIDs).Error; err != nil {
+		return err
+	}
+	if err := deleteDependencies(db, models.TaskKindTask, subtaskIDs); err != nil {
+		return err
+	}
+	if len(subtaskIDs) > 0 {
+		if err := db.Where("task_kind = ? AND task_id IN ?", models.TaskKindTask, subtaskIDs).Delete(&models.ChecklistItem{}).Error; err != nil {
+			return err
+		}
+	}
+	if err := db.Where("parent_kind = ? AND parent_id = ?", kind, taskID).Delete(&models.Task{}).Error; err != nil {
+		return err
+	}
+	return db.Where("task_kind = ? AND task_id = ?", kind, taskID).Delete(&models.ChecklistItem{}).Error
+}
+
+// rolledUpProgress averages the children of a task: subtasks count with their own progress, or 100
+// once completed, and checklist items with 0 or 100. Cancelled subtasks are left out; ok is false
+// when nothing is left to count.
+func rolledUpProgress(subtasks []models.Task, checklistTotal, checklistDone int64) (progress int, ok bool) {
+	var count, sum int64
+	for _, subtask := range subtasks {
+		switch subtask.Status {
+		case models.TaskStatusCancelled:
+			continue
+		case models.TaskStatusCompleted:
+			sum += 100
+		default:
+			sum += int64(subtask.Progress)
+		}
+		count++
+	}
+	count += checklistTotal
+	sum += checklistDone * 100
+	if count == 0 {
+		return 0, false
+	}
+	return int(sum / count), true
+}
+
+// rollUp recomputes the progress of a task or collaborative task from its children, moves it along
+// its workflow to match and records the changes as made by the actor. A subtask that changes rolls
+// up into its own parent in turn.
+func rollUp(db *gorm.DB, kind string, taskID uint, actor *Actor) error {
+	parent, err := loadAnyTask(db, kind, taskID)
+	if err != nil {
+		return err
+	}
+
+	subtasks, err := subtasksOf(db, kind, taskID)
+	if err != nil {
+		return err
+	}
+	var checklistTotal, checklistDone int64
+	if err := db.Model(&models.ChecklistItem{}).Where("task_kind = ? AND task_id = ?", kind, taskID).Count(&checklistTotal).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.ChecklistItem{}).Where("task_kind = ? AND task_id = ? AND done = ?", kind, taskID, true).Count(&checklistDone).Error; err != nil {
+		return err
+	}
+	progress, ok := rolledUpProgress(subtasks, checklistTotal, checklistDone)
+	if !ok {
+		return nil
+	}
+
+	var changes, moves taskChanges
+	workflow := GetTaskWorkflow(kind)
+	if task := parent.task; task != nil {
+		changes.add("progress", "progress", task.Progress, progress)
+		workflow.rollUp(&moves, task.ID, task.Status, taskTimestamps(task), progress, actor.canReopen())
+	} else {
+		task := parent.collaborative
+		changes.add("progress", "progress", task.Progress, progress)
+		workflow.rollUp(&moves, task.ID, task.Status, collaborativeTaskTimestamps(task), progress, actor.canReopen())
+	}
+	// A task waiting on open blockers keeps its status and only records the progress
+	if err := checkBlockers(db, TaskRef{Kind: kind, ID: taskID}, moves); err != nil {
+		if !errors.Is(err, ErrTaskBlocked) {
+			return err
+		}
+		moves = nil
+	}
+	changes = append(changes, moves...)
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := applyTaskChanges(db, parent.model(), kind, taskID, actor.UserID, changes); err != nil {
+		return err
+	}
+	if parent.task != nil {
+		return rollUpParent(db, parent.task, actor)
+	}
+	return nil
+}
+
+// rollUpParent rolls a changed subtask up into its parent; top-level tasks are left alone
+func rollUpParent(db *gorm.DB, task *models.Task, actor *Actor) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	return rollUp(db, task.ParentKind, *task.ParentID, actor)
+}
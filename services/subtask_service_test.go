@@ -0,0 +1,43 @@
+package services
+
+import (
+	"project-x/models"
+	"testing"
+)
+
+func TestRolledUpProgress(t *testing.T) {
+	subtasks := []models.Task{
+		{Status: models.TaskStatusCompleted},
+		{Status: models.TaskStatusInProgress, Progress: 50},
+		{Status: models.TaskStatusCancelled, Progress: 90},
+	}
+
+	// Two subtasks (100 and 50) and two checklist items, one done: (100 + 50 + 100 + 0) / 4
+	if progress, ok := rolledUpProgress(subtasks, 2, 1); !ok || progress != 62 {
+		t.Errorf("got %d, %v, want 62", progress, ok)
+	}
+	if _, ok := rolledUpProgress(subtasks[2:], 0, 0); ok {
+		t.Error("only cancelled subtasks should leave nothing to roll up")
+	}
+	if progress, ok := rolledUpProgress(nil, 3, 3); !ok || progress != 100 {
+		t.Errorf("finished checklist: got %d, %v, want 100", progress, ok)
+	}
+}
+
+func TestSameIDs(t *testing.T) {
+	existing := []uint{4, 7, 9}
+	cases := []struct {
+		ids  []uint
+		want bool
+	}{
+		{[]uint{9, 4, 7}, true},
+		{[]uint{4, 7}, false},
+		{[]uint{4, 7, 7}, false},
+		{[]uint{4, 7, 8}, false},
+	}
+	for _, tc := range cases {
+		if got := sameIDs(existing, tc.ids); got != tc.want {
+			t.Errorf("sameIDs(%v, %v) = %v, want %v", existing, tc.ids, got, tc.want)
+		}
+	}
+}
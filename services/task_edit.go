@@ -0,0 +1,538 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"project-x/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTaskPatch is wrapped by TaskFieldErrors and returned for bodies that are not a JSON object
+var ErrInvalidTaskPatch = errors.New("invalid task update")
+
+// TaskTitleMaxLength caps task titles set through an edit
+const TaskTitleMaxLength = 200
+
+// TaskFieldErrors maps the fields of a rejected merge patch to what is wrong with them
+type TaskFieldErrors map[string]string
+
+func (e TaskFieldErrors) Error() string {
+	parts := make([]string, 0, len(e))
+	for field, problem := range e {
+		parts = append(parts, field+": "+problem)
+	}
+	sort.Strings(parts)
+	return ErrInvalidTaskPatch.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e TaskFieldErrors) Unwrap() error {
+	return ErrInvalidTaskPatch
+}
+
+// Fields a merge patch may change on each kind of task
+var (
+	editableTaskFields              = []string{"title", "description", "due_date", "project_id", "status", "priority"}
+	editableCollaborativeTaskFields = []string{"title", "description", "due_date", "project_id", "status", "priority", "complexity", "progress"}
+)
+
+// readOnlyTaskFields are part of the task resource but cannot be patched; the value says why
+var readOnlyTaskFields = map[string]string{
+	"id":           "is read-only",
+	"created_at":   "is read-only",
+	"updated_at":   "is read-only",
+	"assigned_at":  "is read-only",
+	"workspace_id": "is read-only",
+	"started_at":   "is set by the status workflow",
+	"completed_at": "is set by the status workflow",
+	"user_id":      "use the assign or reassign endpoints to change the owner",
+	"lead_user_id": "is changed by offboarding only",
+	"participants": "use the participants endpoints",
+	"progress":     "is rolled up from the subtasks and checklist items",
+	"parent_kind":  "use the subtask endpoints",
+	"parent_id":    "use the subtask endpoints",
+	"position":     "use the subtask endpoints",
+}
+
+// Allowed values for the string enums of tasks and collaborative tasks
+var (
+	validTaskStatuses     = []string{string(models.TaskStatusPending), string(models.TaskStatusInProgress), string(models.TaskStatusCompleted), string(models.TaskStatusCancelled)}
+	validTaskPriorities   = []string{string(models.PriorityHigh), string(models.PriorityMedium), string(models.PriorityLow)}
+	validTaskComplexities = []string{"simple", "medium", "complex"}
+)
+
+// taskPatch reads the fields of a JSON merge patch (RFC 7396) and collects validation errors.
+// A field left out keeps its value and null removes it.
+type taskPatch struct {
+	fields map[string]json.RawMessage
+	errors TaskFieldErrors
+}
+
+// newTaskPatch decodes a merge patch and rejects fields that are unknown or cannot be changed
+func newTaskPatch(body []byte, editable []string) (*taskPatch, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: the body must be a JSON object", ErrInvalidTaskPatch)
+	}
+
+	patch := &taskPatch{fields: fields, errors: TaskFieldErrors{}}
+	for name := range fields {
+		if isOneOf(editable, name) {
+			continue
+		}
+		if reason, known := readOnlyTaskFields[name]; known {
+			patch.errors[name] = reason
+		} else {
+			patch.errors[name] = "unknown field"
+		}
+	}
+	return patch, nil
+}
+
+// raw returns a field's JSON value and whether it is null; ok is false when the field is absent
+func (p *taskPatch) raw(name string) (value json.RawMessage, null bool, ok bool) {
+	value, ok = p.fields[name]
+	if !ok {
+		return nil, false, false
+	}
+	return value, bytes.Equal(bytes.TrimSpace(value), []byte("null")), true
+}
+
+// text reads a required string field, trimming surrounding whitespace
+func (p *taskPatch) text(name string, maxLength int) (string, bool) {
+	raw, null, ok := p.raw(name)
+	if !ok {
+		return "", false
+	}
+	var value string
+	if null {
+		p.errors[name] = "cannot be removed"
+		return "", false
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		p.errors[name] = "must be a string"
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		p.errors[name] = "must not be empty"
+		return "", false
+	case maxLength > 0 && utf8.RuneCountInString(value) > maxLength:
+		p.errors[name] = fmt.Sprintf("must be at most %d characters", maxLength)
+		return "", false
+	}
+	return value, true
+}
+
+// choice reads a required string field that must be one of the allowed values
+func (p *taskPatch) choice(name string, allowed []string) (string, bool) {
+	value, ok := p.text(name, 0)
+	if !ok {
+		return "", false
+	}
+	if !isOneOf(allowed, value) {
+		p.errors[name] = "must be one of " + strings.Join(allowed, ", ")
+		return "", false
+	}
+	return value, true
+}
+
+// timestamp reads an optional RFC 3339 time
+func (p *taskPatch) timestamp(name string) (*time.Time, bool) {
+	raw, null, ok := p.raw(name)
+	if !ok {
+		return nil, false
+	}
+	if null {
+		return nil, true
+	}
+	var value time.Time
+	if err := json.Unmarshal(raw, &value); err != nil {
+		p.errors[name] = "must be an RFC 3339 time or null"
+		return nil, false
+	}
+	return &value, true
+}
+
+// id reads an optional positive id
+func (p *taskPatch) id(name string) (*uint, bool) {
+	raw, null, ok := p.raw(name)
+	if !ok {
+		return nil, false
+	}
+	if null {
+		return nil, true
+	}
+	var value uint
+	if err := json.Unmarshal(raw, &value); err != nil || value == 0 {
+		p.errors[name] = "must be a positive id or null"
+		return nil, false
+	}
+	return &value, true
+}
+
+// integer reads a required whole number within a range
+func (p *taskPatch) integer(name string, min, max int) (int, bool) {
+	raw, null, ok := p.raw(name)
+	if !ok {
+		return 0, false
+	}
+	var value int
+	if null {
+		p.errors[name] = "cannot be removed"
+		return 0, false
+	}
+	if err := json.Unmarshal(raw, &value); err != nil || value < min || value > max {
+		p.errors[name] = fmt.Sprintf("must be a whole number between %d and %d", min, max)
+		return 0, false
+	}
+	return value, true
+}
+
+// err returns the collected validation errors, or nil when the patch is valid
+func (p *taskPatch) err() error {
+	if len(p.errors) == 0 {
+		return nil
+	}
+	return p.errors
+}
+
+// taskChange is one field an update changes
+type taskChange struct {
+	field    string // JSON name, recorded in the history
+	column   string
+	value    interface{}
+	oldValue *string
+	newValue *string
+}
+
+// taskChanges collects the fields an update actually changes
+type taskChanges []taskChange
+
+// add records a change unless the new value equals the old one
+func (c *taskChanges) add(field, column string, oldValue, newValue interface{}) {
+	before, after := historyValue(oldValue), historyValue(newValue)
+	if (before == nil && after == nil) || (before != nil && after != nil && *before == *after) {
+		return
+	}
+	*c = append(*c, taskChange{field: field, column: column, value: newValue, oldValue: before, newValue: after})
+}
+
+// only reports whether every change is to one of the given fields
+func (c taskChanges) only(fields ...string) bool {
+	for _, change := range c {
+		if !isOneOf(fields, change.field) {
+			return false
+		}
+	}
+	return true
+}
+
+// historyValue formats a field value for the task history; empty values become nil
+func historyValue(value interface{}) *string {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		text = v.UTC().Format(time.RFC3339)
+	case time.Time:
+		text = v.UTC().Format(time.RFC3339)
+	case *uint:
+		if v == nil {
+			return nil
+		}
+		text = strconv.FormatUint(uint64(*v), 10)
+	case uint:
+		text = strconv.FormatUint(uint64(v), 10)
+	case int:
+		text = strconv.Itoa(v)
+	case models.TaskStatus:
+		text = string(v)
+	case models.Priority:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		text = fmt.Sprint(v)
+	}
+	if text == "" {
+		return nil
+	}
+	return &text
+}
+
+// status returns the status change among the changes; ok is false when the status stays
+func (c taskChanges) status() (from, to models.TaskStatus, ok bool) {
+	for _, change := range c {
+		if change.field == "status" && change.oldValue != nil && change.newValue != nil {
+			return models.TaskStatus(*change.oldValue), models.TaskStatus(*change.newValue), true
+		}
+	}
+	return "", "", false
+}
+
+// applyTaskChanges writes the changes to one task and records them in the task history. Changes
+// that would start a task while its blockers are open are rejected with a BlockedTaskError.
+func applyTaskChanges(db *gorm.DB, model interface{}, kind string, taskID, actorID uint, changes taskChanges) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := checkBlockers(db, TaskRef{Kind: kind, ID: taskID}, changes); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := make(map[string]interface{}, len(changes))
+	history := make([]models.TaskHistory, 0, len(changes))
+	for _, change := range changes {
+		updates[change.column] = change.value
+		history = append(history, models.TaskHistory{
+			TaskKind:  kind,
+			TaskID:    taskID,
+			Field:     change.field,
+			OldValue:  change.oldValue,
+			NewValue:  change.newValue,
+			ChangedBy: actorID,
+			ChangedAt: now,
+		})
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(model).Where("id = ?", taskID).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&history).Error
+	})
+}
+
+// checkProjectMembership rejects moving a task into a project the actor may not add work to, or
+// its owner does not belong to
+func checkProjectMembership(db *gorm.DB, actor *Actor, patch *taskPatch, userID uint, projectID *uint) error {
+	if projectID == nil {
+		return nil
+	}
+	if err := AuthorizeTaskProject(actor, *projectID); err != nil {
+		return err
+	}
+	var count int64
+	if err := db.Model(&models.UserProject{}).Where("user_id = ? AND project_id = ?", userID, *projectID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		patch.errors["project_id"] = "the task owner is not a member of this project"
+	}
+	return nil
+}
+
+// taskFieldChanges maps the patched fields of a task to their columns. The status is left to the
+// task workflow; the caller checks a new project first.
+func taskFieldChanges(patch *taskPatch, task *models.Task) taskChanges {
+	var changes taskChanges
+	if title, ok := patch.text("title", TaskTitleMaxLength); ok {
+		changes.add("title", "title", task.Title, title)
+	}
+	if description, ok := patch.text("description", 0); ok {
+		changes.add("description", "description", task.Description, description)
+	}
+	if dueDate, ok := patch.timestamp("due_date"); ok {
+		changes.add("due_date", "due_date", task.DueDate, dueDate)
+	}
+	if projectID, ok := patch.id("project_id"); ok {
+		changes.add("project_id", "project_id", task.ProjectID, projectID)
+	}
+	if priority, ok := patch.choice("priority", validTaskPriorities); ok {
+		changes.add("priority", "priority", task.Priority, models.Priority(priority))
+	}
+	return changes
+}
+
+// collaborativeTaskFieldChanges maps the patched fields of a collaborative task to their columns.
+// The status and progress are left to the task workflow; the caller checks a new project first.
+func collaborativeTaskFieldChanges(patch *taskPatch, task *models.CollaborativeTask) taskChanges {
+	var changes taskChanges
+	if title, ok := patch.text("title", TaskTitleMaxLength); ok {
+		changes.add("title", "title", task.Title, title)
+	}
+	if description, ok := patch.text("description", 0); ok {
+		changes.add("description", "description", task.Description, description)
+	}
+	if dueDate, ok := patch.timestamp("due_date"); ok {
+		changes.add("due_date", "due_date", task.DueDate, dueDate)
+	}
+	if projectID, ok := patch.id("project_id"); ok {
+		changes.add("project_id", "project_id", task.ProjectID, projectID)
+	}
+	if priority, ok := patch.choice("priority", validTaskPriorities); ok {
+		changes.add("priority", "priority", task.Priority, models.Priority(priority))
+	}
+	if complexity, ok := patch.choice("complexity", validTaskComplexities); ok {
+		changes.add("complexity", "complexity", task.Complexity, complexity)
+	}
+	return changes
+}
+
+// EditTask applies a JSON merge patch to a task and records each changed field in the task history.
+// Fields left out keep their value; null clears the due date or moves the task out of its project.
+// Status changes follow the task workflow.
+func (s *TaskService) EditTask(actor *Actor, taskID uint, body []byte) (*models.Task, error) {
+	patch, err := newTaskPatch(body, editableTaskFields)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeTask(actor, task, TaskActionEdit); err != nil {
+		return nil, err
+	}
+
+	if projectID, ok := patch.id("project_id"); ok {
+		if err := checkProjectMembership(s.DB, actor, patch, task.UserID, projectID); err != nil {
+			return nil, err
+		}
+	}
+	changes := taskFieldChanges(patch, task)
+	status, statusSet := patch.choice("status", validTaskStatuses)
+	if err := patch.err(); err != nil {
+		return nil, err
+	}
+
+	if statusSet {
+		timestamps := taskTimestamps(task)
+		if err := GetTaskWorkflow(models.TaskKindTask).transition(&changes, task.ID, task.Status, models.TaskStatus(status), &timestamps, actor.canReopen()); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyTaskChanges(tx, &models.Task{}, models.TaskKindTask, task.ID, actor.UserID, changes); err != nil {
+			return err
+		}
+		return rollUpParent(tx, task, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.loadTask(taskID)
+}
+
+// EditCollaborativeTask applies a JSON merge patch to a collaborative task and records each changed
+// field in the task history. Contributors may patch the progress alone, which moves the task along its workflow.
+func (s *CollaborativeTaskService) EditCollaborativeTask(actor *Actor, taskID uint, body []byte) (*models.CollaborativeTask, error) {
+	patch, err := newTaskPatch(body, editableCollaborativeTaskFields)
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionUpdateProgress); err != nil {
+		return nil, err
+	}
+
+	if projectID, ok := patch.id("project_id"); ok {
+		if err := checkProjectMembership(s.DB, actor, patch, task.LeadUserID, projectID); err != nil {
+			return nil, err
+		}
+	}
+	changes := collaborativeTaskFieldChanges(patch, task)
+	progress, progressSet := patch.integer("progress", 0, 100)
+	if progressSet {
+		rolledUp, err := hasChildren(s.DB, models.TaskKindCollaborative, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if rolledUp {
+			patch.errors["progress"] = "is rolled up from the subtasks and checklist items"
+			progressSet = false
+		} else {
+			changes.add("progress", "progress", task.Progress, progress)
+		}
+	}
+	status, statusSet := patch.choice("status", validTaskStatuses)
+	if err := patch.err(); err != nil {
+		return nil, err
+	}
+
+	// Progress alone is open to contributors, everything else needs the edit rule
+	if !changes.only("progress") || statusSet {
+		if err := AuthorizeCollaborativeTask(actor, task, TaskActionEdit); err != nil {
+			return nil, err
+		}
+	}
+
+	workflow := GetTaskWorkflow(models.TaskKindCollaborative)
+	if statusSet {
+		timestamps := collaborativeTaskTimestamps(task)
+		if err := workflow.transition(&changes, task.ID, task.Status, models.TaskStatus(status), &timestamps, actor.canReopen()); err != nil {
+			return nil, err
+		}
+	} else if progressSet {
+		workflow.followProgress(&changes, task, progress)
+	}
+
+	if err := applyTaskChanges(s.DB, &models.CollaborativeTask{}, models.TaskKindCollaborative, task.ID, actor.UserID, changes); err != nil {
+		return nil, err
+	}
+	return loadCollaborativeTask(s.DB, taskID)
+}
+
+// GetTaskHistory returns the recorded changes of a task, newest first
+func (s *TaskService) GetTaskHistory(actor *Actor, taskID uint) ([]models.TaskHistory, error) {
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeTask(actor, task, TaskActionViewHistory); err != nil {
+		return nil, err
+	}
+	return taskHistory(s.DB, models.TaskKindTask, task.ID)
+}
+
+// GetCollaborativeTaskHistory returns the recorded changes of a collaborative task, newest first
+func (s *CollaborativeTaskService) GetCollaborativeTaskHistory(actor *Actor, taskID uint) ([]models.TaskHistory, error) {
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionViewHistory); err != nil {
+		return nil, err
+	}
+	return taskHistory(s.DB, models.TaskKindCollaborative, task.ID)
+}
+
+// taskHistory loads the history entries of one task with the users who made them
+func taskHistory(db *gorm.DB, kind string, taskID uint) ([]models.TaskHistory, error) {
+	var history []models.TaskHistory
+	err := db.Where("task_kind = ? AND task_id = ?", kind, taskID).
+		Preload("Actor").
+		Order("changed_at DESC, id DESC").
+		Find(&history).Error
+	return history, err
+}
+
+// isOneOf reports whether a value is in the list
+func isOneOf(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestTaskPatchValidation(t *testing.T) {
+	body := `{"title": "  ", "description": null, "due_date": "tomorrow", "project_id": -1, "status": "done", "user_id": 3, "colour": "red"}`
+	patch, err := newTaskPatch([]byte(body), editableTaskFields)
+	if err != nil {
+		t.Fatalf("decode patch: %v", err)
+	}
+	patch.text("title", TaskTitleMaxLength)
+	patch.text("description", 0)
+	patch.timestamp("due_date")
+	patch.id("project_id")
+	patch.choice("status", validTaskStatuses)
+
+	var fieldErrors TaskFieldErrors
+	if err := patch.err(); !errors.As(err, &fieldErrors) || !errors.Is(err, ErrInvalidTaskPatch) {
+		t.Fatalf("got %v, want field errors", err)
+	}
+	want := []string{"colour", "description", "due_date", "project_id", "status", "title", "user_id"}
+	if got := sortedFieldErrors(fieldErrors); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields with errors: got %v, want %v", got, want)
+	}
+
+	if _, err := newTaskPatch([]byte(`["title"]`), editableTaskFields); !errors.Is(err, ErrInvalidTaskPatch) {
+		t.Errorf("array body: got %v, want an invalid patch error", err)
+	}
+}
+
+func TestTaskPatchChanges(t *testing.T) {
+	dueDate := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	projectID := uint(4)
+	task := models.Task{Title: "Write report", Description: "Q1 numbers", Priority: models.PriorityMedium, DueDate: &dueDate, ProjectID: &projectID}
+
+	// Absent fields keep their value, null clears and unchanged values are not recorded
+	body := `{"title": " Write report ", "description": "Q1 and Q2 numbers", "due_date": null, "project_id": 4, "priority": "high"}`
+	patch, err := newTaskPatch([]byte(body), editableTaskFields)
+	if err != nil {
+		t.Fatalf("decode patch: %v", err)
+	}
+
+	changes := taskFieldChanges(patch, &task)
+	if err := patch.err(); err != nil {
+		t.Fatalf("valid patch rejected: %v", err)
+	}
+
+	got := make(map[string][2]string)
+	for _, change := range changes {
+		got[change.field] = [2]string{stringValue(change.oldValue), stringValue(change.newValue)}
+	}
+	want := map[string][2]string{
+		"description": {"Q1 numbers", "Q1 and Q2 numbers"},
+		"due_date":    {"2026-03-01T09:00:00Z", "<nil>"},
+		"priority":    {"medium", "high"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %v, want %v", got, want)
+	}
+}
+
+func sortedFieldErrors(fieldErrors TaskFieldErrors) []string {
+	fields := make([]string, 0, len(fieldErrors))
+	for field := range fieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return "<nil>"
+	}
+	return *value
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPriority = errors.New("invalid priority. Must be 'high', 'medium', or 'low'")
+	ErrInvalidSort     = errors.New("invalid sort. Use priority, due_date, created_at, title or status, with a '-' prefix for descending order")
+)
+
+// ParsePriority checks a requested priority; empty means medium
+func ParsePriority(priority string) (models.Priority, error) {
+	if priority == "" {
+		return models.PriorityMedium, nil
+	}
+	if !models.Priority(priority).Valid() {
+		return "", ErrInvalidPriority
+	}
+	return models.Priority(priority), nil
+}
+
+// taskSortColumns are the fields task lists can be sorted by
+var taskSortColumns = map[string]string{
+	"priority":   "priority",
+	"due_date":   "due_date",
+	"created_at": "created_at",
+	"title":      "title",
+	"status":     "status",
+}
+
+// TaskListOptions filter and order a task list. The zero value lists everything, newest first.
+type TaskListOptions struct {
+	Priorities []models.Priority
+	SortField  string // One of the taskSortColumns keys; empty sorts by creation time
+	Descending bool
+}
+
+// ParseTaskListOptions reads a comma-separated priority filter (e.g. "high,medium") and a sort
+// field with an optional "-" prefix for descending order (e.g. "-priority")
+func ParseTaskListOptions(priorities, sort string) (TaskListOptions, error) {
+	var options TaskListOptions
+	for _, value := range strings.Split(priorities, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !models.Priority(value).Valid() {
+			return options, ErrInvalidPriority
+		}
+		options.Priorities = append(options.Priorities, models.Priority(value))
+	}
+
+	if sort != "" {
+		options.Descending = strings.HasPrefix(sort, "-")
+		options.SortField = strings.TrimPrefix(sort, "-")
+		if _, exists := taskSortColumns[options.SortField]; !exists {
+			return options, ErrInvalidSort
+		}
+	}
+	return options, nil
+}
+
+// scope returns a GORM scope applying the options to a query on tasks or collaborative_tasks.
+// Ties keep the newest tasks first.
+func (o TaskListOptions) scope(table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(o.Priorities) > 0 {
+			db = db.Where(table+".priority IN ?", o.Priorities)
+		}
+
+		direction := "ASC"
+		if o.Descending {
+			direction = "DESC"
+		}
+		switch o.SortField {
+		case "":
+		case "priority":
+			db = db.Order(priorityRankSQL(table+".priority") + " " + direction)
+		case "due_date":
+			db = db.Order(table + ".due_date " + direction + " NULLS LAST")
+		default:
+			db = db.Order(table + "." + taskSortColumns[o.SortField] + " " + direction)
+		}
+		return db.Order(table + ".created_at DESC")
+	}
+}
+
+// priorityRankSQL ranks a priority column by models.Priority.Weight
+func priorityRankSQL(column string) string {
+	var rank strings.Builder
+	rank.WriteString("CASE " + column)
+	for _, priority := range models.Priorities {
+		fmt.Fprintf(&rank, " WHEN '%s' THEN %d", priority, priority.Weight())
+	}
+	rank.WriteString(" ELSE 0 END")
+	return rank.String()
+}
+
+// priorityCounts holds task counts by priority and status
+type priorityCounts map[models.Priority]map[models.TaskStatus]int64
+
+// add counts the rows of a task or collaborative task query by priority and status
+func (p priorityCounts) add(query *gorm.DB, table string) error {
+	var rows []struct {
+		Priority models.Priority
+		Status   models.TaskStatus
+		Count    int64
+	}
+	err := query.Select(table + ".priority AS priority, " + table + ".status AS status, COUNT(*) AS count").
+		Group(table + ".priority, " + table + ".status").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if p[row.Priority] == nil {
+			p[row.Priority] = make(map[models.TaskStatus]int64)
+		}
+		p[row.Priority][row.Status] += row.Count
+	}
+	return nil
+}
+
+// weightedCompletionRate is the share of work completed when each task counts with the
+// weight of its priority, so finishing high priority work moves the rate most
+func (p priorityCounts) weightedCompletionRate() float64 {
+	var total, completed int64
+	for priority, statuses := range p {
+		weight := int64(priority.Weight())
+		for status, count := range statuses {
+			total += weight * count
+			if status == models.TaskStatusCompleted {
+				completed += weight * count
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(completed) / float64(total) * 100
+}
+
+// breakdown returns the counts of each priority with the priority-weighted completion rate
+func (p priorityCounts) breakdown() map[string]interface{} {
+	byPriority := make(map[string]interface{}, len(models.Priorities))
+	for _, priority := range models.Priorities {
+		statuses := p[priority]
+		var total int64
+		for _, count := range statuses {
+			total += count
+		}
+		completionRate := 0.0
+		if total > 0 {
+			completionRate = float64(statuses[models.TaskStatusCompleted]) / float64(total) * 100
+		}
+		byPriority[string(priority)] = map[string]interface{}{
+			"total":           total,
+			"open":            statuses[models.TaskStatusPending] + statuses[models.TaskStatusInProgress],
+			"completed":       statuses[models.TaskStatusCompleted],
+			"cancelled":       statuses[models.TaskStatusCancelled],
+			"weight":          priority.Weight(),
+			"completion_rate": completionRate,
+		}
+	}
+
+	return map[string]interface{}{
+		"by_priority":              byPriority,
+		"weighted_completion_rate": p.weightedCompletionRate(),
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"reflect"
+	"testing"
+)
+
+func TestParseTaskListOptions(t *testing.T) {
+	options, err := ParseTaskListOptions("high, low", "-priority")
+	if err != nil {
+		t.Fatalf("valid options rejected: %v", err)
+	}
+	want := TaskListOptions{Priorities: []models.Priority{models.PriorityHigh, models.PriorityLow}, SortField: "priority", Descending: true}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("got %+v, want %+v", options, want)
+	}
+
+	if options, err := ParseTaskListOptions("", ""); err != nil || !reflect.DeepEqual(options, TaskListOptions{}) {
+		t.Errorf("empty query: got %+v, %v, want the zero options", options, err)
+	}
+	if _, err := ParseTaskListOptions("urgent", ""); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("unknown priority: got %v, want ErrInvalidPriority", err)
+	}
+	if _, err := ParseTaskListOptions("", "-user_id"); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("unknown sort field: got %v, want ErrInvalidSort", err)
+	}
+}
+
+func TestPriorityCounts(t *testing.T) {
+	counts := priorityCounts{
+		models.PriorityHigh: {models.TaskStatusCompleted: 1, models.TaskStatusPending: 1},
+		models.PriorityLow:  {models.TaskStatusCompleted: 3},
+	}
+
+	// Weighted: 1*3 (high) + 3*1 (low) completed out of 2*3 + 3*1
+	completed, total := 6.0, 9.0
+	if got, want := counts.weightedCompletionRate(), completed/total*100; got != want {
+		t.Errorf("weighted completion rate: got %v, want %v", got, want)
+	}
+
+	high := counts.breakdown()["by_priority"].(map[string]interface{})["high"].(map[string]interface{})
+	if high["total"] != int64(2) || high["open"] != int64(1) || high["completion_rate"] != 50.0 {
+		t.Errorf("high priority breakdown: got %v", high)
+	}
+	if got := (priorityCounts{}).weightedCompletionRate(); got != 0 {
+		t.Errorf("no tasks: got %v, want 0", got)
+	}
+}
@@ -2,12 +2,16 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"project-x/models"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// ErrTaskNotFound is returned when a task or collaborative task does not exist
+var ErrTaskNotFound = errors.New("task not found")
+
 type TaskService struct {
 	DB *gorm.DB
 }
@@ -17,7 +21,7 @@ func NewTaskService(db *gorm.DB) *TaskService {
 }
 
 // CreateTask creates a new task
-func (s *TaskService) CreateTask(title, description string, userID uint, projectID *uint, dueDate *time.Time) (*models.Task, error) {
+func (s *TaskService) CreateTask(title, description string, userID uint, projectID *uint, dueDate *time.Time, priority models.Priority) (*models.Task, error) {
 	// Verify user exists
 	var user models.User
 	if err := s.DB.First(&user, userID).Error; err != nil {
@@ -36,6 +40,7 @@ func (s *TaskService) CreateTask(title, description string, userID uint, project
 		Title:       title,
 		Description: description,
 		Status:      models.TaskStatusPending,
+		Priority:    priority,
 		UserID:      userID,
 		ProjectID:   projectID,
 		AssignedAt:  time.Now(),
@@ -49,23 +54,30 @@ func (s *TaskService) CreateTask(title, description string, userID uint, project
 	return task, nil
 }
 
-// CreateTaskForUser allows Admin/Manager to create tasks for specific users
-func (s *TaskService) CreateTaskForUser(title, description string, userID, assignedBy uint, projectID *uint, dueDate *time.Time, priority string) (*models.Task, error) {
+// CreateTaskForUser lets managers and users with task.assign create tasks for specific users
+func (s *TaskService) CreateTaskForUser(title, description string, userID, assignedBy uint, projectID *uint, dueDate *time.Time, priority models.Priority) (*models.Task, error) {
 	// Verify target user exists
 	var user models.User
 	if err := s.DB.First(&user, userID).Error; err != nil {
 		return nil, errors.New("target user not found")
 	}
+	if !user.Active {
+		return nil, ErrUserDeactivated
+	}
 
-	// Verify assigner exists and has appropriate role
+	// Verify assigner exists and may assign work
 	var assigner models.User
 	if err := s.DB.First(&assigner, assignedBy).Error; err != nil {
 		return nil, errors.New("assigner not found")
 	}
 
-	// Check if assigner has permission (Admin or Manager only)
-	if assigner.Role == models.RoleEmployee || assigner.Role == models.RoleHead {
-		return nil, errors.New("employees and heads cannot assign tasks to other users")
+	// Assigners can reach their reports, or users in their departments with task.assign
+	actor, err := NewActor(s.DB, &assigner)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeAssignment(actor, &user); err != nil {
+		return nil, err
 	}
 
 	// If projectID is provided, verify project exists and user is member
@@ -80,6 +92,7 @@ func (s *TaskService) CreateTaskForUser(title, description string, userID, assig
 		Title:       title,
 		Description: description,
 		Status:      models.TaskStatusPending,
+		Priority:    priority,
 		UserID:      userID,
 		ProjectID:   projectID,
 		AssignedAt:  time.Now(),
@@ -94,7 +107,7 @@ func (s *TaskService) CreateTaskForUser(title, description string, userID, assig
 }
 
 // CreateCollaborativeTask creates a new collaborative task
-func (s *TaskService) CreateCollaborativeTask(title, description string, userID uint, projectID *uint, dueDate *time.Time) (*models.CollaborativeTask, error) {
+func (s *TaskService) CreateCollaborativeTask(title, description string, userID uint, projectID *uint, dueDate *time.Time, priority models.Priority) (*models.CollaborativeTask, error) {
 	// Verify user exists
 	var user models.User
 	if err := s.DB.First(&user, userID).Error; err != nil {
@@ -113,6 +126,7 @@ func (s *TaskService) CreateCollaborativeTask(title, description string, userID
 		Title:       title,
 		Description: description,
 		Status:      models.TaskStatusPending,
+		Priority:    priority,
 		LeadUserID:  userID,
 		ProjectID:   projectID,
 		AssignedAt:  time.Now(),
@@ -126,23 +140,30 @@ func (s *TaskService) CreateCollaborativeTask(title, description string, userID
 	return task, nil
 }
 
-// CreateCollaborativeTaskForUser allows Admin/Manager to create collaborative tasks for specific users
-func (s *TaskService) CreateCollaborativeTaskForUser(title, description string, userID, assignedBy uint, projectID *uint, dueDate *time.Time, priority string) (*models.CollaborativeTask, error) {
+// CreateCollaborativeTaskForUser lets managers and users with task.assign create collaborative tasks for specific users
+func (s *TaskService) CreateCollaborativeTaskForUser(title, description string, userID, assignedBy uint, projectID *uint, dueDate *time.Time, priority models.Priority) (*models.CollaborativeTask, error) {
 	// Verify target user exists
 	var user models.User
 	if err := s.DB.First(&user, userID).Error; err != nil {
 		return nil, errors.New("target user not found")
 	}
+	if !user.Active {
+		return nil, ErrUserDeactivated
+	}
 
-	// Verify assigner exists and has appropriate role
+	// Verify assigner exists and may assign work
 	var assigner models.User
 	if err := s.DB.First(&assigner, assignedBy).Error; err != nil {
 		return nil, errors.New("assigner not found")
 	}
 
-	// Check if assigner has permission (Admin or Manager only)
-	if assigner.Role == models.RoleEmployee || assigner.Role == models.RoleHead {
-		return nil, errors.New("employees and heads cannot assign collaborative tasks to other users")
+	// Assigners can reach their reports, or users in their departments with task.assign
+	actor, err := NewActor(s.DB, &assigner)
+	if err != nil {
+		return nil, err
+	}
+	if err := AuthorizeAssignment(actor, &user); err != nil {
+		return nil, err
 	}
 
 	// If projectID is provided, verify project exists and user is member
@@ -157,6 +178,7 @@ func (s *TaskService) CreateCollaborativeTaskForUser(title, description string,
 		Title:       title,
 		Description: description,
 		Status:      models.TaskStatusPending,
+		Priority:    priority,
 		LeadUserID:  userID,
 		ProjectID:   projectID,
 		AssignedAt:  time.Now(),
@@ -171,151 +193,352 @@ func (s *TaskService) CreateCollaborativeTaskForUser(title, description string,
 }
 
 // GetUserTasks returns all tasks for a user
-func (s *TaskService) GetUserTasks(userID uint) ([]models.Task, error) {
+func (s *TaskService) GetUserTasks(userID uint, options TaskListOptions) ([]models.Task, error) {
 	var tasks []models.Task
 	err := s.DB.Where("user_id = ?", userID).
 		Preload("Project").
-		Order("created_at DESC").
+		Scopes(options.scope("tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
 // GetUserCollaborativeTasks returns all collaborative tasks for a user
-func (s *TaskService) GetUserCollaborativeTasks(userID uint) ([]models.CollaborativeTask, error) {
+func (s *TaskService) GetUserCollaborativeTasks(userID uint, options TaskListOptions) ([]models.CollaborativeTask, error) {
 	var tasks []models.CollaborativeTask
-	err := s.DB.Where("user_id = ?", userID).
+	err := s.DB.Where("lead_user_id = ?", userID).
 		Preload("Project").
-		Order("created_at DESC").
+		Scopes(options.scope("collaborative_tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
 // GetProjectTasks returns all tasks for a project
-func (s *TaskService) GetProjectTasks(projectID uint) ([]models.Task, error) {
+func (s *TaskService) GetProjectTasks(projectID uint, options TaskListOptions) ([]models.Task, error) {
 	var tasks []models.Task
 	err := s.DB.Where("project_id = ?", projectID).
 		Preload("User").
-		Order("created_at DESC").
+		Scopes(options.scope("tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
 // GetProjectCollaborativeTasks returns all collaborative tasks for a project
-func (s *TaskService) GetProjectCollaborativeTasks(projectID uint) ([]models.CollaborativeTask, error) {
+func (s *TaskService) GetProjectCollaborativeTasks(projectID uint, options TaskListOptions) ([]models.CollaborativeTask, error) {
 	var tasks []models.CollaborativeTask
 	err := s.DB.Where("project_id = ?", projectID).
-		Preload("User").
-		Order("created_at DESC").
+		Preload("LeadUser").
+		Scopes(options.scope("collaborative_tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
-// UpdateTaskStatus updates task status
-func (s *TaskService) UpdateTaskStatus(taskID uint, status models.TaskStatus) error {
-	return s.DB.Model(&models.Task{}).Where("id = ?", taskID).Update("status", status).Error
+// UpdateTaskStatus updates task status if the access policy and the task workflow allow it
+func (s *TaskService) UpdateTaskStatus(actor *Actor, taskID uint, status models.TaskStatus) error {
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := AuthorizeTask(actor, task, TaskActionUpdateStatus); err != nil {
+		return err
+	}
+
+	var changes taskChanges
+	timestamps := taskTimestamps(task)
+	if err := GetTaskWorkflow(models.TaskKindTask).transition(&changes, task.ID, task.Status, status, &timestamps, actor.canReopen()); err != nil {
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := applyTaskChanges(tx, &models.Task{}, models.TaskKindTask, task.ID, actor.UserID, changes); err != nil {
+			return err
+		}
+		return rollUpParent(tx, task, actor)
+	})
 }
 
-// UpdateCollaborativeTaskStatus updates collaborative task status
-func (s *TaskService) UpdateCollaborativeTaskStatus(taskID uint, status models.TaskStatus) error {
-	return s.DB.Model(&models.CollaborativeTask{}).Where("id = ?", taskID).Update("status", status).Error
+// ReassignTask moves a task to another user; action is TaskActionAssign or TaskActionReassign.
+// Tasks in a project can only go to project members, and actors acting outside a project role
+// can only hand tasks to their reports or to users in their departments.
+func (s *TaskService) ReassignTask(actor *Actor, taskID, newUserID uint, action TaskAction) (*models.Task, error) {
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := AuthorizeTask(actor, task, action); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.DB.First(&user, newUserID).Error; err != nil {
+		return nil, errors.New("target user not found")
+	}
+	if !user.Active {
+		return nil, ErrUserDeactivated
+	}
+
+	projectPermission := models.PermissionTaskAssign
+	if action == TaskActionReassign {
+		projectPermission = models.PermissionTaskReassign
+	}
+	if !actor.grantsInProject(task.ProjectID, projectPermission) && !actor.manages(user.ID) && !actor.Departments.Allows(user.DepartmentID) {
+		return nil, fmt.Errorf("target user is %w", ErrOutsideDepartment)
+	}
+
+	if task.ProjectID != nil {
+		var userProject models.UserProject
+		if err := s.DB.Where("user_id = ? AND project_id = ?", newUserID, *task.ProjectID).First(&userProject).Error; err != nil {
+			return nil, errors.New("target user is not a member of this project")
+		}
+	}
+
+	now := time.Now()
+	var changes taskChanges
+	changes.add("user_id", "user_id", task.UserID, newUserID)
+	changes.add("assigned_at", "assigned_at", task.AssignedAt, now)
+	if err := applyTaskChanges(s.DB, &models.Task{}, models.TaskKindTask, task.ID, actor.UserID, changes); err != nil {
+		return nil, err
+	}
+	task.UserID = newUserID
+	task.AssignedAt = now
+
+	return task, nil
 }
 
-// DeleteTask deletes a task
-func (s *TaskService) DeleteTask(taskID, userID uint) error {
-	var task models.Task
-	if err := s.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
-		return errors.New("task not found or access denied")
+// UpdateCollaborativeTaskStatus updates collaborative task status if the access policy and the task workflow allow it
+func (s *TaskService) UpdateCollaborativeTaskStatus(actor *Actor, taskID uint, status models.TaskStatus) error {
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionUpdateStatus); err != nil {
+		return err
 	}
 
-	return s.DB.Delete(&task).Error
+	var changes taskChanges
+	timestamps := collaborativeTaskTimestamps(task)
+	if err := GetTaskWorkflow(models.TaskKindCollaborative).transition(&changes, task.ID, task.Status, status, &timestamps, actor.canReopen()); err != nil {
+		return err
+	}
+	return applyTaskChanges(s.DB, &models.CollaborativeTask{}, models.TaskKindCollaborative, task.ID, actor.UserID, changes)
 }
 
-// DeleteCollaborativeTask deletes a collaborative task
-func (s *TaskService) DeleteCollaborativeTask(taskID, userID uint) error {
-	var task models.CollaborativeTask
-	if err := s.DB.Where("id = ? AND user_id = ?", taskID, userID).First(&task).Error; err != nil {
-		return errors.New("task not found or access denied")
+// DeleteTask deletes the actor's own task
+func (s *TaskService) DeleteTask(actor *Actor, taskID uint) error {
+	return s.deleteTask(actor, taskID, TaskActionDelete)
+}
+
+// ForceDeleteTask deletes any task in the actor's departments
+func (s *TaskService) ForceDeleteTask(actor *Actor, taskID uint) error {
+	return s.deleteTask(actor, taskID, TaskActionForceDelete)
+}
+
+// deleteTask deletes a task after checking the given delete action
+func (s *TaskService) deleteTask(actor *Actor, taskID uint, action TaskAction) error {
+	task, err := s.loadTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := AuthorizeTask(actor, task, action); err != nil {
+		return err
+	}
+
+	// Subtasks, checklist items and dependencies go with the task, and a deleted subtask no longer
+	// counts for its parent
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteChildren(tx, models.TaskKindTask, task.ID); err != nil {
+			return err
+		}
+		if err := deleteDependencies(tx, models.TaskKindTask, []uint{task.ID}); err != nil {
+			return err
+		}
+		if err := tx.Delete(task).Error; err != nil {
+			return err
+		}
+		return rollUpParent(tx, task, actor)
+	})
+}
+
+// DeleteCollaborativeTask deletes a collaborative task if the access policy allows it
+func (s *TaskService) DeleteCollaborativeTask(actor *Actor, taskID uint) error {
+	task, err := loadCollaborativeTask(s.DB, taskID)
+	if err != nil {
+		return err
+	}
+
+	if err := AuthorizeCollaborativeTask(actor, task, TaskActionDelete); err != nil {
+		return err
 	}
 
-	return s.DB.Delete(&task).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteChildren(tx, models.TaskKindCollaborative, task.ID); err != nil {
+			return err
+		}
+		if err := deleteDependencies(tx, models.TaskKindCollaborative, []uint{task.ID}); err != nil {
+			return err
+		}
+		return tx.Delete(task).Error
+	})
 }
 
-// GetTasksByStatus returns tasks filtered by status
-func (s *TaskService) GetTasksByStatus(userID uint, status models.TaskStatus) ([]models.Task, error) {
+// loadTask loads a task with the owner the access policy needs
+func (s *TaskService) loadTask(taskID uint) (*models.Task, error) {
+	var task models.Task
+	if err := s.DB.Preload("User").First(&task, taskID).Error; err != nil {
+		return nil, ErrTaskNotFound
+	}
+	return &task, nil
+}
+
+// loadCollaborativeTask loads a collaborative task with the lead and participants the access policy needs
+func loadCollaborativeTask(db *gorm.DB, taskID uint) (*models.CollaborativeTask, error) {
+	var task models.CollaborativeTask
+	if err := db.Preload("LeadUser").Preload("Participants").First(&task, taskID).Error; err != nil {
+		return nil, ErrTaskNotFound
+	}
+	return &task, nil
+}
+
+// GetTasksByStatus returns the user's tasks filtered by status
+func (s *TaskService) GetTasksByStatus(userID uint, status models.TaskStatus, options TaskListOptions) ([]models.Task, error) {
 	var tasks []models.Task
 	err := s.DB.Where("user_id = ? AND status = ?", userID, status).
 		Preload("Project").
-		Order("created_at DESC").
+		Scopes(options.scope("tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
-// GetCollaborativeTasksByStatus returns collaborative tasks filtered by status
-func (s *TaskService) GetCollaborativeTasksByStatus(userID uint, status models.TaskStatus) ([]models.CollaborativeTask, error) {
+// GetCollaborativeTasksByStatus returns the collaborative tasks the user leads, filtered by status
+func (s *TaskService) GetCollaborativeTasksByStatus(userID uint, status models.TaskStatus, options TaskListOptions) ([]models.CollaborativeTask, error) {
 	var tasks []models.CollaborativeTask
-	err := s.DB.Where("user_id = ? AND status = ?", userID, status).
+	err := s.DB.Where("lead_user_id = ? AND status = ?", userID, status).
 		Preload("Project").
-		Order("created_at DESC").
+		Scopes(options.scope("collaborative_tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
-// GetTasksByDepartment returns all tasks for users in a specific department
-func (s *TaskService) GetTasksByDepartment(department string) ([]models.Task, error) {
+// GetTasksByDepartment returns all tasks for users in a department and its sub-departments
+func (s *TaskService) GetTasksByDepartment(departmentID uint, options TaskListOptions) ([]models.Task, error) {
+	departmentIDs, err := NewDepartmentService(s.DB).SubtreeIDs([]uint{departmentID})
+	if err != nil {
+		return nil, err
+	}
+
 	var tasks []models.Task
-	err := s.DB.Joins("JOIN users ON tasks.user_id = users.id").
-		Where("users.department = ?", department).
-		Preload("User").
+	err = s.DB.Joins("JOIN users ON tasks.user_id = users.id").
+		Where("users.department_id IN ?", departmentIDs).
+		Preload("User.Department").
 		Preload("Project").
-		Order("tasks.created_at DESC").
+		Scopes(options.scope("tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
-// GetCollaborativeTasksByDepartment returns all collaborative tasks for users in a specific department
-func (s *TaskService) GetCollaborativeTasksByDepartment(department string) ([]models.CollaborativeTask, error) {
+// GetCollaborativeTasksByDepartment returns all collaborative tasks led by users in a department and its sub-departments
+func (s *TaskService) GetCollaborativeTasksByDepartment(departmentID uint, options TaskListOptions) ([]models.CollaborativeTask, error) {
+	departmentIDs, err := NewDepartmentService(s.DB).SubtreeIDs([]uint{departmentID})
+	if err != nil {
+		return nil, err
+	}
+
 	var tasks []models.CollaborativeTask
-	err := s.DB.Joins("JOIN users ON collaborative_tasks.user_id = users.id").
-		Where("users.department = ?", department).
-		Preload("User").
+	err = s.DB.Joins("JOIN users ON collaborative_tasks.lead_user_id = users.id").
+		Where("users.department_id IN ?", departmentIDs).
+		Preload("LeadUser.Department").
 		Preload("Project").
-		Order("collaborative_tasks.created_at DESC").
+		Scopes(options.scope("collaborative_tasks")).
 		Find(&tasks).Error
 
 	return tasks, err
 }
 
-// BulkUpdateTaskStatus updates multiple task statuses at once
-func (s *TaskService) BulkUpdateTaskStatus(taskIDs []uint, status models.TaskStatus) (int64, error) {
-	result := s.DB.Model(&models.Task{}).Where("id IN ?", taskIDs).Update("status", status)
-	return result.RowsAffected, result.Error
+// BulkUpdateTaskStatus updates multiple task statuses at once and records each change in the
+// task history. Nothing is updated if any of the tasks belongs to a user outside the actor's
+// departments or cannot make the transition.
+func (s *TaskService) BulkUpdateTaskStatus(actor *Actor, taskIDs []uint, status models.TaskStatus) (int64, error) {
+	scope := actor.Departments
+	if !scope.All {
+		var existingIDs, allowedIDs []uint
+		if err := s.DB.Model(&models.Task{}).Where("id IN ?", taskIDs).Pluck("id", &existingIDs).Error; err != nil {
+			return 0, err
+		}
+		if err := s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("tasks.id IN ?", taskIDs).Pluck("tasks.id", &allowedIDs).Error; err != nil {
+			return 0, err
+		}
+		if len(allowedIDs) < len(existingIDs) {
+			return 0, fmt.Errorf("some tasks are %w", ErrOutsideDepartment)
+		}
+	}
+
+	var tasks []models.Task
+	if err := s.DB.Where("id IN ?", taskIDs).Order("id").Find(&tasks).Error; err != nil {
+		return 0, err
+	}
+
+	workflow := GetTaskWorkflow(models.TaskKindTask)
+	changesByTask := make([]taskChanges, len(tasks))
+	for i := range tasks {
+		timestamps := taskTimestamps(&tasks[i])
+		if err := workflow.transition(&changesByTask[i], tasks[i].ID, tasks[i].Status, status, &timestamps, actor.canReopen()); err != nil {
+			return 0, err
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		for i, task := range tasks {
+			if err := applyTaskChanges(tx, &models.Task{}, models.TaskKindTask, task.ID, actor.UserID, changesByTask[i]); err != nil {
+				return err
+			}
+			if err := rollUpParent(tx, &tasks[i], actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(tasks)), nil
 }
 
-// GetTaskStatistics returns overall task statistics
-func (s *TaskService) GetTaskStatistics() (map[string]interface{}, error) {
+// GetTaskStatistics returns task statistics for the departments in scope
+func (s *TaskService) GetTaskStatistics(scope DepartmentScope) (map[string]interface{}, error) {
 	var totalTasks, pendingTasks, inProgressTasks, completedTasks, cancelledTasks int64
 	var totalCollaborativeTasks, pendingCollaborativeTasks, inProgressCollaborativeTasks, completedCollaborativeTasks, cancelledCollaborativeTasks int64
 
 	// Count regular tasks by status
-	s.DB.Model(&models.Task{}).Count(&totalTasks)
-	s.DB.Model(&models.Task{}).Where("status = ?", models.TaskStatusPending).Count(&pendingTasks)
-	s.DB.Model(&models.Task{}).Where("status = ?", models.TaskStatusInProgress).Count(&inProgressTasks)
-	s.DB.Model(&models.Task{}).Where("status = ?", models.TaskStatusCompleted).Count(&completedTasks)
-	s.DB.Model(&models.Task{}).Where("status = ?", models.TaskStatusCancelled).Count(&cancelledTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Count(&totalTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("status = ?", models.TaskStatusPending).Count(&pendingTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("status = ?", models.TaskStatusInProgress).Count(&inProgressTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("status = ?", models.TaskStatusCompleted).Count(&completedTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("status = ?", models.TaskStatusCancelled).Count(&cancelledTasks)
 
 	// Count collaborative tasks by status
-	s.DB.Model(&models.CollaborativeTask{}).Count(&totalCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("status = ?", models.TaskStatusPending).Count(&pendingCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("status = ?", models.TaskStatusInProgress).Count(&inProgressCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("status = ?", models.TaskStatusCompleted).Count(&completedCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("status = ?", models.TaskStatusCancelled).Count(&cancelledCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Count(&totalCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("status = ?", models.TaskStatusPending).Count(&pendingCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("status = ?", models.TaskStatusInProgress).Count(&inProgressCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("status = ?", models.TaskStatusCompleted).Count(&completedCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("status = ?", models.TaskStatusCancelled).Count(&cancelledCollaborativeTasks)
+
+	// Break both kinds of task down by priority
+	priorities := priorityCounts{}
+	if err := priorities.add(s.DB.Model(&models.Task{}).Scopes(scope.Tasks), "tasks"); err != nil {
+		return nil, err
+	}
+	if err := priorities.add(s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks), "collaborative_tasks"); err != nil {
+		return nil, err
+	}
 
 	// Calculate completion rates
 	totalAllTasks := totalTasks + totalCollaborativeTasks
@@ -346,13 +569,15 @@ func (s *TaskService) GetTaskStatistics() (map[string]interface{}, error) {
 			"completed_tasks": totalCompletedTasks,
 			"completion_rate": completionRate,
 		},
+		"priority": priorities.breakdown(),
 	}
 
 	return stats, nil
 }
 
-// GetProjectReport returns detailed statistics for a specific project
-func (s *TaskService) GetProjectReport(projectID uint, period string) (map[string]interface{}, error) {
+// GetProjectReport returns detailed statistics for a specific project, limited to work
+// owned by users in the departments in scope
+func (s *TaskService) GetProjectReport(projectID uint, period string, scope DepartmentScope) (map[string]interface{}, error) {
 	var startDate, endDate time.Time
 	now := time.Now()
 
@@ -378,29 +603,40 @@ func (s *TaskService) GetProjectReport(projectID uint, period string) (map[strin
 	var totalTasks, pendingTasks, inProgressTasks, completedTasks, cancelledTasks int64
 	var tasksInPeriod int64
 
-	s.DB.Model(&models.Task{}).Where("project_id = ?", projectID).Count(&totalTasks)
-	s.DB.Model(&models.Task{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusPending).Count(&pendingTasks)
-	s.DB.Model(&models.Task{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusInProgress).Count(&inProgressTasks)
-	s.DB.Model(&models.Task{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCompleted).Count(&completedTasks)
-	s.DB.Model(&models.Task{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCancelled).Count(&cancelledTasks)
-	s.DB.Model(&models.Task{}).Where("project_id = ? AND created_at BETWEEN ? AND ?", projectID, startDate, endDate).Count(&tasksInPeriod)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ?", projectID).Count(&totalTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusPending).Count(&pendingTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusInProgress).Count(&inProgressTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCompleted).Count(&completedTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCancelled).Count(&cancelledTasks)
+	s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND created_at BETWEEN ? AND ?", projectID, startDate, endDate).Count(&tasksInPeriod)
 
 	// Collaborative tasks statistics
 	var totalCollaborativeTasks, pendingCollaborativeTasks, inProgressCollaborativeTasks, completedCollaborativeTasks, cancelledCollaborativeTasks int64
 	var collaborativeTasksInPeriod int64
 
-	s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ?", projectID).Count(&totalCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusPending).Count(&pendingCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusInProgress).Count(&inProgressCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCompleted).Count(&completedCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCancelled).Count(&cancelledCollaborativeTasks)
-	s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND created_at BETWEEN ? AND ?", projectID, startDate, endDate).Count(&collaborativeTasksInPeriod)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ?", projectID).Count(&totalCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusPending).Count(&pendingCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusInProgress).Count(&inProgressCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCompleted).Count(&completedCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND status = ?", projectID, models.TaskStatusCancelled).Count(&cancelledCollaborativeTasks)
+	s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND created_at BETWEEN ? AND ?", projectID, startDate, endDate).Count(&collaborativeTasksInPeriod)
+
+	// Priority breakdown of both kinds of task
+	projectPriorities := priorityCounts{}
+	if err := projectPriorities.add(s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ?", projectID), "tasks"); err != nil {
+		return nil, err
+	}
+	if err := projectPriorities.add(s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ?", projectID), "collaborative_tasks"); err != nil {
+		return nil, err
+	}
 
 	// User performance in this project
 	var userStats []map[string]interface{}
 	var users []models.User
 	s.DB.Joins("JOIN user_projects ON users.id = user_projects.user_id").
 		Where("user_projects.project_id = ?", projectID).
+		Scopes(scope.Users).
+		Preload("Department").
 		Find(&users)
 
 	for _, user := range users {
@@ -408,12 +644,20 @@ func (s *TaskService) GetProjectReport(projectID uint, period string) (map[strin
 		var userCompletedCollaborativeTasks, userTotalCollaborativeTasks int64
 
 		// Count user's regular tasks in this project
-		s.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", projectID, user.ID).Count(&userTotalTasks)
-		s.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ? AND status = ?", projectID, user.ID, models.TaskStatusCompleted).Count(&userCompletedTasks)
+		s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND user_id = ?", projectID, user.ID).Count(&userTotalTasks)
+		s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND user_id = ? AND status = ?", projectID, user.ID, models.TaskStatusCompleted).Count(&userCompletedTasks)
 
 		// Count user's collaborative tasks in this project (as lead)
-		s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND lead_user_id = ?", projectID, user.ID).Count(&userTotalCollaborativeTasks)
-		s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND lead_user_id = ? AND status = ?", projectID, user.ID, models.TaskStatusCompleted).Count(&userCompletedCollaborativeTasks)
+		s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND lead_user_id = ?", projectID, user.ID).Count(&userTotalCollaborativeTasks)
+		s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND lead_user_id = ? AND status = ?", projectID, user.ID, models.TaskStatusCompleted).Count(&userCompletedCollaborativeTasks)
+
+		userPriorities := priorityCounts{}
+		if err := userPriorities.add(s.DB.Model(&models.Task{}).Scopes(scope.Tasks).Where("project_id = ? AND user_id = ?", projectID, user.ID), "tasks"); err != nil {
+			return nil, err
+		}
+		if err := userPriorities.add(s.DB.Model(&models.CollaborativeTask{}).Scopes(scope.CollaborativeTasks).Where("project_id = ? AND lead_user_id = ?", projectID, user.ID), "collaborative_tasks"); err != nil {
+			return nil, err
+		}
 
 		userCompletionRate := 0.0
 		totalUserTasks := userTotalTasks + userTotalCollaborativeTasks
@@ -423,13 +667,15 @@ func (s *TaskService) GetProjectReport(projectID uint, period string) (map[strin
 		}
 
 		userStats = append(userStats, map[string]interface{}{
-			"user_id":         user.ID,
-			"username":        user.Username,
-			"role":            user.Role,
-			"department":      user.Department,
-			"total_tasks":     totalUserTasks,
-			"completed_tasks": totalUserCompleted,
-			"completion_rate": userCompletionRate,
+			"user_id":                  user.ID,
+			"username":                 user.Username,
+			"role":                     user.Role,
+			"department_id":            user.DepartmentID,
+			"department":               user.DepartmentName(),
+			"total_tasks":              totalUserTasks,
+			"completed_tasks":          totalUserCompleted,
+			"completion_rate":          userCompletionRate,
+			"weighted_completion_rate": userPriorities.weightedCompletionRate(),
 			"regular_tasks": map[string]interface{}{
 				"total":     userTotalTasks,
 				"completed": userCompletedTasks,
@@ -485,6 +731,7 @@ func (s *TaskService) GetProjectReport(projectID uint, period string) (map[strin
 				"completed_tasks": totalProjectCompleted,
 				"completion_rate": projectCompletionRate,
 			},
+			"priority": projectPriorities.breakdown(),
 		},
 		"user_performance": userStats,
 	}
@@ -511,7 +758,7 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 
 	// Get user details
 	var user models.User
-	if err := s.DB.First(&user, userID).Error; err != nil {
+	if err := s.DB.Preload("Department").First(&user, userID).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
 
@@ -525,7 +772,7 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 	s.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, models.TaskStatusCompleted).Count(&completedTasks)
 	s.DB.Model(&models.Task{}).Where("user_id = ? AND status = ?", userID, models.TaskStatusCancelled).Count(&cancelledTasks)
 	s.DB.Model(&models.Task{}).Where("user_id = ? AND created_at BETWEEN ? AND ?", userID, startDate, endDate).Count(&tasksInPeriod)
-	s.DB.Model(&models.Task{}).Where("user_id = ? AND status = ? AND updated_at BETWEEN ? AND ?", userID, models.TaskStatusCompleted, startDate, endDate).Count(&completedInPeriod)
+	s.DB.Model(&models.Task{}).Where("user_id = ? AND status = ? AND COALESCE(completed_at, updated_at) BETWEEN ? AND ?", userID, models.TaskStatusCompleted, startDate, endDate).Count(&completedInPeriod)
 
 	// Collaborative tasks statistics (as lead)
 	var totalCollaborativeTasks, pendingCollaborativeTasks, inProgressCollaborativeTasks, completedCollaborativeTasks, cancelledCollaborativeTasks int64
@@ -537,7 +784,16 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 	s.DB.Model(&models.CollaborativeTask{}).Where("lead_user_id = ? AND status = ?", userID, models.TaskStatusCompleted).Count(&completedCollaborativeTasks)
 	s.DB.Model(&models.CollaborativeTask{}).Where("lead_user_id = ? AND status = ?", userID, models.TaskStatusCancelled).Count(&cancelledCollaborativeTasks)
 	s.DB.Model(&models.CollaborativeTask{}).Where("lead_user_id = ? AND created_at BETWEEN ? AND ?", userID, startDate, endDate).Count(&collaborativeTasksInPeriod)
-	s.DB.Model(&models.CollaborativeTask{}).Where("lead_user_id = ? AND status = ? AND updated_at BETWEEN ? AND ?", userID, models.TaskStatusCompleted, startDate, endDate).Count(&collaborativeCompletedInPeriod)
+	s.DB.Model(&models.CollaborativeTask{}).Where("lead_user_id = ? AND status = ? AND COALESCE(completed_at, updated_at) BETWEEN ? AND ?", userID, models.TaskStatusCompleted, startDate, endDate).Count(&collaborativeCompletedInPeriod)
+
+	// Priority breakdown of both kinds of task
+	userPriorities := priorityCounts{}
+	if err := userPriorities.add(s.DB.Model(&models.Task{}).Where("user_id = ?", userID), "tasks"); err != nil {
+		return nil, err
+	}
+	if err := userPriorities.add(s.DB.Model(&models.CollaborativeTask{}).Where("lead_user_id = ?", userID), "collaborative_tasks"); err != nil {
+		return nil, err
+	}
 
 	// Project performance breakdown
 	var projectStats []map[string]interface{}
@@ -558,6 +814,14 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 		s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND lead_user_id = ?", project.ID, userID).Count(&projectCollaborativeTasks)
 		s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND lead_user_id = ? AND status = ?", project.ID, userID, models.TaskStatusCompleted).Count(&projectCollaborativeCompleted)
 
+		projectPriorities := priorityCounts{}
+		if err := projectPriorities.add(s.DB.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", project.ID, userID), "tasks"); err != nil {
+			return nil, err
+		}
+		if err := projectPriorities.add(s.DB.Model(&models.CollaborativeTask{}).Where("project_id = ? AND lead_user_id = ?", project.ID, userID), "collaborative_tasks"); err != nil {
+			return nil, err
+		}
+
 		projectCompletionRate := 0.0
 		totalProjectUserTasks := projectTasks + projectCollaborativeTasks
 		totalProjectUserCompleted := projectCompleted + projectCollaborativeCompleted
@@ -566,12 +830,13 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 		}
 
 		projectStats = append(projectStats, map[string]interface{}{
-			"project_id":      project.ID,
-			"project_title":   project.Title,
-			"project_status":  project.Status,
-			"total_tasks":     totalProjectUserTasks,
-			"completed_tasks": totalProjectUserCompleted,
-			"completion_rate": projectCompletionRate,
+			"project_id":               project.ID,
+			"project_title":            project.Title,
+			"project_status":           project.Status,
+			"total_tasks":              totalProjectUserTasks,
+			"completed_tasks":          totalProjectUserCompleted,
+			"completion_rate":          projectCompletionRate,
+			"weighted_completion_rate": projectPriorities.weightedCompletionRate(),
 			"regular_tasks": map[string]interface{}{
 				"total":     projectTasks,
 				"completed": projectCompleted,
@@ -601,10 +866,11 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 
 	report := map[string]interface{}{
 		"user": map[string]interface{}{
-			"id":         user.ID,
-			"username":   user.Username,
-			"role":       user.Role,
-			"department": user.Department,
+			"id":            user.ID,
+			"username":      user.Username,
+			"role":          user.Role,
+			"department_id": user.DepartmentID,
+			"department":    user.DepartmentName(),
 		},
 		"period": map[string]interface{}{
 			"type":       period,
@@ -640,6 +906,7 @@ func (s *TaskService) GetUserReport(userID uint, period string) (map[string]inte
 				"completed_tasks": totalCompletedInPeriod,
 				"completion_rate": periodCompletionRate,
 			},
+			"priority": userPriorities.breakdown(),
 		},
 		"project_performance": projectStats,
 	}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"project-x/models"
+	"sync"
+	"time"
+)
+
+// ErrInvalidTransition is wrapped by TransitionError
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// TransitionError is returned for a status change the workflow does not allow. Allowed lists
+// the statuses the caller could move the task to instead.
+type TransitionError struct {
+	TaskID  uint
+	From    models.TaskStatus
+	To      models.TaskStatus
+	Allowed []models.TaskStatus
+	Reason  string // Set when the transition exists but the caller may not make it
+}
+
+func (e *TransitionError) Error() string {
+	message := fmt.Sprintf("%s: task %d cannot move from %s to %s", ErrInvalidTransition, e.TaskID, e.From, e.To)
+	if e.Reason != "" {
+		message += " (" + e.Reason + ")"
+	}
+	return message
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// TaskTimestamps are the lifecycle times a transition hook may set
+type TaskTimestamps struct {
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+}
+
+// TransitionHook runs when a task changes status and may update its lifecycle times
+type TransitionHook func(from, to models.TaskStatus, at time.Time, timestamps *TaskTimestamps)
+
+// TaskWorkflow is the status transition graph of one kind of task. Moving from a closed status
+// back to an open one is a reopen and needs task.reopen.
+type TaskWorkflow struct {
+	Transitions map[models.TaskStatus][]models.TaskStatus // Status -> the statuses it may move to
+	Closed      []models.TaskStatus
+	Hooks       []TransitionHook
+}
+
+// StampStartedAt records when a task is first moved to in_progress
+func StampStartedAt(from, to models.TaskStatus, at time.Time, timestamps *TaskTimestamps) {
+	if to == models.TaskStatusInProgress && timestamps.StartedAt == nil {
+		timestamps.StartedAt = &at
+	}
+}
+
+// StampCompletedAt records when a task is completed and clears the time when it leaves completed
+func StampCompletedAt(from, to models.TaskStatus, at time.Time, timestamps *TaskTimestamps) {
+	switch {
+	case to == models.TaskStatusCompleted:
+		timestamps.CompletedAt = &at
+	case from == models.TaskStatusCompleted:
+		timestamps.CompletedAt = nil
+	}
+}
+
+// defaultTaskWorkflow moves tasks pending -> in_progress -> completed. Work can be paused back to
+// pending and anything open or completed can be cancelled; completed and cancelled tasks can be reopened.
+func defaultTaskWorkflow() *TaskWorkflow {
+	return &TaskWorkflow{
+		Transitions: map[models.TaskStatus][]models.TaskStatus{
+			models.TaskStatusPending:    {models.TaskStatusInProgress, models.TaskStatusCancelled},
+			models.TaskStatusInProgress: {models.TaskStatusPending, models.TaskStatusCompleted, models.TaskStatusCancelled},
+			models.TaskStatusCompleted:  {models.TaskStatusInProgress, models.TaskStatusCancelled},
+			models.TaskStatusCancelled:  {models.TaskStatusPending},
+		},
+		Closed: []models.TaskStatus{models.TaskStatusCompleted, models.TaskStatusCancelled},
+		Hooks:  []TransitionHook{StampStartedAt, StampCompletedAt},
+	}
+}
+
+var (
+	taskWorkflows  = map[string]*TaskWorkflow{models.TaskKindTask: defaultTaskWorkflow(), models.TaskKindCollaborative: defaultTaskWorkflow()}
+	taskWorkflowMu sync.RWMutex
+)
+
+// GetTaskWorkflow returns the workflow of a kind of task (see the models.TaskKind constants)
+func GetTaskWorkflow(kind string) *TaskWorkflow {
+	taskWorkflowMu.RLock()
+	defer taskWorkflowMu.RUnlock()
+	return taskWorkflows[kind]
+}
+
+// SetTaskWorkflow replaces the workflow of a kind of task, e.g. to add review steps or drop the hooks
+func SetTaskWorkflow(kind string, workflow *TaskWorkflow) {
+	taskWorkflowMu.Lock()
+	defer taskWorkflowMu.Unlock()
+	taskWorkflows[kind] = workflow
+}
+
+// isReopen reports whether a transition moves a task out of a closed status into an open one
+func (w *TaskWorkflow) isReopen(from, to models.TaskStatus) bool {
+	return w.isClosed(from) && !w.isClosed(to)
+}
+
+func (w *TaskWorkflow) isClosed(status models.TaskStatus) bool {
+	for _, closed := range w.Closed {
+		if closed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the statuses a task can move to from a status; reopens are left out unless canReopen
+func (w *TaskWorkflow) Next(from models.TaskStatus, canReopen bool) []models.TaskStatus {
+	next := make([]models.TaskStatus, 0, len(w.Transitions[from]))
+	for _, to := range w.Transitions[from] {
+		if canReopen || !w.isReopen(from, to) {
+			next = append(next, to)
+		}
+	}
+	return next
+}
+
+// Check returns a TransitionError unless the workflow allows the transition for the caller
+func (w *TaskWorkflow) Check(taskID uint, from, to models.TaskStatus, canReopen bool) error {
+	for _, next := range w.Transitions[from] {
+		if next != to {
+			continue
+		}
+		if w.isReopen(from, to) && !canReopen {
+			return &TransitionError{TaskID: taskID, From: from, To: to, Allowed: w.Next(from, false),
+				Reason: "reopening needs " + models.PermissionTaskReopen}
+		}
+		return nil
+	}
+	return &TransitionError{TaskID: taskID, From: from, To: to, Allowed: w.Next(from, canReopen)}
+}
+
+// transition checks a status change and adds it, with the times the hooks stamp, to the changes.
+// Setting the current status again is a no-op.
+func (w *TaskWorkflow) transition(changes *taskChanges, taskID uint, from, to models.TaskStatus, timestamps *TaskTimestamps, canReopen bool) error {
+	if from == to {
+		return nil
+	}
+	if err := w.Check(taskID, from, to, canReopen); err != nil {
+		return err
+	}
+
+	before := *timestamps
+	now := time.Now()
+	for _, hook := range w.Hooks {
+		hook(from, to, now, timestamps)
+	}
+
+	changes.add("status", "status", from, to)
+	changes.add("started_at", "started_at", before.StartedAt, timestamps.StartedAt)
+	changes.add("completed_at", "completed_at", before.CompletedAt, timestamps.CompletedAt)
+	return nil
+}
+
+// followProgress moves a collaborative task along the workflow as progress is reported: any
+// progress starts a pending task and 100% completes it. Closed tasks, and steps the workflow
+// does not allow, keep their status.
+func (w *TaskWorkflow) followProgress(changes *taskChanges, task *models.CollaborativeTask, progress int) {
+	if w.isClosed(task.Status) {
+		return
+	}
+	w.advance(changes, task.ID, task.Status, collaborativeTaskTimestamps(task), progressSteps(task.Status, progress), false)
+}
+
+// rollUp moves a parent task along the workflow as its subtasks and checklist items are done. It
+// works like followProgress, and also moves a completed parent back to in_progress when its
+// children are no longer all done, if the actor may reopen tasks. Cancelled parents keep their status.
+func (w *TaskWorkflow) rollUp(changes *taskChanges, taskID uint, status models.TaskStatus, timestamps TaskTimestamps, progress int, canReopen bool) {
+	if status == models.TaskStatusCompleted && progress < 100 {
+		w.advance(changes, taskID, status, timestamps, []models.TaskStatus{models.TaskStatusInProgress}, canReopen)
+		return
+	}
+	if w.isClosed(status) {
+		return
+	}
+	w.advance(changes, taskID, status, timestamps, progressSteps(status, progress), false)
+}
+
+// progressSteps returns the statuses an open task passes through to reflect its progress
+func progressSteps(status models.TaskStatus, progress int) []models.TaskStatus {
+	var steps []models.TaskStatus
+	if progress > 0 && status == models.TaskStatusPending {
+		steps = append(steps, models.TaskStatusInProgress)
+	}
+	if progress == 100 {
+		steps = append(steps, models.TaskStatusCompleted)
+	}
+	return steps
+}
+
+// advance makes the transitions in order, stopping at the first one the workflow does not allow
+func (w *TaskWorkflow) advance(changes *taskChanges, taskID uint, status models.TaskStatus, timestamps TaskTimestamps, steps []models.TaskStatus, canReopen bool) {
+	for _, step := range steps {
+		if w.transition(changes, taskID, status, step, &timestamps, canReopen) != nil {
+			return
+		}
+		status = step
+	}
+}
+
+// taskTimestamps returns the lifecycle times of a task
+func taskTimestamps(task *models.Task) TaskTimestamps {
+	return TaskTimestamps{StartedAt: task.StartedAt, CompletedAt: task.CompletedAt}
+}
+
+// collaborativeTaskTimestamps returns the lifecycle times of a collaborative task
+func collaborativeTaskTimestamps(task *models.CollaborativeTask) TaskTimestamps {
+	return TaskTimestamps{StartedAt: task.StartedAt, CompletedAt: task.CompletedAt}
+}
+
+// canReopen reports whether the actor may reopen closed tasks
+func (a *Actor) canReopen() bool {
+	return a.Permissions.Has(models.PermissionTaskReopen)
+}
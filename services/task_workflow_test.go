@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTaskWorkflowCheck(t *testing.T) {
+	workflow := defaultTaskWorkflow()
+
+	tests := []struct {
+		from, to  models.TaskStatus
+		canReopen bool
+		allowed   bool
+	}{
+		{models.TaskStatusPending, models.TaskStatusInProgress, false, true},
+		{models.TaskStatusInProgress, models.TaskStatusCompleted, false, true},
+		{models.TaskStatusPending, models.TaskStatusCompleted, false, false},
+		{models.TaskStatusCompleted, models.TaskStatusCancelled, false, true},
+		{models.TaskStatusCancelled, models.TaskStatusCompleted, true, false},
+		{models.TaskStatusCompleted, models.TaskStatusInProgress, false, false},
+		{models.TaskStatusCompleted, models.TaskStatusInProgress, true, true},
+		{models.TaskStatusCancelled, models.TaskStatusPending, true, true},
+	}
+
+	for _, tt := range tests {
+		err := workflow.Check(1, tt.from, tt.to, tt.canReopen)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s -> %s (reopen %v): got err %v, want allowed=%v", tt.from, tt.to, tt.canReopen, err, tt.allowed)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("%s -> %s: error %v does not wrap ErrInvalidTransition", tt.from, tt.to, err)
+		}
+	}
+
+	// Callers who cannot reopen are only offered the statuses they can reach
+	var transitionErr *TransitionError
+	if err := workflow.Check(1, models.TaskStatusCancelled, models.TaskStatusCompleted, false); !errors.As(err, &transitionErr) {
+		t.Fatalf("got %v, want a transition error", err)
+	}
+	if len(transitionErr.Allowed) != 0 {
+		t.Errorf("allowed statuses without reopen: %v", transitionErr.Allowed)
+	}
+	want := []models.TaskStatus{models.TaskStatusInProgress, models.TaskStatusCancelled}
+	if got := workflow.Next(models.TaskStatusCompleted, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("next from completed: got %v, want %v", got, want)
+	}
+}
+
+func TestTaskWorkflowHooks(t *testing.T) {
+	workflow := defaultTaskWorkflow()
+	started := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	timestamps := TaskTimestamps{StartedAt: &started}
+
+	// Completing keeps the first start time and stamps the completion
+	var changes taskChanges
+	if err := workflow.transition(&changes, 1, models.TaskStatusInProgress, models.TaskStatusCompleted, &timestamps, false); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if timestamps.StartedAt != &started || timestamps.CompletedAt == nil {
+		t.Errorf("after completing: %+v", timestamps)
+	}
+	if fields := changedFields(changes); !reflect.DeepEqual(fields, []string{"status", "completed_at"}) {
+		t.Errorf("changes after completing: %v", fields)
+	}
+
+	// Reopening clears the completion time
+	changes = nil
+	if err := workflow.transition(&changes, 1, models.TaskStatusCompleted, models.TaskStatusInProgress, &timestamps, true); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if timestamps.CompletedAt != nil {
+		t.Errorf("completion time kept after reopening: %v", timestamps.CompletedAt)
+	}
+}
+
+func TestTaskWorkflowFollowProgress(t *testing.T) {
+	workflow := defaultTaskWorkflow()
+
+	task := &models.CollaborativeTask{Status: models.TaskStatusPending}
+	var changes taskChanges
+	workflow.followProgress(&changes, task, 100)
+	want := []string{"status", "started_at", "status", "completed_at"}
+	if fields := changedFields(changes); !reflect.DeepEqual(fields, want) {
+		t.Errorf("pending task reaching 100%%: got %v, want %v", fields, want)
+	}
+	if last := changes[len(changes)-2]; last.value != models.TaskStatusCompleted {
+		t.Errorf("final status %v", last.value)
+	}
+
+	changes = nil
+	workflow.followProgress(&changes, &models.CollaborativeTask{Status: models.TaskStatusCancelled}, 100)
+	if len(changes) != 0 {
+		t.Errorf("cancelled task changed by progress: %v", changedFields(changes))
+	}
+}
+
+func TestTaskWorkflowRollUp(t *testing.T) {
+	workflow := defaultTaskWorkflow()
+	completedAt := time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+	completed := TaskTimestamps{StartedAt: &completedAt, CompletedAt: &completedAt}
+
+	// A completed parent whose children are no longer all done goes back to work, if the actor may reopen
+	var changes taskChanges
+	workflow.rollUp(&changes, 1, models.TaskStatusCompleted, completed, 50, true)
+	if fields := changedFields(changes); !reflect.DeepEqual(fields, []string{"status", "completed_at"}) {
+		t.Errorf("reopened parent: got %v", fields)
+	}
+
+	changes = nil
+	workflow.rollUp(&changes, 1, models.TaskStatusCompleted, completed, 50, false)
+	if len(changes) != 0 {
+		t.Errorf("parent reopened without task.reopen: %v", changedFields(changes))
+	}
+
+	changes = nil
+	workflow.rollUp(&changes, 1, models.TaskStatusPending, TaskTimestamps{}, 40, false)
+	if fields := changedFields(changes); !reflect.DeepEqual(fields, []string{"status", "started_at"}) {
+		t.Errorf("started parent: got %v", fields)
+	}
+}
+
+func changedFields(changes taskChanges) []string {
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.field)
+	}
+	return fields
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// openTaskStatuses are the statuses that count as outstanding work
+var openTaskStatuses = []models.TaskStatus{models.TaskStatusPending, models.TaskStatusInProgress}
+
+type TeamService struct {
+	DB *gorm.DB
+}
+
+func NewTeamService(db *gorm.DB) *TeamService {
+	return &TeamService{DB: db}
+}
+
+// SetManager changes who a user reports to, or clears it when managerID is nil.
+// A user cannot report to themselves or to anyone who reports to them. A manager gains
+// authority over their reports' work, so users whose role outranks the actor's are refused.
+func (s *TeamService) SetManager(actor *Actor, userID uint, managerID *uint) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, &user); err != nil {
+		return nil, err
+	}
+
+	if managerID != nil {
+		var manager models.User
+		if err := s.DB.First(&manager, *managerID).Error; err != nil {
+			return nil, errors.New("manager not found")
+		}
+		if !manager.Active {
+			return nil, ErrUserDeactivated
+		}
+		if *managerID == userID {
+			return nil, errors.New("a user cannot report to themselves")
+		}
+		if s.IsManagerOf(userID, *managerID) {
+			return nil, errors.New("a user cannot report to someone in their own reporting line")
+		}
+	}
+
+	if err := s.DB.Model(&user).Update("manager_id", managerID).Error; err != nil {
+		return nil, err
+	}
+
+	return NewUserService(s.DB).GetUserByID(userID)
+}
+
+// ReportLevels returns everyone who reports to the manager, directly or indirectly,
+// mapped to their distance from the manager (1 for direct reports). With directOnly
+// only direct reports are returned. Deactivated users are no longer part of a team.
+func (s *TeamService) ReportLevels(managerID uint, directOnly bool) (map[uint]int, error) {
+	levels := make(map[uint]int)
+	frontier := []uint{managerID}
+	for depth := 1; len(frontier) > 0; depth++ {
+		var reportIDs []uint
+		if err := s.DB.Model(&models.User{}).Where("manager_id IN ? AND active = ?", frontier, true).Pluck("id", &reportIDs).Error; err != nil {
+			return nil, err
+		}
+
+		frontier = frontier[:0]
+		for _, id := range reportIDs {
+			// The manager check on SetManager prevents cycles; this guards against bad data
+			if _, seen := levels[id]; seen || id == managerID {
+				continue
+			}
+			levels[id] = depth
+			frontier = append(frontier, id)
+		}
+
+		if directOnly {
+			break
+		}
+	}
+	return levels, nil
+}
+
+// ReportIDs returns the IDs of everyone who reports to the manager, directly or indirectly
+func (s *TeamService) ReportIDs(managerID uint) ([]uint, error) {
+	levels, err := s.ReportLevels(managerID, false)
+	if err != nil {
+		return nil, err
+	}
+	return levelIDs(levels), nil
+}
+
+// IsManagerOf reports whether userID is somewhere below managerID in the reporting line
+func (s *TeamService) IsManagerOf(managerID, userID uint) bool {
+	seen := map[uint]bool{userID: true}
+	current := userID
+	for {
+		var user models.User
+		if err := s.DB.Select("id", "manager_id").First(&user, current).Error; err != nil || user.ManagerID == nil {
+			return false
+		}
+		if *user.ManagerID == managerID {
+			return true
+		}
+		if seen[*user.ManagerID] {
+			return false
+		}
+		seen[*user.ManagerID] = true
+		current = *user.ManagerID
+	}
+}
+
+// GetReports returns a manager's reports, with each user's distance from the manager
+func (s *TeamService) GetReports(managerID uint, directOnly bool) ([]models.User, map[uint]int, error) {
+	levels, err := s.ReportLevels(managerID, directOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var users []models.User
+	if err := s.DB.Where("id IN ?", levelIDs(levels)).
+		Preload("Department").
+		Order("username").
+		Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+	return users, levels, nil
+}
+
+// GetTeamTasks returns the tasks of a manager's reports, optionally filtered by status
+func (s *TeamService) GetTeamTasks(managerID uint, directOnly bool, status string, options TaskListOptions) ([]models.Task, error) {
+	levels, err := s.ReportLevels(managerID, directOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.DB.Where("user_id IN ?", levelIDs(levels))
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var tasks []models.Task
+	err = query.Preload("User").
+		Preload("Project").
+		Scopes(options.scope("tasks")).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// GetTeamWorkload returns open, overdue and finished work for each of a manager's reports
+// and for the team as a whole
+func (s *TeamService) GetTeamWorkload(managerID uint, directOnly bool) (map[string]interface{}, error) {
+	users, levels, err := s.GetReports(managerID, directOnly)
+	if err != nil {
+		return nil, err
+	}
+	reportIDs := levelIDs(levels)
+
+	var taskCounts []struct {
+		UserID uint
+		Status models.TaskStatus
+		Count  int64
+	}
+	if err := s.DB.Model(&models.Task{}).
+		Select("user_id, status, COUNT(*) AS count").
+		Where("user_id IN ?", reportIDs).
+		Group("user_id, status").
+		Scan(&taskCounts).Error; err != nil {
+		return nil, err
+	}
+
+	type userCount struct {
+		UserID uint
+		Count  int64
+	}
+	var overdueCounts, collaborativeCounts []userCount
+	if err := s.DB.Model(&models.Task{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("user_id IN ? AND status IN ? AND due_date < ?", reportIDs, openTaskStatuses, time.Now()).
+		Group("user_id").
+		Scan(&overdueCounts).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&models.CollaborativeTask{}).
+		Select("lead_user_id AS user_id, COUNT(*) AS count").
+		Where("lead_user_id IN ? AND status IN ?", reportIDs, openTaskStatuses).
+		Group("lead_user_id").
+		Scan(&collaborativeCounts).Error; err != nil {
+		return nil, err
+	}
+
+	byStatus := make(map[uint]map[models.TaskStatus]int64)
+	for _, row := range taskCounts {
+		if byStatus[row.UserID] == nil {
+			byStatus[row.UserID] = make(map[models.TaskStatus]int64)
+		}
+		byStatus[row.UserID][row.Status] = row.Count
+	}
+	overdue := make(map[uint]int64)
+	for _, row := range overdueCounts {
+		overdue[row.UserID] = row.Count
+	}
+	openCollaborative := make(map[uint]int64)
+	for _, row := range collaborativeCounts {
+		openCollaborative[row.UserID] = row.Count
+	}
+
+	teamTotals := make(map[models.TaskStatus]int64)
+	var teamOverdue, teamOpenCollaborative int64
+	members := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		counts := byStatus[user.ID]
+		for status, count := range counts {
+			teamTotals[status] += count
+		}
+		teamOverdue += overdue[user.ID]
+		teamOpenCollaborative += openCollaborative[user.ID]
+
+		members = append(members, map[string]interface{}{
+			"user_id":                  user.ID,
+			"username":                 user.Username,
+			"manager_id":               user.ManagerID,
+			"level":                    levels[user.ID],
+			"department":               user.DepartmentName(),
+			"open_tasks":               counts[models.TaskStatusPending] + counts[models.TaskStatusInProgress],
+			"overdue_tasks":            overdue[user.ID],
+			"open_collaborative_tasks": openCollaborative[user.ID],
+			"tasks":                    statusSummary(counts),
+		})
+	}
+
+	// Busiest people first
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i]["open_tasks"].(int64) > members[j]["open_tasks"].(int64)
+	})
+
+	return map[string]interface{}{
+		"manager_id": managerID,
+		"team_size":  len(users),
+		"totals": map[string]interface{}{
+			"open_tasks":               teamTotals[models.TaskStatusPending] + teamTotals[models.TaskStatusInProgress],
+			"overdue_tasks":            teamOverdue,
+			"open_collaborative_tasks": teamOpenCollaborative,
+			"tasks":                    statusSummary(teamTotals),
+		},
+		"members": members,
+	}, nil
+}
+
+// levelIDs returns the user IDs of a ReportLevels result
+func levelIDs(levels map[uint]int) []uint {
+	ids := make([]uint, 0, len(levels))
+	for id := range levels {
+		ids = append(ids, id)
+	}
+	return ids
+}
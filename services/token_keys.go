@@ -0,0 +1,262 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"project-x/config"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token types, sent in the typ header. Each type has its own audience, and tokens are only
+// accepted as the type they were issued as, so a 2FA challenge token is never an access token.
+const (
+	TokenTypeAccess    = "at+jwt"
+	TokenTypeChallenge = "2fa-challenge+jwt"
+)
+
+// SigningKey is one entry in the key ring. Retired keys have no private key
+// and are only used to verify tokens issued before a rotation.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeyRing holds the active signing key and every key still accepted for verification
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	issuer string
+}
+
+// JSONWebKey is a public key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	keyRing     *KeyRing
+	keyRingErr  error
+	keyRingOnce sync.Once
+)
+
+// GetKeyRing returns the token key ring, loading it from config on first use
+func GetKeyRing() (*KeyRing, error) {
+	keyRingOnce.Do(func() {
+		keyRing, keyRingErr = loadKeyRing(config.Get())
+	})
+	return keyRing, keyRingErr
+}
+
+// Sign signs claims of a token type with the active key and sets the kid and typ headers.
+// The claims' audience must be Audience(tokenType).
+func (k *KeyRing) Sign(tokenType string, claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+	token.Header["typ"] = tokenType
+	return token.SignedString(k.active.PrivateKey)
+}
+
+// Parse verifies a token of a type against the key named in its kid header and fills in claims
+func (k *KeyRing) Parse(tokenString, tokenType string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != tokenType {
+			return nil, errors.New("unexpected token type")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, exists := k.keys[kid]
+		if !exists {
+			return nil, errors.New("unknown signing key")
+		}
+
+		// The algorithm must match the key, never what the token claims on its own
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return key.PublicKey, nil
+	}, jwt.WithIssuer(k.issuer), jwt.WithAudience(k.Audience(tokenType)), jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
+}
+
+// Issuer returns the iss value stamped on issued tokens
+func (k *KeyRing) Issuer() string {
+	return k.issuer
+}
+
+// Audience returns the aud value of tokens of a type, e.g. "project-x/api" for access tokens
+func (k *KeyRing) Audience(tokenType string) string {
+	if tokenType == TokenTypeChallenge {
+		return k.issuer + "/2fa"
+	}
+	return k.issuer + "/api"
+}
+
+// JWKS returns the public part of every key in the ring
+func (k *KeyRing) JWKS() JSONWebKeySet {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// loadKeyRing reads every <kid>.pem file in the keys directory.
+// Without a keys directory an ephemeral Ed25519 key is generated for local development.
+func loadKeyRing(cfg *config.Config) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]*SigningKey), issuer: cfg.JWTIssuer}
+
+	if cfg.JWTKeysDir == "" {
+		key, err := generateEphemeralKey()
+		if err != nil {
+			return nil, err
+		}
+		log.Println("⚠️ JWT_KEYS_DIR is not set, using an ephemeral signing key; tokens will not survive a restart")
+		ring.keys[key.ID] = key
+		ring.active = key
+		return ring, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		key, err := loadSigningKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s: %w", file, err)
+		}
+		// k1.pem and k1.pub.pem would both be k1; keep one file per key ID
+		if _, exists := ring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("loading %s: more than one file has key ID %q", file, key.ID)
+		}
+		ring.keys[key.ID] = key
+	}
+
+	active, exists := ring.keys[cfg.JWTActiveKeyID]
+	if !exists {
+		return nil, fmt.Errorf("active signing key %q not found in %s", cfg.JWTActiveKeyID, cfg.JWTKeysDir)
+	}
+	if active.PrivateKey == nil {
+		return nil, fmt.Errorf("active signing key %q has no private key", cfg.JWTActiveKeyID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// loadSigningKey parses a PEM file; the key ID is the file name without ".pem" (and ".pub")
+func loadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	id := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".pem"), ".pub")
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, PrivateKey: key, PublicKey: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, PrivateKey: key, PublicKey: key.Public()}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodRS256, PublicKey: key}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: id, Method: jwt.SigningMethodEdDSA, PublicKey: key}, nil
+	default:
+		return nil, errors.New("unsupported key type, use RSA or Ed25519")
+	}
+}
+
+// generateEphemeralKey creates an in-memory Ed25519 key with a random ID
+func generateEphemeralKey() (*SigningKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	idBytes := make([]byte, 4)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		ID:         "ephemeral-" + hex.EncodeToString(idBytes),
+		Method:     jwt.SigningMethodEdDSA,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"project-x/config"
+	"project-x/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeyFiles writes an Ed25519 key pair as <kid>.pem and <kid>.pub.pem
+func writeKeyFiles(t *testing.T, dir, kid string) {
+	t.Helper()
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDER, _ := x509.MarshalPKIXPublicKey(publicKey)
+	files := map[string]*pem.Block{
+		kid + ".pem":     {Type: "PRIVATE KEY", Bytes: privateDER},
+		kid + ".pub.pem": {Type: "PUBLIC KEY", Bytes: publicDER},
+	}
+	for name, block := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadKeyRingRejectsDuplicateKeyIDs(t *testing.T) {
+	dir := t.TempDir()
+	writeKeyFiles(t, dir, "k1")
+
+	_, err := loadKeyRing(&config.Config{JWTIssuer: "project-x", JWTKeysDir: dir, JWTActiveKeyID: "k1"})
+	if err == nil || !strings.Contains(err.Error(), `key ID "k1"`) {
+		t.Errorf("k1.pem and k1.pub.pem: got %v, want a duplicate key ID error", err)
+	}
+
+	os.Remove(filepath.Join(dir, "k1.pub.pem"))
+	if _, err := loadKeyRing(&config.Config{JWTIssuer: "project-x", JWTKeysDir: dir, JWTActiveKeyID: "k1"}); err != nil {
+		t.Errorf("k1.pem alone: got %v", err)
+	}
+}
+
+func TestKeyRingTokenTypes(t *testing.T) {
+	keys, err := loadKeyRing(&config.Config{JWTIssuer: "project-x"})
+	if err != nil {
+		t.Fatalf("load key ring: %v", err)
+	}
+	registered := func(tokenType string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    keys.Issuer(),
+			Audience:  jwt.ClaimStrings{keys.Audience(tokenType)},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+	}
+
+	accessToken, err := keys.Sign(TokenTypeAccess, &models.Claims{UserID: 1, RegisteredClaims: registered(TokenTypeAccess)})
+	if err != nil {
+		t.Fatalf("sign access token: %v", err)
+	}
+	challengeToken, err := keys.Sign(TokenTypeChallenge, &models.ChallengeClaims{UserID: 1, Purpose: twoFactorChallengePurpose, RegisteredClaims: registered(TokenTypeChallenge)})
+	if err != nil {
+		t.Fatalf("sign challenge token: %v", err)
+	}
+
+	if _, err := keys.Parse(accessToken, TokenTypeAccess, &models.Claims{}); err != nil {
+		t.Errorf("access token as access token: %v", err)
+	}
+	if _, err := keys.Parse(challengeToken, TokenTypeChallenge, &models.ChallengeClaims{}); err != nil {
+		t.Errorf("challenge token as challenge token: %v", err)
+	}
+	if _, err := keys.Parse(challengeToken, TokenTypeAccess, &models.Claims{}); err == nil {
+		t.Error("a 2FA challenge token was accepted as an access token")
+	}
+	if _, err := keys.Parse(accessToken, TokenTypeChallenge, &models.ChallengeClaims{}); err == nil {
+		t.Error("an access token was accepted as a 2FA challenge token")
+	}
+
+	// The typ header alone is not enough: the audience must match too
+	mislabeled, _ := keys.Sign(TokenTypeAccess, &models.Claims{UserID: 1, RegisteredClaims: registered(TokenTypeChallenge)})
+	if _, err := keys.Parse(mislabeled, TokenTypeAccess, &models.Claims{}); err == nil {
+		t.Error("an access token with the challenge audience was accepted")
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"project-x/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key of RFC 6238, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The RFC's 8-digit values, cut to the 6 digits authenticator apps show
+	cases := []struct {
+		unixTime int64
+		want     string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got, err := totpCode(rfc6238Secret, tc.unixTime/totpPeriod)
+		if err != nil || got != tc.want {
+			t.Errorf("code at %d: got %q, %v, want %q", tc.unixTime, got, err, tc.want)
+		}
+	}
+	if got, err := totpCode(strings.ToLower(rfc6238Secret), 1); err != nil || got != "287082" {
+		t.Errorf("lowercase secret: got %q, %v", got, err)
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("an invalid secret should be rejected")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	current := time.Now().Unix() / totpPeriod
+	codeAt := func(step int64) string {
+		code, err := totpCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatalf("code for step %d: %v", step, err)
+		}
+		return code
+	}
+
+	cases := []struct {
+		name         string
+		code         string
+		lastUsedStep int64
+		wantStep     int64
+		valid        bool
+	}{
+		{"current step", codeAt(current), 0, current, true},
+		{"surrounding spaces", " " + codeAt(current) + " ", 0, current, true},
+		{"previous step", codeAt(current - 1), 0, current - 1, true},
+		{"next step", codeAt(current + 1), 0, current + 1, true},
+		{"two steps old", codeAt(current - 2), 0, 0, false},
+		{"two steps ahead", codeAt(current + 2), 0, 0, false},
+		{"replayed step", codeAt(current), current, 0, false},
+		{"step before the last used one", codeAt(current - 1), current, 0, false},
+		{"later step after a used one", codeAt(current + 1), current, current + 1, true},
+		{"too short", codeAt(current)[:5], 0, 0, false},
+	}
+	for _, tc := range cases {
+		step, ok := validateTOTP(rfc6238Secret, tc.code, tc.lastUsedStep)
+		if ok != tc.valid || (ok && step != tc.wantStep) {
+			t.Errorf("%s: got step %d, %v, want %d, %v", tc.name, step, ok, tc.wantStep, tc.valid)
+		}
+	}
+}
+
+func TestTwoFactorReplayAndChallenges(t *testing.T) {
+	db := testDB(t)
+
+	workspaceID, err := NewWorkspaceService(db).DefaultWorkspaceID()
+	if err != nil {
+		t.Fatalf("default workspace: %v", err)
+	}
+	user := models.User{WorkspaceID: workspaceID, Username: "totp-user", Password: "x", Role: models.RoleEmployee,
+		TwoFactorEnabled: true, TwoFactorSecret: rfc6238Secret}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	// A step can be recorded once; a second request with the same code loses
+	step := time.Now().Unix() / totpPeriod
+	if err := recordTOTPStep(db, user.ID, step); err != nil {
+		t.Fatalf("record step: %v", err)
+	}
+	if err := recordTOTPStep(db, user.ID, step); err == nil {
+		t.Error("recording the same step twice should fail")
+	}
+
+	// A challenge token works once, and only the newest one works at all
+	authService := NewAuthService(db)
+	first, err := authService.issueChallengeToken(&user)
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+	second, err := authService.issueChallengeToken(&user)
+	if err != nil {
+		t.Fatalf("issue challenge: %v", err)
+	}
+	code, _ := totpCode(rfc6238Secret, step+1)
+	if _, err := authService.VerifyTwoFactor(first, code, "test", "127.0.0.1"); err == nil {
+		t.Error("a superseded challenge should be rejected")
+	}
+	if _, err := authService.VerifyTwoFactor(second, code, "test", "127.0.0.1"); err != nil {
+		t.Fatalf("verify with the newest challenge: %v", err)
+	}
+	if _, err := authService.VerifyTwoFactor(second, code, "test", "127.0.0.1"); err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("reusing a challenge: got %v, want the challenge rejected", err)
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"project-x/config"
+	"project-x/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+type TwoFactorService struct {
+	DB *gorm.DB
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{DB: db}
+}
+
+// BeginEnrollment generates a new TOTP secret for the user; 2FA stays off until confirmed
+func (s *TwoFactorService) BeginEnrollment(userID uint) (string, string, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return "", "", errors.New("user not found")
+	}
+
+	if user.TwoFactorEnabled {
+		return "", "", errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.DB.Model(&user).Updates(map[string]interface{}{
+		"two_factor_secret":         secret,
+		"two_factor_last_used_step": 0,
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	return secret, totpURI(config.Get().TOTPIssuer, user.Username, secret), nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves their authenticator works,
+// and returns a fresh set of recovery codes
+func (s *TwoFactorService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if user.TwoFactorSecret == "" {
+		return nil, errors.New("two-factor enrollment has not been started")
+	}
+
+	step, ok := validateTOTP(user.TwoFactorSecret, code, user.TwoFactorLastUsedStep)
+	if !ok {
+		return nil, errors.New("invalid verification code")
+	}
+
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := recordTOTPStep(tx, user.ID, step); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off 2FA after verifying a current code or recovery code
+func (s *TwoFactorService) Disable(userID uint, code string) error {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+
+	if !user.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.VerifySecondFactor(&user, code); err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"two_factor_enabled":        false,
+			"two_factor_secret":         "",
+			"two_factor_last_used_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a current code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.VerifySecondFactor(&user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// VerifySecondFactor accepts either a TOTP code or an unused recovery code
+func (s *TwoFactorService) VerifySecondFactor(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	if step, ok := validateTOTP(user.TwoFactorSecret, code, user.TwoFactorLastUsedStep); ok {
+		return recordTOTPStep(s.DB, user.ID, step)
+	}
+
+	// Fall back to a recovery code
+	result := s.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid verification code")
+	}
+
+	return nil
+}
+
+// recordTOTPStep marks a TOTP time step as used. The update only applies while the stored step
+// is older, so two requests racing with the same code cannot both succeed.
+func recordTOTPStep(db *gorm.DB, userID uint, step int64) error {
+	result := db.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_used_step < ?", userID, step).
+		Update("two_factor_last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errors.New("invalid verification code")
+	}
+	return nil
+}
+
+// replaceRecoveryCodes deletes a user's recovery codes and stores a new hashed set
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+
+		if err := tx.Create(&models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(code)),
+		}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode lets users type recovery codes with or without the dash
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
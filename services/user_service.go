@@ -8,6 +8,17 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrInvalidCurrentPassword is returned when a self-service password change has the wrong current password
+	ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+
+	// ErrUserDeactivated is returned when a deactivated user is picked for new work or a new role
+	ErrUserDeactivated = errors.New("user is deactivated")
+
+	// ErrUsernameTaken is returned when a new or renamed user would share a username
+	ErrUsernameTaken = errors.New("username already exists")
+)
+
 type UserService struct {
 	DB *gorm.DB
 }
@@ -16,44 +27,67 @@ func NewUserService(db *gorm.DB) *UserService {
 	return &UserService{DB: db}
 }
 
-// CreateUser creates a new user with validation
-func (s *UserService) CreateUser(username, password, role, department string) (*models.User, error) {
-	// Validate role
+// CreateUser creates a new user with validation. The actor must hold every permission of the role.
+func (s *UserService) CreateUser(actor *Actor, username, password, role string, departmentID uint) (*models.User, error) {
+	// Validate role and department
 	if !s.isValidRole(role) {
 		return nil, errors.New("invalid role")
 	}
+	if err := NewPermissionService(s.DB).AuthorizeRoleGrant(actor, models.Role(role)); err != nil {
+		return nil, err
+	}
+	if !NewDepartmentService(s.DB).Exists(departmentID) {
+		return nil, ErrDepartmentNotFound
+	}
 
 	// Check if username already exists
-	var existingUser models.User
-	if err := s.DB.Where("username = ?", username).First(&existingUser).Error; err == nil {
-		return nil, errors.New("username already exists")
+	if s.usernameTaken(username) {
+		return nil, ErrUsernameTaken
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	// Enforce the password policy
+	if err := ValidatePassword(password, username); err != nil {
 		return nil, err
 	}
 
-	// Create user
-	user := &models.User{
-		Username:   username,
-		Password:   string(hashedPassword),
-		Role:       models.Role(role),
-		Department: department,
+	return s.insertUser(&models.User{
+		Username:     username,
+		Role:         models.Role(role),
+		DepartmentID: &departmentID,
+	}, password)
+}
+
+// insertUser hashes the password and stores a new user along with their first password history entry
+func (s *UserService) insertUser(user *models.User, password string) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
 	}
+	user.Password = string(hashedPassword)
 
-	if err := s.DB.Create(user).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, user.ID, user.Password)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	return s.GetUserByID(user.ID)
+}
+
+// usernameTaken reports whether a username is already in use in any workspace
+func (s *UserService) usernameTaken(username string) bool {
+	var existingUser models.User
+	return AcrossWorkspaces(s.DB).Where("username = ?", username).First(&existingUser).Error == nil
 }
 
 // GetUserByID returns a user by ID
 func (s *UserService) GetUserByID(userID uint) (*models.User, error) {
 	var user models.User
-	if err := s.DB.First(&user, userID).Error; err != nil {
+	if err := s.DB.Preload("Department").First(&user, userID).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -71,7 +105,7 @@ func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 // GetAllUsers returns all users with optional filtering
 func (s *UserService) GetAllUsers() ([]models.User, error) {
 	var users []models.User
-	if err := s.DB.Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := s.DB.Preload("Department").Order("created_at DESC").Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
@@ -84,23 +118,29 @@ func (s *UserService) GetUsersByRole(role string) ([]models.User, error) {
 	}
 
 	var users []models.User
-	if err := s.DB.Where("role = ?", role).Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := s.DB.Where("role = ?", role).Preload("Department").Order("created_at DESC").Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-// GetUsersByDepartment returns users filtered by department
-func (s *UserService) GetUsersByDepartment(department string) ([]models.User, error) {
+// GetUsersByDepartment returns users in a department and its sub-departments
+func (s *UserService) GetUsersByDepartment(departmentID uint) ([]models.User, error) {
+	departmentIDs, err := NewDepartmentService(s.DB).SubtreeIDs([]uint{departmentID})
+	if err != nil {
+		return nil, err
+	}
+
 	var users []models.User
-	if err := s.DB.Where("department = ?", department).Order("created_at DESC").Find(&users).Error; err != nil {
+	if err := s.DB.Where("department_id IN ?", departmentIDs).Preload("Department").Order("created_at DESC").Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-// UpdateUserRole updates a user's role
-func (s *UserService) UpdateUserRole(userID uint, newRole string) (*models.User, error) {
+// UpdateUserRole updates a user's role. The actor must hold every permission of the new role and
+// of the user's current one.
+func (s *UserService) UpdateUserRole(actor *Actor, userID uint, newRole string) (*models.User, error) {
 	if !s.isValidRole(newRole) {
 		return nil, errors.New("invalid role")
 	}
@@ -110,83 +150,137 @@ func (s *UserService) UpdateUserRole(userID uint, newRole string) (*models.User,
 		return nil, errors.New("user not found")
 	}
 
+	permissionService := NewPermissionService(s.DB)
+	if err := permissionService.AuthorizeUserAdministration(actor, &user); err != nil {
+		return nil, err
+	}
+	if err := permissionService.AuthorizeRoleGrant(actor, models.Role(newRole)); err != nil {
+		return nil, err
+	}
+
 	user.Role = models.Role(newRole)
 	if err := s.DB.Save(&user).Error; err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	return s.GetUserByID(user.ID)
 }
 
-// UpdateUserDepartment updates a user's department
-func (s *UserService) UpdateUserDepartment(userID uint, newDepartment string) (*models.User, error) {
+// UpdateUserDepartment moves a user to a department, or out of their department when departmentID
+// is nil. Users whose role outranks the actor's are refused.
+func (s *UserService) UpdateUserDepartment(actor *Actor, userID uint, departmentID *uint) (*models.User, error) {
+	if departmentID != nil && !NewDepartmentService(s.DB).Exists(*departmentID) {
+		return nil, ErrDepartmentNotFound
+	}
+
 	var user models.User
 	if err := s.DB.First(&user, userID).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, &user); err != nil {
+		return nil, err
+	}
 
-	user.Department = newDepartment
-	if err := s.DB.Save(&user).Error; err != nil {
+	if err := s.DB.Model(&user).Update("department_id", departmentID).Error; err != nil {
 		return nil, err
 	}
 
-	return &user, nil
+	return s.GetUserByID(user.ID)
 }
 
-// UpdateUserPassword updates a user's password
-func (s *UserService) UpdateUserPassword(userID uint, newPassword string) error {
-	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err != nil {
-		return err
+// ChangeOwnPassword changes a user's password after verifying their current one
+func (s *UserService) ChangeOwnPassword(userID uint, currentPassword, newPassword string) error {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
 	}
 
-	return s.DB.Model(&models.User{}).Where("id = ?", userID).Update("password", string(hashedPassword)).Error
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(currentPassword)); err != nil {
+		return ErrInvalidCurrentPassword
+	}
+
+	return s.UpdateUserPassword(userID, newPassword)
 }
 
-// DeleteUser deletes a user and all related data
-func (s *UserService) DeleteUser(userID uint) error {
-	// Start a transaction
-	tx := s.DB.Begin()
+// ResetUserPassword sets another user's password on behalf of a user manager. Users whose role
+// outranks the actor's are refused.
+func (s *UserService) ResetUserPassword(actor *Actor, userID uint, newPassword string) error {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return errors.New("user not found")
+	}
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, &user); err != nil {
+		return err
+	}
+	return s.UpdateUserPassword(userID, newPassword)
+}
 
-	// Check if user exists
+// UpdateUserPassword updates a user's password and revokes their existing sessions
+func (s *UserService) UpdateUserPassword(userID uint, newPassword string) error {
 	var user models.User
-	if err := tx.First(&user, userID).Error; err != nil {
-		tx.Rollback()
+	if err := s.DB.First(&user, userID).Error; err != nil {
 		return errors.New("user not found")
 	}
 
-	// Delete user project memberships
-	if err := tx.Where("user_id = ?", userID).Delete(&models.UserProject{}).Error; err != nil {
-		tx.Rollback()
+	// Enforce the password policy and block reuse of recent passwords
+	if err := ValidatePassword(newPassword, user.Username); err != nil {
 		return err
 	}
-
-	// Delete user's tasks (they will be deleted due to CASCADE constraint)
-	if err := tx.Where("user_id = ?", userID).Delete(&models.Task{}).Error; err != nil {
-		tx.Rollback()
+	if err := checkPasswordHistory(s.DB, userID, newPassword); err != nil {
 		return err
 	}
 
-	// Delete user's collaborative tasks (they will be deleted due to CASCADE constraint)
-	if err := tx.Where("user_id = ?", userID).Delete(&models.CollaborativeTask{}).Error; err != nil {
-		tx.Rollback()
+	// Hash new password
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
 		return err
 	}
 
-	// Delete the user
-	if err := tx.Delete(&user).Error; err != nil {
-		tx.Rollback()
-		return err
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+
+		if err := recordPasswordHistory(tx, userID, string(hashedPassword)); err != nil {
+			return err
+		}
+
+		// Log the user out everywhere
+		return NewSessionService(tx).RevokeAllUserSessions(userID)
+	})
+}
+
+// UnlockUser clears a user's lockout and failed login counters on behalf of a user manager.
+// Users whose role outranks the actor's are refused.
+func (s *UserService) UnlockUser(actor *Actor, userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := NewPermissionService(s.DB).AuthorizeUserAdministration(actor, &user); err != nil {
+		return nil, err
+	}
+
+	return s.clearLockout(&user)
+}
+
+// clearLockout resets a user's lockout and failed login counters
+func (s *UserService) clearLockout(user *models.User) (*models.User, error) {
+	if err := s.DB.Model(user).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"lockout_count":         0,
+		"locked_until":          nil,
+	}).Error; err != nil {
+		return nil, err
 	}
 
-	return tx.Commit().Error
+	return user, nil
 }
 
 // GetUserStats returns statistics about a user
 func (s *UserService) GetUserStats(userID uint) (map[string]interface{}, error) {
 	var user models.User
-	if err := s.DB.First(&user, userID).Error; err != nil {
+	if err := s.DB.Preload("Department").First(&user, userID).Error; err != nil {
 		return nil, errors.New("user not found")
 	}
 
@@ -214,7 +308,8 @@ func (s *UserService) GetUserStats(userID uint) (map[string]interface{}, error)
 		"user_id":                       userID,
 		"username":                      user.Username,
 		"role":                          user.Role,
-		"department":                    user.Department,
+		"department_id":                 user.DepartmentID,
+		"department":                    user.DepartmentName(),
 		"total_tasks":                   taskCount,
 		"total_collaborative_tasks":     collaborativeTaskCount,
 		"total_projects":                projectCount,
@@ -226,15 +321,9 @@ func (s *UserService) GetUserStats(userID uint) (map[string]interface{}, error)
 	}, nil
 }
 
-// isValidRole checks if a role is valid
+// isValidRole checks if a role exists, built-in or custom
 func (s *UserService) isValidRole(role string) bool {
-	validRoles := []string{"admin", "head", "manager", "employee"}
-	for _, validRole := range validRoles {
-		if role == validRole {
-			return true
-		}
-	}
-	return false
+	return NewPermissionService(s.DB).RoleExists(role)
 }
 
 // calculateCompletionRate calculates the completion rate percentage
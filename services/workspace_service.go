@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"project-x/models"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultWorkspaceSlug identifies the workspace created on first start. Existing data is
+// moved into it, and users holding workspace.manage in it can act inside any workspace.
+const DefaultWorkspaceSlug = "default"
+
+var (
+	ErrWorkspaceNotFound     = errors.New("workspace not found")
+	ErrWorkspaceAccessDenied = errors.New("you cannot act inside this workspace")
+)
+
+// workspaceSlugPattern keeps slugs URL friendly
+var workspaceSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+// workspaceTables are the tables that carry a workspace_id
+var workspaceTables = []string{"users", "projects", "tasks", "collaborative_tasks", "invitations"}
+
+type workspaceContextKey struct{}
+
+// WithWorkspace returns a context that limits queries run with it to one workspace
+func WithWorkspace(ctx context.Context, workspaceID uint) context.Context {
+	return context.WithValue(ctx, workspaceContextKey{}, workspaceID)
+}
+
+// WorkspaceFromContext returns the workspace set by WithWorkspace
+func WorkspaceFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	workspaceID, ok := ctx.Value(workspaceContextKey{}).(uint)
+	return workspaceID, ok
+}
+
+// InWorkspace returns a session whose queries are limited to one workspace, for code
+// that runs outside a request
+func InWorkspace(db *gorm.DB, workspaceID uint) *gorm.DB {
+	return db.WithContext(WithWorkspace(db.Statement.Context, workspaceID))
+}
+
+// AcrossWorkspaces returns a session whose queries see every workspace, for checks against
+// unique indexes that span workspaces, such as usernames and email addresses
+func AcrossWorkspaces(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, workspaceContextKey{}, nil))
+}
+
+// RegisterWorkspaceScope installs callbacks that filter every query, update and delete on a
+// model with a WorkspaceID field by the session's workspace, and stamp it on created rows.
+// Sessions without a workspace (startup, migrations, login) are not filtered.
+func RegisterWorkspaceScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("workspace:assign", assignWorkspace); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("workspace:scope", scopeToWorkspace); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("workspace:scope", scopeToWorkspace); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("workspace:scope", scopeToWorkspace); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("workspace:scope", scopeToWorkspace)
+}
+
+// workspaceField returns the session's workspace and the model's workspace column, if both exist
+func workspaceField(tx *gorm.DB) (uint, string, bool) {
+	workspaceID, ok := WorkspaceFromContext(tx.Statement.Context)
+	if !ok || tx.Statement.Schema == nil {
+		return 0, "", false
+	}
+	field := tx.Statement.Schema.LookUpField("WorkspaceID")
+	if field == nil {
+		return 0, "", false
+	}
+	return workspaceID, field.DBName, true
+}
+
+func scopeToWorkspace(tx *gorm.DB) {
+	workspaceID, column, ok := workspaceField(tx)
+	if !ok {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: workspaceID},
+	}})
+}
+
+// assignWorkspace overwrites any workspace set by the caller, so rows cannot be created
+// in another workspace
+func assignWorkspace(tx *gorm.DB) {
+	workspaceID, _, ok := workspaceField(tx)
+	if !ok {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField("WorkspaceID")
+
+	value := tx.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(tx.Statement.Context, reflect.Indirect(value.Index(i)), workspaceID); err != nil {
+				tx.AddError(err)
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(tx.Statement.Context, value, workspaceID); err != nil {
+			tx.AddError(err)
+		}
+	}
+}
+
+// CanManageWorkspaces reports whether a user may create workspaces and act inside any of them.
+// Every admin holds workspace.manage, so it only counts in the default workspace; admins of
+// other workspaces stay inside their own.
+func CanManageWorkspaces(user *models.User, permissions models.PermissionSet, defaultWorkspaceID uint) bool {
+	return user.WorkspaceID == defaultWorkspaceID && permissions.Has(models.PermissionWorkspaceManage)
+}
+
+// CanActInWorkspace reports whether a user may work inside a workspace
+func CanActInWorkspace(user *models.User, permissions models.PermissionSet, workspaceID, defaultWorkspaceID uint) bool {
+	return user.WorkspaceID == workspaceID || CanManageWorkspaces(user, permissions, defaultWorkspaceID)
+}
+
+type WorkspaceService struct {
+	DB *gorm.DB
+}
+
+func NewWorkspaceService(db *gorm.DB) *WorkspaceService {
+	return &WorkspaceService{DB: db}
+}
+
+// ListWorkspaces returns every workspace
+func (s *WorkspaceService) ListWorkspaces() ([]models.Workspace, error) {
+	var workspaces []models.Workspace
+	err := s.DB.Order("name").Find(&workspaces).Error
+	return workspaces, err
+}
+
+// GetWorkspace returns a workspace by ID
+func (s *WorkspaceService) GetWorkspace(workspaceID uint) (*models.Workspace, error) {
+	var workspace models.Workspace
+	if err := s.DB.First(&workspace, workspaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// CreateWorkspace creates an empty workspace
+func (s *WorkspaceService) CreateWorkspace(name, slug string) (*models.Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("workspace name is required")
+	}
+	if !workspaceSlugPattern.MatchString(slug) {
+		return nil, errors.New("slug must be 2-63 lowercase letters, digits or dashes")
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.Workspace{}).Where("slug = ?", slug).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("workspace slug already exists")
+	}
+
+	workspace := models.Workspace{Name: name, Slug: slug}
+	if err := s.DB.Create(&workspace).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// DefaultWorkspaceID returns the ID of the default workspace
+func (s *WorkspaceService) DefaultWorkspaceID() (uint, error) {
+	var workspace models.Workspace
+	if err := s.DB.Where("slug = ?", DefaultWorkspaceSlug).First(&workspace).Error; err != nil {
+		return 0, err
+	}
+	return workspace.ID, nil
+}
+
+// CanManage reports whether a user may create and list workspaces
+func (s *WorkspaceService) CanManage(user *models.User, permissions models.PermissionSet) (bool, error) {
+	defaultID, err := s.DefaultWorkspaceID()
+	if err != nil {
+		return false, err
+	}
+	return CanManageWorkspaces(user, permissions, defaultID), nil
+}
+
+// ResolveWorkspace picks the workspace a request acts inside: the requested one when given,
+// otherwise the user's own
+func (s *WorkspaceService) ResolveWorkspace(user *models.User, permissions models.PermissionSet, requestedID *uint) (uint, error) {
+	if requestedID == nil || *requestedID == user.WorkspaceID {
+		return user.WorkspaceID, nil
+	}
+
+	if _, err := s.GetWorkspace(*requestedID); err != nil {
+		return 0, err
+	}
+	defaultID, err := s.DefaultWorkspaceID()
+	if err != nil {
+		return 0, err
+	}
+	if !CanActInWorkspace(user, permissions, *requestedID, defaultID) {
+		return 0, ErrWorkspaceAccessDenied
+	}
+	return *requestedID, nil
+}
+
+// MigrateWorkspaces creates the default workspace and moves rows from before workspaces
+// existed into it
+func MigrateWorkspaces(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		workspace := models.Workspace{Slug: DefaultWorkspaceSlug}
+		if err := tx.Where(models.Workspace{Slug: DefaultWorkspaceSlug}).
+			Attrs(models.Workspace{Name: "Default"}).
+			FirstOrCreate(&workspace).Error; err != nil {
+			return err
+		}
+
+		for _, table := range workspaceTables {
+			result := tx.Table(table).
+				Where("workspace_id IS NULL OR workspace_id = 0").
+				Update("workspace_id", workspace.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				log.Printf("Moved %d row(s) of %s into the default workspace", result.RowsAffected, table)
+			}
+		}
+		return nil
+	})
+}
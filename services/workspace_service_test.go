@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"os"
+	"project-x/models"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	defaultWorkspaceID uint = iota + 1
+	acmeWorkspaceID
+	globexWorkspaceID
+)
+
+// dryRunDB builds SQL without a database so the workspace filter can be inspected
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=workspace_test"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open dry run database: %v", err)
+	}
+	if err := RegisterWorkspaceScope(db); err != nil {
+		t.Fatalf("register workspace scope: %v", err)
+	}
+	return db
+}
+
+var (
+	migrateTestDBOnce sync.Once
+	migrateTestDBErr  error
+)
+
+// testDB connects to the Postgres database named by TEST_DATABASE_URL, skipping the test when
+// it is unset. Each test runs in a transaction that is rolled back when it finishes.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := RegisterWorkspaceScope(db); err != nil {
+		t.Fatalf("register workspace scope: %v", err)
+	}
+	migrateTestDBOnce.Do(func() {
+		if migrateTestDBErr = db.AutoMigrate(models.All()...); migrateTestDBErr != nil {
+			return
+		}
+		if migrateTestDBErr = MigrateWorkspaces(db); migrateTestDBErr != nil {
+			return
+		}
+		migrateTestDBErr = NewPermissionService(db).SyncPermissions()
+	})
+	if migrateTestDBErr != nil {
+		t.Fatalf("migrate test database: %v", migrateTestDBErr)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() {
+		tx.Rollback()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return tx
+}
+
+// requireWorkspaceFilter fails unless the statement is filtered to the workspace
+func requireWorkspaceFilter(t *testing.T, name string, stmt *gorm.Statement, table string, workspaceID uint) {
+	t.Helper()
+	sql := stmt.SQL.String()
+	if !strings.Contains(sql, `"`+table+`"."workspace_id" = $`) {
+		t.Errorf("%s: no workspace filter in %s", name, sql)
+		return
+	}
+	for _, v := range stmt.Vars {
+		if id, ok := v.(uint); ok && id == workspaceID {
+			return
+		}
+	}
+	t.Errorf("%s: workspace %d not bound in %s %v", name, workspaceID, sql, stmt.Vars)
+}
+
+func TestWorkspaceScopeFiltersReads(t *testing.T) {
+	db := InWorkspace(dryRunDB(t), acmeWorkspaceID)
+
+	var tasks []models.Task
+	requireWorkspaceFilter(t, "find tasks", db.Where("status = ? OR status = ?", "pending", "in_progress").Find(&tasks).Statement, "tasks", acmeWorkspaceID)
+
+	var task models.Task
+	requireWorkspaceFilter(t, "first task by ID", db.First(&task, 42).Statement, "tasks", acmeWorkspaceID)
+
+	var collaborativeTask models.CollaborativeTask
+	requireWorkspaceFilter(t, "first collaborative task", db.First(&collaborativeTask, 42).Statement, "collaborative_tasks", acmeWorkspaceID)
+
+	var projects []models.Project
+	requireWorkspaceFilter(t, "find projects", db.Find(&projects).Statement, "projects", acmeWorkspaceID)
+
+	var user models.User
+	requireWorkspaceFilter(t, "user by username", db.Where("username = ?", "alice").First(&user).Statement, "users", acmeWorkspaceID)
+
+	var count int64
+	requireWorkspaceFilter(t, "count users", db.Model(&models.User{}).Where("role = ?", models.RoleEmployee).Count(&count).Statement, "users", acmeWorkspaceID)
+
+	var ids []uint
+	requireWorkspaceFilter(t, "pluck user IDs", db.Model(&models.User{}).Where("manager_id IN ?", []uint{1}).Pluck("id", &ids).Statement, "users", acmeWorkspaceID)
+
+	var rows []struct {
+		UserID uint
+		Count  int64
+	}
+	requireWorkspaceFilter(t, "grouped scan", db.Model(&models.Task{}).Select("user_id, COUNT(*) AS count").Group("user_id").Scan(&rows).Statement, "tasks", acmeWorkspaceID)
+
+	// Joined tables must not make the filter ambiguous
+	joined := db.Model(&models.Task{}).
+		Joins("JOIN users ON users.id = tasks.user_id").
+		Where("users.department_id IN ?", []uint{1}).
+		Find(&tasks).Statement
+	requireWorkspaceFilter(t, "joined tasks", joined, "tasks", acmeWorkspaceID)
+}
+
+func TestWorkspaceScopeFiltersWrites(t *testing.T) {
+	db := InWorkspace(dryRunDB(t), globexWorkspaceID)
+
+	// A task ID from another workspace must not match
+	task := models.Task{}
+	task.ID = 42
+	requireWorkspaceFilter(t, "update status", db.Model(&task).Update("status", models.TaskStatusCompleted).Statement, "tasks", globexWorkspaceID)
+	requireWorkspaceFilter(t, "bulk update", db.Model(&models.Task{}).Where("id IN ?", []uint{1, 2}).Update("status", models.TaskStatusCancelled).Statement, "tasks", globexWorkspaceID)
+	requireWorkspaceFilter(t, "delete task", db.Delete(&models.Task{}, 42).Statement, "tasks", globexWorkspaceID)
+	requireWorkspaceFilter(t, "delete collaborative task", db.Delete(&models.CollaborativeTask{}, 42).Statement, "collaborative_tasks", globexWorkspaceID)
+	requireWorkspaceFilter(t, "update project", db.Model(&models.Project{}).Where("id = ?", 7).Update("status", models.ProjectStatusPaused).Statement, "projects", globexWorkspaceID)
+	requireWorkspaceFilter(t, "update user role", db.Model(&models.User{}).Where("id = ?", 3).Update("role", models.RoleAdmin).Statement, "users", globexWorkspaceID)
+}
+
+func TestWorkspaceScopeAssignsCreatedRows(t *testing.T) {
+	db := InWorkspace(dryRunDB(t), acmeWorkspaceID)
+
+	// Rows cannot be planted in another workspace by setting the field
+	task := models.Task{Title: "Ship it", WorkspaceID: globexWorkspaceID}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if task.WorkspaceID != acmeWorkspaceID {
+		t.Errorf("created task in workspace %d, want %d", task.WorkspaceID, acmeWorkspaceID)
+	}
+
+	projects := []models.Project{{Title: "One"}, {Title: "Two", WorkspaceID: globexWorkspaceID}}
+	if err := db.Create(&projects).Error; err != nil {
+		t.Fatalf("create projects: %v", err)
+	}
+	for _, project := range projects {
+		if project.WorkspaceID != acmeWorkspaceID {
+			t.Errorf("created project %q in workspace %d, want %d", project.Title, project.WorkspaceID, acmeWorkspaceID)
+		}
+	}
+}
+
+func TestWorkspaceScopeIgnoresUnscopedSessions(t *testing.T) {
+	db := dryRunDB(t)
+
+	var tasks []models.Task
+	if sql := db.Find(&tasks).Statement.SQL.String(); strings.Contains(sql, "workspace_id") {
+		t.Errorf("session without a workspace was filtered: %s", sql)
+	}
+
+	// Tables shared by all workspaces are never filtered
+	var departments []models.Department
+	if sql := InWorkspace(db, acmeWorkspaceID).Find(&departments).Statement.SQL.String(); strings.Contains(sql, "workspace_id") {
+		t.Errorf("departments were filtered by workspace: %s", sql)
+	}
+
+	// Uniqueness checks look across workspaces, like the indexes they guard
+	var user models.User
+	if sql := AcrossWorkspaces(InWorkspace(db, acmeWorkspaceID)).Where("username = ?", "alice").First(&user).Statement.SQL.String(); strings.Contains(sql, "workspace_id") {
+		t.Errorf("session across workspaces was filtered: %s", sql)
+	}
+
+	task := models.Task{WorkspaceID: globexWorkspaceID}
+	if err := db.Create(&task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	if task.WorkspaceID != globexWorkspaceID {
+		t.Errorf("unscoped create changed the workspace to %d", task.WorkspaceID)
+	}
+}
+
+func TestWorkspaceFromContext(t *testing.T) {
+	if _, ok := WorkspaceFromContext(context.Background()); ok {
+		t.Error("background context has a workspace")
+	}
+	if id, ok := WorkspaceFromContext(WithWorkspace(context.Background(), acmeWorkspaceID)); !ok || id != acmeWorkspaceID {
+		t.Errorf("got workspace %d (%v), want %d", id, ok, acmeWorkspaceID)
+	}
+}
+
+func TestCanActInWorkspace(t *testing.T) {
+	admin := defaultPermissions(models.RoleAdmin)
+	employee := defaultPermissions(models.RoleEmployee)
+
+	tests := []struct {
+		name        string
+		home        uint
+		permissions models.PermissionSet
+		workspace   uint
+		want        bool
+	}{
+		{"employee in own workspace", acmeWorkspaceID, employee, acmeWorkspaceID, true},
+		{"employee in another workspace", acmeWorkspaceID, employee, globexWorkspaceID, false},
+		{"tenant admin in another workspace", acmeWorkspaceID, admin, globexWorkspaceID, false},
+		{"tenant admin in the default workspace", acmeWorkspaceID, admin, defaultWorkspaceID, false},
+		{"default workspace admin anywhere", defaultWorkspaceID, admin, globexWorkspaceID, true},
+		{"default workspace employee elsewhere", defaultWorkspaceID, employee, acmeWorkspaceID, false},
+	}
+
+	for _, tt := range tests {
+		user := &models.User{WorkspaceID: tt.home}
+		if got := CanActInWorkspace(user, tt.permissions, tt.workspace, defaultWorkspaceID); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}